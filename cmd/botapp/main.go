@@ -16,16 +16,26 @@ import (
 
 	"github.com/HalvaPovidlo/halvabot-go/cmd/config"
 	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/admin"
 	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
 	musicrest "github.com/HalvaPovidlo/halvabot-go/internal/api/v1/music"
+	statsrest "github.com/HalvaPovidlo/halvabot-go/internal/api/v1/stats"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/users"
 	capi "github.com/HalvaPovidlo/halvabot-go/internal/chess/api/discord"
 	"github.com/HalvaPovidlo/halvabot-go/internal/chess/lichess"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/audio"
 	dapi "github.com/HalvaPovidlo/halvabot-go/internal/music/discord"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/library"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/likes"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/playlist"
 	ytsearch "github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/stats"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/bolt"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/files"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/firestore"
-	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 	dpkg "github.com/HalvaPovidlo/halvabot-go/pkg/discord"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/http/jwt"
@@ -33,6 +43,13 @@ import (
 	pfirestore "github.com/HalvaPovidlo/halvabot-go/pkg/storage/firestore"
 )
 
+const flushTimeout = 15 * time.Second
+
+type filesCache interface {
+	Add(path string)
+	Remove(path string)
+}
+
 func main() {
 	// TODO: all magic vars to config
 	cfg, err := config.InitConfig()
@@ -49,12 +66,37 @@ func main() {
 		logger.Panic("discord open session failed", zap.Error(err))
 	}
 	defer session.Close()
-	// Load master
-	loadMaster := pkg.NewLoadMaster(ctx, 12*time.Hour)
+	// Downloaded files
+	var (
+		loadedFiles  filesCache
+		filesManager *files.Manager
+	)
+	useFilesCache := cfg.Youtube.Download && cfg.Youtube.CacheSizeMB > 0
+	if useFilesCache {
+		loadedFiles, err = files.NewCache(ctx, cfg.Youtube.OutputDir, cfg.Youtube.CacheSizeMB<<20)
+		if err != nil {
+			logger.Panic("new files cache", zap.Error(err))
+		}
+	} else {
+		filesManager, err = files.NewManager(ctx, cfg.Youtube.OutputDir, 12*time.Hour)
+		if err != nil {
+			logger.Panic("new files manager", zap.Error(err))
+		}
+		loadedFiles = filesManager
+	}
 
 	// Cache
-	songsCache := firestore.NewSongsCache(ctx, 12*time.Hour)
-	defer songsCache.Clear()
+	songsCache := storage.NewSongsCache(ctx, cfg.Storage.CacheSize, 12*time.Hour)
+
+	// Storage stage
+	backend, closeBackend, err := newStorageBackend(ctx, cfg.Storage, cfg.General.Debug)
+	if err != nil {
+		logger.Panic("new storage backend", zap.String("backend", cfg.Storage.Backend), zap.Error(err))
+	}
+	storageService, err := storage.NewService(ctx, backend, songsCache)
+	if err != nil {
+		logger.Panic("new storage service", zap.Error(err))
+	}
 
 	// YouTube services
 	ytService, err := youtube.NewService(ctx, option.WithCredentialsFile("halvabot-google.json"))
@@ -65,56 +107,94 @@ func main() {
 	ytClient := ytsearch.NewYouTubeClient(
 		&ytdlClient,
 		ytService,
-		ytsearch.NewDownloader(ytdlClient, cfg.Youtube.OutputDir, loadMaster),
+		ytsearch.NewDownloader(ctx, ytdlClient, cfg.Youtube.OutputDir, loadedFiles, cfg.Youtube.Workers),
+		storageService,
 		cfg.Youtube,
 	)
 
-	// Firestore stage
-	fireClient, err := pfirestore.NewFirestoreClient(ctx, "halvabot-firebase.json")
-	if err != nil {
-		logger.Panic("new firestore client", zap.Error(err))
-	}
-	fireStorage, err := firestore.NewFirestoreClient(ctx, fireClient, cfg.General.Debug)
-	if err != nil {
-		logger.Panic("new firestore storage", zap.Error(err))
-	}
-	fireService, err := firestore.NewFirestoreService(ctx, fireStorage, songsCache)
-	if err != nil {
-		logger.Panic("new firestore service", zap.Error(err))
-	}
-
 	// Music stage
 	voiceClient := audio.NewVoiceClient(session)
-	rawAudioPlayer := audio.NewPlayer(loadMaster, &cfg.Discord.Voice.EncodeOptions)
-	musicPlayer := player.NewMusicService(ctx, fireService, ytClient, voiceClient, rawAudioPlayer)
+	rawAudioPlayer := audio.NewPlayer(loadedFiles, &cfg.Discord.Voice.EncodeOptions)
+	bans, err := moderation.NewService(ctx, storageService)
+	if err != nil {
+		logger.Panic("new moderation service", zap.Error(err))
+	}
+	musicPlayer := player.NewMusicService(ctx, storageService, ytClient, bans, voiceClient, rawAudioPlayer, cfg.Player)
+	playlists := playlist.NewService(ctx, storageService, musicPlayer, ytClient)
+	likesService := likes.NewService(storageService, musicPlayer)
+	statsService := stats.NewService(storageService)
+	musicPlayer.SubscribeOnEvents(statsService.RecordEvent)
 
 	// Chess
 	lichessClient := lichess.NewClient()
 
 	// Discord commands
-	musicCog := dapi.NewCog(musicPlayer, cfg.Discord.Prefix, cfg.Discord.API)
+	musicCog := dapi.NewCog(musicPlayer, ytClient, playlists, likesService, bans, statsService, storageService, cfg.Discord.Prefix, cfg.Discord.API)
 	musicCog.RegisterCommands(ctx, session, cfg.General.Debug, logger)
 	chessCog := capi.NewCog(cfg.Discord.Prefix, lichessClient)
 	chessCog.RegisterCommands(session, cfg.General.Debug, logger)
 
 	// Auth stage
-	loginService := login.NewLoginService(login.NewAccountStorage(fireClient), jwt.NewJWTokenizer(cfg.Secret))
+	loginService := login.NewLoginService(storageService, jwt.NewJWTokenizer(cfg.Secret))
 
 	// Http routers
-	server := v1.NewServer(musicrest.NewMusicHandler(musicPlayer, logger), loginService)
+	server := v1.NewServer(
+		musicrest.NewMusicHandler(musicPlayer, storageService, playlists, logger),
+		loginService,
+		users.NewUsersHandler(likesService, logger),
+		admin.NewAdminHandler(bans, library.NewService(storageService), cfg.Discord.API.Admins, logger),
+		statsrest.NewStatsHandler(statsService, logger),
+	)
 	server.Run(cfg.Host.IP, cfg.Host.Bot, config.SwaggerPath, cfg.General.Debug)
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 	cancel()
-	if err := os.RemoveAll("/" + cfg.Youtube.OutputDir + "/"); err != nil {
-		logger.Error(err.Error())
-	}
-	if err := os.MkdirAll("/"+cfg.Youtube.OutputDir+"/", 0o755); err != nil {
-		logger.Error(err.Error())
+	closeBackend()
+	if filesManager != nil {
+		if err := filesManager.Shutdown(); err != nil {
+			logger.Error("files manager shutdown", zap.Error(err))
+		}
 	}
 
 	logger.Info("Graceful shutdown")
 	_ = logger.Sync()
 }
+
+// newStorageBackend opens the backend selected by config and returns the function closing it
+func newStorageBackend(ctx context.Context, cfg storage.Config, debug bool) (storage.Backend, func(), error) {
+	switch cfg.Backend {
+	case storage.BackendBolt:
+		if cfg.Path == "" {
+			cfg.Path = "halvabot.db"
+		}
+		client, err := bolt.NewBoltClient(cfg.Path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "new bolt client")
+		}
+		return client, func() { _ = client.Close() }, nil
+	case storage.BackendFirestore, "":
+		if cfg.Credentials == "" {
+			cfg.Credentials = "halvabot-firebase.json"
+		}
+		fireClient, err := pfirestore.NewFirestoreClient(ctx, cfg.Credentials)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "new firestore client")
+		}
+		fireStorage, err := firestore.NewFirestoreClient(ctx, fireClient, debug)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "new firestore storage")
+		}
+		// The pending writes are flushed after ctx is canceled
+		logger := contexts.GetLogger(ctx)
+		return fireStorage, func() {
+			closeCtx, cancel := context.WithTimeout(contexts.WithLogger(context.Background(), logger), flushTimeout)
+			defer cancel()
+			if err := fireStorage.Close(closeCtx); err != nil {
+				logger.Error("close firestore storage", zap.Error(err))
+			}
+		}, nil
+	}
+	return nil, nil, errors.Errorf("unknown storage backend %q", cfg.Backend)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/audio"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	mockGuildID   = "mock_guild"
+	mockChannelID = "mock_channel"
+)
+
+// fakeVoice is always connected, so the REST API can enqueue songs without a discord session
+type fakeVoice struct {
+	mx   sync.Mutex
+	conn *discordgo.VoiceConnection
+}
+
+func newFakeVoice() *fakeVoice {
+	return &fakeVoice{conn: &discordgo.VoiceConnection{GuildID: mockGuildID, ChannelID: mockChannelID}}
+}
+
+func (v *fakeVoice) Connection() *discordgo.VoiceConnection {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	return v.conn
+}
+
+func (v *fakeVoice) Connect(guildID, channelID string) error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.conn = &discordgo.VoiceConnection{GuildID: guildID, ChannelID: channelID}
+	return nil
+}
+
+func (v *fakeVoice) IsConnected() bool {
+	return true
+}
+
+func (v *fakeVoice) Disconnect() error {
+	return nil
+}
+
+// fakeAudio pretends to play every song for the same duration
+type fakeAudio struct {
+	duration time.Duration
+
+	mx      sync.Mutex
+	playing bool
+	stop    chan struct{} // closed by Stop, nil while nothing plays
+	stats   pkg.SessionStats
+}
+
+func newFakeAudio(duration time.Duration) *fakeAudio {
+	return &fakeAudio{
+		duration: duration,
+	}
+}
+
+func (a *fakeAudio) Process(requests <-chan *audio.SongRequest) <-chan error {
+	out := make(chan error)
+	go func() {
+		defer close(out)
+		for range requests {
+			out <- a.play()
+		}
+	}()
+	return out
+}
+
+func (a *fakeAudio) play() error {
+	stop := make(chan struct{})
+	a.mx.Lock()
+	a.playing = true
+	a.stop = stop
+	a.stats = pkg.SessionStats{Duration: a.duration.Seconds()}
+	a.mx.Unlock()
+	defer func() {
+		a.mx.Lock()
+		a.playing = false
+		a.stop = nil
+		a.mx.Unlock()
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	timer := time.NewTimer(a.duration)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return audio.ErrManualStop
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			a.mx.Lock()
+			a.stats.Pos = time.Since(start).Seconds()
+			a.mx.Unlock()
+		}
+	}
+}
+
+func (a *fakeAudio) Stats() pkg.SessionStats {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return a.stats
+}
+
+func (a *fakeAudio) IsPlaying() bool {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return a.playing
+}
+
+func (a *fakeAudio) Stop() {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
+
+// fakeSearch finds songs among the fixtures by URL or by title
+type fakeSearch struct {
+	songs    *memory.Client
+	duration time.Duration
+}
+
+func (s *fakeSearch) FindSong(ctx context.Context, query string) (*pkg.Song, error) {
+	songs, err := s.songs.GetAllSongs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queryID := pkg.GetIDFromURL(query)
+	query = strings.ToLower(query)
+	for _, song := range songs {
+		if song.ID == queryID || strings.Contains(strings.ToLower(song.Title), query) {
+			song.Playbacks = 0
+			return s.LoadSongInfo(ctx, song)
+		}
+	}
+	return nil, youtube.ErrSongNotFound
+}
+
+func (s *fakeSearch) LoadSongInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	song.Duration = s.duration.Seconds()
+	return song, nil
+}
+
+func (s *fakeSearch) EnsureStreamInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	song.StreamURL = "fake://" + song.ID.String()
+	return s.LoadSongInfo(ctx, song)
+}
+
+func (s *fakeSearch) RefreshStream(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	return song, nil
+}
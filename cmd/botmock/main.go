@@ -1,39 +1,114 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/HalvaPovidlo/halvabot-go/cmd/config"
 	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/admin"
 	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
 	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/music"
+	statsrest "github.com/HalvaPovidlo/halvabot-go/internal/api/v1/stats"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/users"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/library"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/likes"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/playlist"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/stats"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/http/jwt"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/log"
 )
 
+type playerService interface {
+	Play(ctx context.Context, query, userID, guildID, channelID string) (*pkg.Song, error)
+	Skip(ctx context.Context)
+	SetLoop(ctx context.Context, b bool)
+	SetRadio(ctx context.Context, b bool, guildID, channelID string) error
+	Status() pkg.PlayerStatus
+	NowPlaying() *pkg.Song
+	Queue() []*pkg.Song
+}
+
+type accountsStorage interface {
+	GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error)
+}
+
 func main() {
+	fixtures := flag.String("fixtures", "", "JSON file with songs and accounts to run the real player on fake voice and audio")
+	songDuration := flag.Duration("song-duration", 2*time.Minute, "how long every fake song plays")
+	flag.Parse()
+
 	cfg, err := config.InitConfig()
 	if err != nil {
 		panic(errors.Wrap(err, "config read failed"))
 	}
 	logger := log.NewLogger(cfg.General.Debug)
+	ctx := contexts.WithLogger(context.Background(), logger)
+	ctx, cancel := context.WithCancel(ctx)
 
-	mock := &player.MockPlayer{}
-	loginService := login.NewLoginService(login.NewMockStorage(), jwt.NewJWTokenizer("mock_secret"))
-	musicService := music.NewMusicHandler(mock, logger)
+	backend := memory.NewMemoryClient()
+	if *fixtures != "" {
+		if err := loadFixtures(backend, *fixtures); err != nil {
+			logger.Panic("load fixtures", zap.Error(err))
+		}
+	}
+	songs, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, 12*time.Hour))
+	if err != nil {
+		logger.Panic("new storage service", zap.Error(err))
+	}
+	bans, err := moderation.NewService(ctx, songs)
+	if err != nil {
+		logger.Panic("new moderation service", zap.Error(err))
+	}
+	statsCounter := stats.NewService(songs)
+	var (
+		musicPlayer playerService   = &player.MockPlayer{}
+		accounts    accountsStorage = login.NewMockStorage()
+		search                      = &fakeSearch{songs: backend, duration: *songDuration}
+	)
+	if *fixtures != "" {
+		service := player.NewMusicService(ctx, songs, search, bans, newFakeVoice(), newFakeAudio(*songDuration), cfg.Player)
+		service.SubscribeOnEvents(statsCounter.RecordEvent)
+		musicPlayer = service
+		accounts = songs
+	}
+	loginService := login.NewLoginService(accounts, jwt.NewJWTokenizer("mock_secret"))
+	playlists := playlist.NewService(ctx, songs, musicPlayer, search)
+	musicService := music.NewMusicHandler(musicPlayer, songs, playlists, logger)
+	usersService := users.NewUsersHandler(likes.NewService(songs, musicPlayer), logger)
+	adminService := admin.NewAdminHandler(bans, library.NewService(songs), cfg.Discord.API.Admins, logger)
+	statsService := statsrest.NewStatsHandler(statsCounter, logger)
 	// Http routers
-	server := v1.NewServer(musicService, loginService)
+	server := v1.NewServer(musicService, loginService, usersService, adminService, statsService)
 	server.Run(cfg.Host.IP, cfg.Host.Mock, config.SwaggerPath, cfg.General.Debug)
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
+	cancel()
 
 	logger.Info("Graceful shutdown")
 	_ = logger.Sync()
 }
+
+func loadFixtures(backend *memory.Client, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return backend.LoadFixtures(f)
+}
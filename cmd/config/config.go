@@ -8,7 +8,9 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/discord"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
 )
 
 const FilePath = "secret_config.json"
@@ -19,6 +21,8 @@ type Config struct {
 	Host    HostConfig     `json:"host"`
 	Discord DiscordConfig  `json:"discord"`
 	Youtube youtube.Config `json:"youtube"`
+	Player  player.Config  `json:"player"`
+	Storage storage.Config `json:"storage"`
 	Secret  string         `json:"secret"`
 	// Sheets  SheetsConfig  `json:"sheets"`
 	// VK      VKConfig      `json:"vk"`
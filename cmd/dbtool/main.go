@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/library"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/bolt"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/firestore"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/log"
+	pfirestore "github.com/HalvaPovidlo/halvabot-go/pkg/storage/firestore"
+)
+
+const usage = `Usage: dbtool [flags] <command>
+
+Commands:
+  normalize-ids                          merge firestore song documents stored under non-canonical YouTube IDs
+  dedup [titles]                         merge duplicate songs by the video ID and optionally by title and artist
+  migrate                                upgrade stored songs to the current schema version, continues a stopped one
+  export [songs|users|playlists]         write the library or its part, csv needs the part
+  import [songs|users|playlists]         upsert the exported library or its part
+  add-account <login> <password> <user>  create the web account in the bolt database
+
+Flags:
+`
+
+func main() {
+	creds := flag.String("creds", "halvabot-firebase.json", "firebase credentials file")
+	db := flag.String("db", "halvabot.db", "bolt database file")
+	backend := flag.String("backend", storage.BackendFirestore, "storage backend of dedup, migrate, export and import: firestore or bolt")
+	format := flag.String("format", "json", "format of export and import: json or csv")
+	file := flag.String("file", "-", "file of export and import, - is stdout and stdin")
+	dryRun := flag.Bool("dry-run", false, "only print what is going to be changed")
+	debug := flag.Bool("debug", false, "debug logs")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger := log.NewLogger(*debug)
+	ctx := contexts.WithLogger(context.Background(), logger)
+	switch flag.Arg(0) {
+	case "normalize-ids":
+		normalizeIDs(ctx, *creds, *dryRun)
+	case "dedup":
+		if flag.NArg() > 2 || (flag.NArg() == 2 && flag.Arg(1) != "titles") {
+			flag.Usage()
+			os.Exit(2)
+		}
+		client, closeBackend := openBackend(ctx, *backend, *creds, *db)
+		dedup(ctx, client, flag.Arg(1) == "titles", *dryRun)
+		closeBackend()
+	case "migrate":
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		client, closeBackend := openBackend(ctx, *backend, *creds, *db)
+		migrate(ctx, client, *dryRun)
+		closeBackend()
+	case "export", "import":
+		if flag.NArg() > 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		client, closeBackend := openBackend(ctx, *backend, *creds, *db)
+		transfer(ctx, client, flag.Arg(0) == "import", *file, *format, flag.Arg(1))
+		closeBackend()
+	case "add-account":
+		if flag.NArg() != 4 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		addAccount(ctx, *db, flag.Arg(1), flag.Arg(2), flag.Arg(3))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	_ = logger.Sync()
+}
+
+func normalizeIDs(ctx context.Context, creds string, dryRun bool) {
+	logger := contexts.GetLogger(ctx)
+	fireClient, err := pfirestore.NewFirestoreClient(ctx, creds)
+	if err != nil {
+		logger.Fatal("new firestore client", zap.Error(err))
+	}
+	fireStorage, err := firestore.NewFirestoreClient(ctx, fireClient, false)
+	if err != nil {
+		logger.Fatal("new firestore storage", zap.Error(err))
+	}
+	n, err := fireStorage.MergeDuplicateSongs(ctx, dryRun)
+	if err != nil {
+		logger.Fatal("normalize song ids", zap.Error(err))
+	}
+	logger.Info("song ids normalized", zap.Int("removed", n), zap.Bool("dry_run", dryRun))
+	_ = fireClient.Close()
+}
+
+// openBackend opens the storage backend, the returned function flushes and closes it
+func openBackend(ctx context.Context, backend, creds, db string) (storage.Backend, func()) {
+	logger := contexts.GetLogger(ctx)
+	switch backend {
+	case storage.BackendBolt:
+		client, err := bolt.NewBoltClient(db)
+		if err != nil {
+			logger.Fatal("new bolt client", zap.Error(err))
+		}
+		return client, func() {
+			if err := client.Close(); err != nil {
+				logger.Error("close bolt", zap.Error(err))
+			}
+		}
+	case storage.BackendFirestore:
+		fireClient, err := pfirestore.NewFirestoreClient(ctx, creds)
+		if err != nil {
+			logger.Fatal("new firestore client", zap.Error(err))
+		}
+		client, err := firestore.NewFirestoreClient(ctx, fireClient, false)
+		if err != nil {
+			logger.Fatal("new firestore storage", zap.Error(err))
+		}
+		return client, func() {
+			if err := client.Close(ctx); err != nil {
+				logger.Error("close firestore", zap.Error(err))
+			}
+		}
+	}
+	logger.Fatal("unknown backend", zap.String("backend", backend))
+	return nil, nil
+}
+
+func dedup(ctx context.Context, backend storage.Backend, titles, dryRun bool) {
+	logger := contexts.GetLogger(ctx)
+	res, err := storage.Dedup(ctx, backend, titles, dryRun)
+	if err != nil {
+		logger.Fatal("dedup songs", zap.Error(err))
+	}
+	for _, group := range res.Groups {
+		ids := make([]string, 0, len(group.Songs))
+		for _, song := range group.Songs {
+			ids = append(ids, song.ID.String())
+		}
+		fmt.Printf("%s\t%s\t%s <- %s\n", group.Reason, group.Target.String(), group.Songs[0].Title, strings.Join(ids, " "))
+	}
+	logger.Info("songs deduplicated",
+		zap.Int("groups", len(res.Groups)),
+		zap.Int("removed", res.Removed),
+		zap.Int("user_songs", res.UserSongs),
+		zap.Bool("dry_run", dryRun))
+}
+
+type songMigrator interface {
+	MigrateSongs(ctx context.Context, dryRun bool) (*storage.MigrationResult, error)
+}
+
+func migrate(ctx context.Context, backend storage.Backend, dryRun bool) {
+	logger := contexts.GetLogger(ctx)
+	migrator, ok := backend.(songMigrator)
+	if !ok {
+		logger.Fatal("backend does not store songs of older versions")
+	}
+	res, err := migrator.MigrateSongs(ctx, dryRun)
+	if err != nil {
+		logger.Fatal("migrate songs", zap.Error(err))
+	}
+	logger.Info("songs migrated",
+		zap.Int("version", storage.SongSchemaVersion),
+		zap.Int("checked", res.Checked),
+		zap.Int("migrated", res.Migrated),
+		zap.Int("skipped", res.Skipped),
+		zap.Bool("dry_run", dryRun))
+}
+
+func transfer(ctx context.Context, backend storage.Backend, load bool, file, formatName, kindName string) {
+	logger := contexts.GetLogger(ctx)
+	format, err := library.ParseFormat(formatName)
+	if err != nil {
+		logger.Fatal("parse format", zap.Error(err))
+	}
+	kind, err := library.ParseKind(kindName)
+	if err != nil {
+		logger.Fatal("parse kind", zap.Error(err))
+	}
+	service := library.NewService(backend)
+
+	if !load {
+		out := os.Stdout
+		if file != "-" {
+			if out, err = os.Create(file); err != nil {
+				logger.Fatal("create file", zap.Error(err))
+			}
+			defer out.Close()
+		}
+		if err := service.Export(ctx, out, format, kind); err != nil {
+			logger.Fatal("export", zap.Error(err))
+		}
+		logger.Info("library exported", zap.String("kind", kindName), zap.String("format", string(format)))
+		return
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		if in, err = os.Open(file); err != nil {
+			logger.Fatal("open file", zap.Error(err))
+		}
+		defer in.Close()
+	}
+	res, err := service.Import(ctx, in, format, kind)
+	if err != nil {
+		logger.Fatal("import", zap.Error(err))
+	}
+	logger.Info("library imported",
+		zap.Int("songs", res.Songs),
+		zap.Int("user_songs", res.UserSongs),
+		zap.Int("playlists", res.Playlists))
+}
+
+func addAccount(ctx context.Context, db, login, password, userID string) {
+	logger := contexts.GetLogger(ctx)
+	client, err := bolt.NewBoltClient(db)
+	if err != nil {
+		logger.Fatal("new bolt client", zap.Error(err))
+	}
+	login = strings.ToLower(login)
+	err = client.SetAccount(ctx, login, &pkg.AccountInfo{Password: password, UserID: userID})
+	_ = client.Close()
+	if err != nil {
+		logger.Fatal("set account", zap.Error(err))
+	}
+	logger.Info("account added", zap.String("login", login))
+}
@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/library"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const (
+	maxAuditLimit = 1000
+	// maxImportSize limits the body of the library import
+	maxImportSize = 64 << 20
+)
+
+type bansService interface {
+	Ban(ctx context.Context, kind pkg.BanKind, value, reason, by string) (*pkg.Ban, int, error)
+	Unban(ctx context.Context, kind pkg.BanKind, value, by string) (*pkg.Ban, int, error)
+	List() []*pkg.Ban
+	Audit(ctx context.Context, limit int) ([]*pkg.BanAudit, error)
+}
+
+type libraryService interface {
+	Export(ctx context.Context, w io.Writer, format library.Format, kind library.Kind) error
+	Import(ctx context.Context, r io.Reader, format library.Format, kind library.Kind) (*library.ImportResult, error)
+}
+
+type Handler struct {
+	bans    bansService
+	library libraryService
+	admins  map[string]struct{} // userID{}
+	logger  *zap.Logger
+}
+
+func NewAdminHandler(bans bansService, library libraryService, admins []string, logger *zap.Logger) *Handler {
+	h := &Handler{
+		bans:    bans,
+		library: library,
+		admins:  make(map[string]struct{}, len(admins)),
+		logger:  logger,
+	}
+	for _, id := range admins {
+		h.admins[id] = struct{}{}
+	}
+	return h
+}
+
+// AdminOnly must follow the authorization, it aborts requests of users who are not admins
+func (h *Handler) AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := h.admins[c.GetString(login.UserID)]; !ok {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *Handler) GetAdminBans(c *gin.Context) {
+	bans := h.bans.List()
+	res := make([]v1.Ban, 0, len(bans))
+	for _, ban := range bans {
+		res = append(res, buildBan(ban))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) PostAdminBans(c *gin.Context) {
+	var json v1.PostAdminBansJSONRequestBody
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	reason := ""
+	if json.Reason != nil {
+		reason = *json.Reason
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	ban, n, err := h.bans.Ban(ctx, pkg.BanKind(json.Kind), json.Value, reason, c.GetString(login.UserID))
+	if err != nil {
+		banError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ban": buildBan(ban), "songs": n})
+}
+
+func (h *Handler) DeleteAdminBans(c *gin.Context, params v1.DeleteAdminBansParams) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	if _, _, err := h.bans.Unban(ctx, pkg.BanKind(params.Kind), params.Value, c.GetString(login.UserID)); err != nil {
+		banError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) GetAdminBansAudit(c *gin.Context, params v1.GetAdminBansAuditParams) {
+	limit := 0
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, v1.Error{Msg: "limit must be between 1 and 1000"})
+			return
+		}
+		limit = *params.Limit
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	entries, err := h.bans.Audit(ctx, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+		return
+	}
+	res := make([]v1.BanAudit, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, v1.BanAudit{
+			Action: v1.BanAuditAction(e.Action),
+			At:     e.At,
+			By:     e.By,
+			Kind:   v1.BanKind(e.Kind),
+			Reason: optional(e.Reason),
+			Songs:  e.Songs,
+			Value:  e.Value,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) GetAdminLibraryExport(c *gin.Context, params v1.GetAdminLibraryExportParams) {
+	format, kind, err := parseLibraryParams((*string)(params.Format), (*string)(params.Kind))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	var buf bytes.Buffer
+	if err := h.library.Export(ctx, &buf, format, kind); err != nil {
+		libraryError(c, err)
+		return
+	}
+	name := "library"
+	if kind != "" {
+		name = string(kind)
+	}
+	contentType := "application/json"
+	if format == library.FormatCSV {
+		contentType = "text/csv"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+string(format)))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func (h *Handler) PostAdminLibraryImport(c *gin.Context, params v1.PostAdminLibraryImportParams) {
+	format, kind, err := parseLibraryParams((*string)(params.Format), (*string)(params.Kind))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	res, err := h.library.Import(ctx, http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize), format, kind)
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, v1.ImportResult{Playlists: res.Playlists, Songs: res.Songs, UserSongs: res.UserSongs})
+}
+
+func parseLibraryParams(formatName, kindName *string) (library.Format, library.Kind, error) {
+	format, err := library.ParseFormat(value(formatName))
+	if err != nil {
+		return "", "", err
+	}
+	kind, err := library.ParseKind(value(kindName))
+	return format, kind, err
+}
+
+func libraryError(c *gin.Context, err error) {
+	if errors.Is(err, library.ErrInvalidRecord) || errors.Is(err, library.ErrUnknownKind) {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+}
+
+func banError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrInvalidBan):
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+	case errors.Is(err, moderation.ErrAlreadyBanned):
+		c.JSON(http.StatusConflict, v1.Error{Msg: err.Error()})
+	case errors.Is(err, moderation.ErrBanNotFound):
+		c.JSON(http.StatusNotFound, v1.Error{Msg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+	}
+}
+
+func buildBan(ban *pkg.Ban) v1.Ban {
+	return v1.Ban{
+		At:     ban.At,
+		By:     ban.By,
+		Kind:   v1.BanKind(ban.Kind),
+		Reason: optional(ban.Reason),
+		Value:  ban.Value,
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
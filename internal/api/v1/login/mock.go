@@ -2,6 +2,8 @@ package login
 
 import (
 	"context"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 )
 
 type mock struct{}
@@ -10,8 +12,8 @@ func NewMockStorage() *mock {
 	return &mock{}
 }
 
-func (a *mock) GetAccount(ctx context.Context, login string) (*AccountInfo, error) {
-	return &AccountInfo{
+func (a *mock) GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error) {
+	return &pkg.AccountInfo{
 		Password: "password",
 		UserID:   "user_id",
 	}, nil
@@ -10,6 +10,8 @@ import (
 	"github.com/pkg/errors"
 
 	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 )
 
 const (
@@ -19,7 +21,7 @@ const (
 )
 
 type accountsStorage interface {
-	GetAccount(ctx context.Context, login string) (*AccountInfo, error)
+	GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error)
 }
 
 type tokenizer interface {
@@ -48,6 +50,9 @@ func (s *Service) PostAuthToken(c *gin.Context) {
 	}
 	input.Login = strings.ToLower(input.Login)
 	user, err := s.accounts.GetAccount(c, input.Login)
+	if errors.Is(err, storage.ErrNotFound) {
+		user, err = nil, nil
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, v1.Error{Msg: errors.Wrap(err, "failed to get account info").Error()})
 		return
@@ -12,6 +12,7 @@ import (
 
 	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
 	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
@@ -25,15 +26,23 @@ type playerService interface {
 	Status() pkg.PlayerStatus
 }
 
+type historyStorage interface {
+	GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error)
+}
+
 type Handler struct {
-	player playerService
-	logger *zap.Logger
+	player    playerService
+	history   historyStorage
+	playlists playlistService
+	logger    *zap.Logger
 }
 
-func NewMusicHandler(player playerService, logger *zap.Logger) *Handler {
+func NewMusicHandler(player playerService, history historyStorage, playlists playlistService, logger *zap.Logger) *Handler {
 	return &Handler{
-		player: player,
-		logger: logger,
+		player:    player,
+		history:   history,
+		playlists: playlists,
+		logger:    logger,
 	}
 }
 
@@ -50,10 +59,20 @@ func (h *Handler) PostMusicEnqueueServiceIdentifier(c *gin.Context, service stri
 		}
 		ctx := contexts.WithValues(c, h.logger, "")
 		song, err := h.player.Play(ctx, json.Input, c.GetString(login.UserID), "", "")
+		var (
+			limitErr *player.LimitError
+			banErr   *moderation.BanError
+		)
 		switch {
 		case errors.Is(err, player.ErrNotConnected):
 			c.Status(http.StatusConflict)
 			return
+		case errors.As(err, &limitErr):
+			c.JSON(http.StatusForbidden, v1.Error{Msg: limitMessage(limitErr)})
+			return
+		case errors.As(err, &banErr):
+			c.JSON(http.StatusForbidden, v1.Error{Msg: banMessage(banErr.Ban)})
+			return
 		case status.Convert(err).Code() != codes.OK && status.Convert(err).Code() != codes.Unknown:
 			c.JSON(http.StatusInsufficientStorage, gin.H{"song": song, "msg": err.Error()})
 			return
@@ -67,6 +86,28 @@ func (h *Handler) PostMusicEnqueueServiceIdentifier(c *gin.Context, service stri
 	c.Status(http.StatusNotImplemented)
 }
 
+func limitMessage(err *player.LimitError) string {
+	switch {
+	case errors.Is(err, player.ErrSongTooLong):
+		return "Song is too long, maximum duration is " + err.Value
+	case errors.Is(err, player.ErrChannelBlocked):
+		return "Songs of this channel are blocked on the server"
+	case errors.Is(err, player.ErrLiveNotAllowed):
+		return "Livestreams are not allowed"
+	case errors.Is(err, player.ErrKeywordBlocked):
+		return "Song contains a word blocked on the server: " + err.Value
+	}
+	return "Song is blocked on the server"
+}
+
+func banMessage(ban *pkg.Ban) string {
+	msg := "Song is banned"
+	if ban.Reason != "" {
+		msg += ": " + ban.Reason
+	}
+	return msg
+}
+
 func buildSong(song *pkg.Song) *v1.Song {
 	return &v1.Song{
 		ArtistName:   song.ArtistName,
@@ -128,3 +169,68 @@ func (h *Handler) GetMusicStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, st)
 }
+
+const (
+	defaultEventsLimit = 100
+	maxEventsLimit     = 1000
+)
+
+func (h *Handler) GetMusicEvents(c *gin.Context, params v1.GetMusicEventsParams) {
+	filter := pkg.EventFilter{Limit: defaultEventsLimit}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxEventsLimit {
+			c.JSON(http.StatusBadRequest, v1.Error{Msg: "limit must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = *params.Limit
+	}
+	if params.From != nil {
+		filter.From = *params.From
+	}
+	if params.To != nil {
+		filter.To = *params.To
+	}
+	if params.GuildId != nil {
+		filter.GuildID = *params.GuildId
+	}
+	if params.RequesterId != nil {
+		filter.RequesterID = *params.RequesterId
+	}
+	if params.SongId != nil {
+		filter.SongID = pkg.ParseSongID(*params.SongId)
+	}
+	if params.Type != nil {
+		filter.Types = []pkg.EventType{pkg.EventType(*params.Type)}
+	}
+
+	ctx := contexts.WithValues(c, h.logger, "")
+	events, err := h.history.GetEvents(ctx, &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+		return
+	}
+	res := make([]v1.PlayEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, buildEvent(e))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func buildEvent(e *pkg.PlayEvent) v1.PlayEvent {
+	event := v1.PlayEvent{
+		At:        e.At,
+		Duration:  float32(e.Duration),
+		Position:  float32(e.Position),
+		SongId:    e.SongID.String(),
+		StartedAt: e.StartedAt,
+		Title:     e.Title,
+		Type:      v1.PlayEventType(e.Type),
+	}
+	if e.GuildID != "" {
+		event.GuildId = &e.GuildID
+	}
+	if e.RequesterID != "" {
+		event.RequesterId = &e.RequesterID
+	}
+	return event
+}
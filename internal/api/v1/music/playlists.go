@@ -0,0 +1,174 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/playlist"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type playlistService interface {
+	CurrentQueue() []*pkg.Song
+	Find(ctx context.Context, inputs []string) ([]*pkg.Song, error)
+	Save(ctx context.Context, ownerID, guildID, name string, songs []*pkg.Song) (*pkg.Playlist, error)
+	Reorder(ctx context.Context, ownerID, name string, ids []pkg.SongID) (*pkg.Playlist, error)
+	Share(ctx context.Context, ownerID, name string, visibility pkg.PlaylistVisibility) (*pkg.Playlist, error)
+	Delete(ctx context.Context, ownerID, name string) error
+	Get(ctx context.Context, userID, guildID, name string) (*pkg.Playlist, error)
+	List(ctx context.Context, userID, guildID string) ([]*pkg.Playlist, error)
+	Load(ctx context.Context, userID, guildID, channelID, name string) (*pkg.Playlist, int, error)
+}
+
+func (h *Handler) GetMusicPlaylists(c *gin.Context, params v1.GetMusicPlaylistsParams) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	list, err := h.playlists.List(ctx, c.GetString(login.UserID), stringValue(params.GuildId))
+	if err != nil {
+		playlistError(c, err)
+		return
+	}
+	res := make([]v1.Playlist, 0, len(list))
+	for _, p := range list {
+		res = append(res, buildPlaylist(p))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) PostMusicPlaylists(c *gin.Context) {
+	var json v1.PostMusicPlaylistsJSONRequestBody
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	userID := c.GetString(login.UserID)
+	songs := h.playlists.CurrentQueue()
+	if json.Inputs != nil && len(*json.Inputs) > 0 {
+		var err error
+		if songs, err = h.playlists.Find(ctx, *json.Inputs); err != nil {
+			playlistError(c, err)
+			return
+		}
+	}
+	p, err := h.playlists.Save(ctx, userID, stringValue(json.GuildId), json.Name, songs)
+	if err == nil && json.Visibility != nil {
+		p, err = h.playlists.Share(ctx, userID, p.Name, pkg.PlaylistVisibility(*json.Visibility))
+	}
+	if err != nil {
+		playlistError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildPlaylist(p))
+}
+
+func (h *Handler) GetMusicPlaylistsName(c *gin.Context, name string, params v1.GetMusicPlaylistsNameParams) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	p, err := h.playlists.Get(ctx, c.GetString(login.UserID), stringValue(params.GuildId), name)
+	if err != nil {
+		playlistError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildPlaylist(p))
+}
+
+func (h *Handler) PutMusicPlaylistsName(c *gin.Context, name string) {
+	var json v1.PutMusicPlaylistsNameJSONRequestBody
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	ctx := contexts.WithValues(c, h.logger, "")
+	userID := c.GetString(login.UserID)
+	var (
+		p   *pkg.Playlist
+		err error
+	)
+	if json.Songs != nil {
+		ids := make([]pkg.SongID, 0, len(*json.Songs))
+		for _, id := range *json.Songs {
+			ids = append(ids, pkg.ParseSongID(id))
+		}
+		p, err = h.playlists.Reorder(ctx, userID, name, ids)
+	}
+	if err == nil && json.Visibility != nil {
+		p, err = h.playlists.Share(ctx, userID, name, pkg.PlaylistVisibility(*json.Visibility))
+	}
+	if err == nil && p == nil {
+		p, err = h.playlists.Get(ctx, userID, "", name)
+	}
+	if err != nil {
+		playlistError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildPlaylist(p))
+}
+
+func (h *Handler) DeleteMusicPlaylistsName(c *gin.Context, name string) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	if err := h.playlists.Delete(ctx, c.GetString(login.UserID), name); err != nil {
+		playlistError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) PostMusicPlaylistsNameLoad(c *gin.Context, name string, params v1.PostMusicPlaylistsNameLoadParams) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	p, n, err := h.playlists.Load(ctx, c.GetString(login.UserID), stringValue(params.GuildId), "", name)
+	if err != nil {
+		playlistError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enqueued": n, "playlist": buildPlaylist(p)})
+}
+
+func playlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, playlist.ErrNotFound):
+		c.JSON(http.StatusNotFound, v1.Error{Msg: err.Error()})
+	case errors.Is(err, youtube.ErrSongNotFound):
+		c.JSON(http.StatusNotFound, v1.Error{Msg: err.Error()})
+	case errors.Is(err, playlist.ErrInvalidName), errors.Is(err, playlist.ErrEmpty),
+		errors.Is(err, playlist.ErrTooLong), errors.Is(err, playlist.ErrNoSong), errors.Is(err, playlist.ErrVisibility):
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+	case errors.Is(err, player.ErrNotConnected):
+		c.Status(http.StatusConflict)
+	case errors.Is(err, playlist.ErrBusy):
+		c.JSON(http.StatusTooManyRequests, v1.Error{Msg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+	}
+}
+
+func buildPlaylist(p *pkg.Playlist) v1.Playlist {
+	res := v1.Playlist{
+		Created:    p.Created,
+		Name:       p.Name,
+		OwnerId:    p.OwnerID,
+		Songs:      make([]v1.PlaylistSong, 0, len(p.Songs)),
+		Updated:    p.Updated,
+		Visibility: v1.PlaylistVisibility(p.Visibility),
+	}
+	if p.GuildID != "" {
+		res.GuildId = &p.GuildID
+	}
+	for _, song := range p.Songs {
+		res.Songs = append(res.Songs, v1.PlaylistSong{Id: song.ID.String(), Title: song.Title, Url: song.URL})
+	}
+	return res
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -13,15 +13,54 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Unban
+	// (DELETE /admin/bans)
+	DeleteAdminBans(c *gin.Context, params DeleteAdminBansParams)
+	// List bans
+	// (GET /admin/bans)
+	GetAdminBans(c *gin.Context)
+	// Ban
+	// (POST /admin/bans)
+	PostAdminBans(c *gin.Context)
+	// Ban audit trail
+	// (GET /admin/bans/audit)
+	GetAdminBansAudit(c *gin.Context, params GetAdminBansAuditParams)
+	// Export the library
+	// (GET /admin/library/export)
+	GetAdminLibraryExport(c *gin.Context, params GetAdminLibraryExportParams)
+	// Import the library
+	// (POST /admin/library/import)
+	PostAdminLibraryImport(c *gin.Context, params PostAdminLibraryImportParams)
 	// Login
 	// (POST /auth/token)
 	PostAuthToken(c *gin.Context)
 	// Find and enqueue song
 	// (POST /music/enqueue/{service}/{kind})
 	PostMusicEnqueueServiceIdentifier(c *gin.Context, service string, kind string)
+	// Playback history
+	// (GET /music/events)
+	GetMusicEvents(c *gin.Context, params GetMusicEventsParams)
 	// Set loop mode
 	// (POST /music/loop)
 	PostMusicLoop(c *gin.Context)
+	// List playlists
+	// (GET /music/playlists)
+	GetMusicPlaylists(c *gin.Context, params GetMusicPlaylistsParams)
+	// Create playlist
+	// (POST /music/playlists)
+	PostMusicPlaylists(c *gin.Context)
+	// Delete playlist
+	// (DELETE /music/playlists/{name})
+	DeleteMusicPlaylistsName(c *gin.Context, name string)
+	// Get playlist
+	// (GET /music/playlists/{name})
+	GetMusicPlaylistsName(c *gin.Context, name string, params GetMusicPlaylistsNameParams)
+	// Update playlist
+	// (PUT /music/playlists/{name})
+	PutMusicPlaylistsName(c *gin.Context, name string)
+	// Load playlist
+	// (POST /music/playlists/{name}/load)
+	PostMusicPlaylistsNameLoad(c *gin.Context, name string, params PostMusicPlaylistsNameLoadParams)
 	// Set radio mode
 	// (POST /music/radio)
 	PostMusicRadio(c *gin.Context)
@@ -31,6 +70,21 @@ type ServerInterface interface {
 	// Player status
 	// (GET /music/status)
 	GetMusicStatus(c *gin.Context)
+	// Top artists
+	// (GET /stats/artists)
+	GetStatsArtists(c *gin.Context, params GetStatsArtistsParams)
+	// Top requesters
+	// (GET /stats/requesters)
+	GetStatsRequesters(c *gin.Context, params GetStatsRequestersParams)
+	// Top songs
+	// (GET /stats/songs)
+	GetStatsSongs(c *gin.Context, params GetStatsSongsParams)
+	// User stats
+	// (GET /stats/users/{id})
+	GetStatsUsersId(c *gin.Context, id string, params GetStatsUsersIdParams)
+	// Liked songs
+	// (GET /users/me/likes)
+	GetUsersMeLikes(c *gin.Context)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -41,6 +95,181 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(c *gin.Context)
 
+// DeleteAdminBans operation middleware
+func (siw *ServerInterfaceWrapper) DeleteAdminBans(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteAdminBansParams
+
+	// ------------- Required query parameter "kind" -------------
+	if paramValue := c.Query("kind"); paramValue != "" {
+
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "Query argument kind is required, but not found"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "kind", c.Request.URL.Query(), &params.Kind)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter kind: %s", err)})
+		return
+	}
+
+	// ------------- Required query parameter "value" -------------
+	if paramValue := c.Query("value"); paramValue != "" {
+
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "Query argument value is required, but not found"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "value", c.Request.URL.Query(), &params.Value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter value: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.DeleteAdminBans(c, params)
+}
+
+// GetAdminBans operation middleware
+func (siw *ServerInterfaceWrapper) GetAdminBans(c *gin.Context) {
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetAdminBans(c)
+}
+
+// PostAdminBans operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminBans(c *gin.Context) {
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostAdminBans(c)
+}
+
+// GetAdminBansAudit operation middleware
+func (siw *ServerInterfaceWrapper) GetAdminBansAudit(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAdminBansAuditParams
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := c.Query("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter limit: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetAdminBansAudit(c, params)
+}
+
+// GetAdminLibraryExport operation middleware
+func (siw *ServerInterfaceWrapper) GetAdminLibraryExport(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAdminLibraryExportParams
+
+	// ------------- Optional query parameter "format" -------------
+	if paramValue := c.Query("format"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "format", c.Request.URL.Query(), &params.Format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter format: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "kind" -------------
+	if paramValue := c.Query("kind"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "kind", c.Request.URL.Query(), &params.Kind)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter kind: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetAdminLibraryExport(c, params)
+}
+
+// PostAdminLibraryImport operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminLibraryImport(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostAdminLibraryImportParams
+
+	// ------------- Optional query parameter "format" -------------
+	if paramValue := c.Query("format"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "format", c.Request.URL.Query(), &params.Format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter format: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "kind" -------------
+	if paramValue := c.Query("kind"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "kind", c.Request.URL.Query(), &params.Kind)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter kind: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostAdminLibraryImport(c, params)
+}
+
 // PostAuthToken operation middleware
 func (siw *ServerInterfaceWrapper) PostAuthToken(c *gin.Context) {
 
@@ -83,6 +312,100 @@ func (siw *ServerInterfaceWrapper) PostMusicEnqueueServiceIdentifier(c *gin.Cont
 	siw.Handler.PostMusicEnqueueServiceIdentifier(c, service, kind)
 }
 
+// GetMusicEvents operation middleware
+func (siw *ServerInterfaceWrapper) GetMusicEvents(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMusicEventsParams
+
+	// ------------- Optional query parameter "from" -------------
+	if paramValue := c.Query("from"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "from", c.Request.URL.Query(), &params.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter from: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+	if paramValue := c.Query("to"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "to", c.Request.URL.Query(), &params.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter to: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "guild_id" -------------
+	if paramValue := c.Query("guild_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "guild_id", c.Request.URL.Query(), &params.GuildId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter guild_id: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "requester_id" -------------
+	if paramValue := c.Query("requester_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "requester_id", c.Request.URL.Query(), &params.RequesterId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter requester_id: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "song_id" -------------
+	if paramValue := c.Query("song_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "song_id", c.Request.URL.Query(), &params.SongId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter song_id: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "type" -------------
+	if paramValue := c.Query("type"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "type", c.Request.URL.Query(), &params.Type)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter type: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := c.Query("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter limit: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetMusicEvents(c, params)
+}
+
 // PostMusicLoop operation middleware
 func (siw *ServerInterfaceWrapper) PostMusicLoop(c *gin.Context) {
 
@@ -95,6 +418,166 @@ func (siw *ServerInterfaceWrapper) PostMusicLoop(c *gin.Context) {
 	siw.Handler.PostMusicLoop(c)
 }
 
+// GetMusicPlaylists operation middleware
+func (siw *ServerInterfaceWrapper) GetMusicPlaylists(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMusicPlaylistsParams
+
+	// ------------- Optional query parameter "guild_id" -------------
+	if paramValue := c.Query("guild_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "guild_id", c.Request.URL.Query(), &params.GuildId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter guild_id: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetMusicPlaylists(c, params)
+}
+
+// PostMusicPlaylists operation middleware
+func (siw *ServerInterfaceWrapper) PostMusicPlaylists(c *gin.Context) {
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostMusicPlaylists(c)
+}
+
+// DeleteMusicPlaylistsName operation middleware
+func (siw *ServerInterfaceWrapper) DeleteMusicPlaylistsName(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter name: %s", err)})
+		return
+	}
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.DeleteMusicPlaylistsName(c, name)
+}
+
+// GetMusicPlaylistsName operation middleware
+func (siw *ServerInterfaceWrapper) GetMusicPlaylistsName(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter name: %s", err)})
+		return
+	}
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMusicPlaylistsNameParams
+
+	// ------------- Optional query parameter "guild_id" -------------
+	if paramValue := c.Query("guild_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "guild_id", c.Request.URL.Query(), &params.GuildId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter guild_id: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetMusicPlaylistsName(c, name, params)
+}
+
+// PutMusicPlaylistsName operation middleware
+func (siw *ServerInterfaceWrapper) PutMusicPlaylistsName(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter name: %s", err)})
+		return
+	}
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PutMusicPlaylistsName(c, name)
+}
+
+// PostMusicPlaylistsNameLoad operation middleware
+func (siw *ServerInterfaceWrapper) PostMusicPlaylistsNameLoad(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter name: %s", err)})
+		return
+	}
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostMusicPlaylistsNameLoadParams
+
+	// ------------- Optional query parameter "guild_id" -------------
+	if paramValue := c.Query("guild_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "guild_id", c.Request.URL.Query(), &params.GuildId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter guild_id: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostMusicPlaylistsNameLoad(c, name, params)
+}
+
 // PostMusicRadio operation middleware
 func (siw *ServerInterfaceWrapper) PostMusicRadio(c *gin.Context) {
 
@@ -129,6 +612,172 @@ func (siw *ServerInterfaceWrapper) GetMusicStatus(c *gin.Context) {
 	siw.Handler.GetMusicStatus(c)
 }
 
+// GetStatsArtists operation middleware
+func (siw *ServerInterfaceWrapper) GetStatsArtists(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetStatsArtistsParams
+
+	// ------------- Optional query parameter "window" -------------
+	if paramValue := c.Query("window"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "window", c.Request.URL.Query(), &params.Window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter window: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := c.Query("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter limit: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetStatsArtists(c, params)
+}
+
+// GetStatsRequesters operation middleware
+func (siw *ServerInterfaceWrapper) GetStatsRequesters(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetStatsRequestersParams
+
+	// ------------- Optional query parameter "window" -------------
+	if paramValue := c.Query("window"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "window", c.Request.URL.Query(), &params.Window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter window: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := c.Query("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter limit: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetStatsRequesters(c, params)
+}
+
+// GetStatsSongs operation middleware
+func (siw *ServerInterfaceWrapper) GetStatsSongs(c *gin.Context) {
+
+	var err error
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetStatsSongsParams
+
+	// ------------- Optional query parameter "window" -------------
+	if paramValue := c.Query("window"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "window", c.Request.URL.Query(), &params.Window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter window: %s", err)})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := c.Query("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter limit: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetStatsSongs(c, params)
+}
+
+// GetStatsUsersId operation middleware
+func (siw *ServerInterfaceWrapper) GetStatsUsersId(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter id: %s", err)})
+		return
+	}
+
+	c.Set(JWTScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetStatsUsersIdParams
+
+	// ------------- Optional query parameter "window" -------------
+	if paramValue := c.Query("window"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "window", c.Request.URL.Query(), &params.Window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": fmt.Sprintf("Invalid format for parameter window: %s", err)})
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetStatsUsersId(c, id, params)
+}
+
+// GetUsersMeLikes operation middleware
+func (siw *ServerInterfaceWrapper) GetUsersMeLikes(c *gin.Context) {
+
+	c.Set(JWTScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetUsersMeLikes(c)
+}
+
 // GinServerOptions provides options for the Gin server.
 type GinServerOptions struct {
 	BaseURL     string
@@ -147,17 +796,53 @@ func RegisterHandlersWithOptions(router *gin.Engine, si ServerInterface, options
 		HandlerMiddlewares: options.Middlewares,
 	}
 
+	router.DELETE(options.BaseURL+"/admin/bans", wrapper.DeleteAdminBans)
+
+	router.GET(options.BaseURL+"/admin/bans", wrapper.GetAdminBans)
+
+	router.POST(options.BaseURL+"/admin/bans", wrapper.PostAdminBans)
+
+	router.GET(options.BaseURL+"/admin/bans/audit", wrapper.GetAdminBansAudit)
+
+	router.GET(options.BaseURL+"/admin/library/export", wrapper.GetAdminLibraryExport)
+
+	router.POST(options.BaseURL+"/admin/library/import", wrapper.PostAdminLibraryImport)
+
 	router.POST(options.BaseURL+"/auth/token", wrapper.PostAuthToken)
 
 	router.POST(options.BaseURL+"/music/enqueue/:service/:kind", wrapper.PostMusicEnqueueServiceIdentifier)
 
+	router.GET(options.BaseURL+"/music/events", wrapper.GetMusicEvents)
+
 	router.POST(options.BaseURL+"/music/loop", wrapper.PostMusicLoop)
 
+	router.GET(options.BaseURL+"/music/playlists", wrapper.GetMusicPlaylists)
+
+	router.POST(options.BaseURL+"/music/playlists", wrapper.PostMusicPlaylists)
+
+	router.DELETE(options.BaseURL+"/music/playlists/:name", wrapper.DeleteMusicPlaylistsName)
+
+	router.GET(options.BaseURL+"/music/playlists/:name", wrapper.GetMusicPlaylistsName)
+
+	router.PUT(options.BaseURL+"/music/playlists/:name", wrapper.PutMusicPlaylistsName)
+
+	router.POST(options.BaseURL+"/music/playlists/:name/load", wrapper.PostMusicPlaylistsNameLoad)
+
 	router.POST(options.BaseURL+"/music/radio", wrapper.PostMusicRadio)
 
 	router.POST(options.BaseURL+"/music/skip", wrapper.PostMusicSkip)
 
 	router.GET(options.BaseURL+"/music/status", wrapper.GetMusicStatus)
 
+	router.GET(options.BaseURL+"/stats/artists", wrapper.GetStatsArtists)
+
+	router.GET(options.BaseURL+"/stats/requesters", wrapper.GetStatsRequesters)
+
+	router.GET(options.BaseURL+"/stats/songs", wrapper.GetStatsSongs)
+
+	router.GET(options.BaseURL+"/stats/users/:id", wrapper.GetStatsUsersId)
+
+	router.GET(options.BaseURL+"/users/me/likes", wrapper.GetUsersMeLikes)
+
 	return router
 }
@@ -23,18 +23,52 @@ type MusicHandler interface {
 	PostMusicRadio(c *gin.Context)
 	PostMusicSkip(c *gin.Context)
 	GetMusicStatus(c *gin.Context)
+	GetMusicEvents(c *gin.Context, params GetMusicEventsParams)
+	GetMusicPlaylists(c *gin.Context, params GetMusicPlaylistsParams)
+	PostMusicPlaylists(c *gin.Context)
+	GetMusicPlaylistsName(c *gin.Context, name string, params GetMusicPlaylistsNameParams)
+	PutMusicPlaylistsName(c *gin.Context, name string)
+	DeleteMusicPlaylistsName(c *gin.Context, name string)
+	PostMusicPlaylistsNameLoad(c *gin.Context, name string, params PostMusicPlaylistsNameLoadParams)
+}
+
+type UsersHandler interface {
+	GetUsersMeLikes(c *gin.Context)
+}
+
+type AdminHandler interface {
+	GetAdminBans(c *gin.Context)
+	PostAdminBans(c *gin.Context)
+	DeleteAdminBans(c *gin.Context, params DeleteAdminBansParams)
+	GetAdminBansAudit(c *gin.Context, params GetAdminBansAuditParams)
+	GetAdminLibraryExport(c *gin.Context, params GetAdminLibraryExportParams)
+	PostAdminLibraryImport(c *gin.Context, params PostAdminLibraryImportParams)
+	AdminOnly() gin.HandlerFunc
+}
+
+type StatsHandler interface {
+	GetStatsSongs(c *gin.Context, params GetStatsSongsParams)
+	GetStatsArtists(c *gin.Context, params GetStatsArtistsParams)
+	GetStatsRequesters(c *gin.Context, params GetStatsRequestersParams)
+	GetStatsUsersId(c *gin.Context, id string, params GetStatsUsersIdParams)
 }
 
 type Server struct {
 	router *gin.Engine
 	MusicHandler
 	LoginHandler
+	UsersHandler
+	AdminHandler
+	StatsHandler
 }
 
-func NewServer(music MusicHandler, login LoginHandler) *Server {
+func NewServer(music MusicHandler, login LoginHandler, users UsersHandler, admin AdminHandler, stats StatsHandler) *Server {
 	return &Server{
 		MusicHandler: music,
 		LoginHandler: login,
+		UsersHandler: users,
+		AdminHandler: admin,
+		StatsHandler: stats,
 		router:       gin.New(),
 	}
 }
@@ -76,6 +110,26 @@ func (s *Server) RegisterHandlers() {
 	api.POST("/music/loop", wrapper.PostMusicLoop)
 	api.POST("/music/radio", wrapper.PostMusicRadio)
 	api.POST("/music/skip", wrapper.PostMusicSkip)
+	api.GET("/music/events", wrapper.GetMusicEvents)
+	api.GET("/music/playlists", wrapper.GetMusicPlaylists)
+	api.POST("/music/playlists", wrapper.PostMusicPlaylists)
+	api.GET("/music/playlists/:name", wrapper.GetMusicPlaylistsName)
+	api.PUT("/music/playlists/:name", wrapper.PutMusicPlaylistsName)
+	api.DELETE("/music/playlists/:name", wrapper.DeleteMusicPlaylistsName)
+	api.POST("/music/playlists/:name/load", wrapper.PostMusicPlaylistsNameLoad)
+	api.GET("/users/me/likes", wrapper.GetUsersMeLikes)
+	api.GET("/stats/songs", wrapper.GetStatsSongs)
+	api.GET("/stats/artists", wrapper.GetStatsArtists)
+	api.GET("/stats/requesters", wrapper.GetStatsRequesters)
+	api.GET("/stats/users/:id", wrapper.GetStatsUsersId)
+
+	admin := api.Group("/admin", s.AdminOnly())
+	admin.GET("/bans", wrapper.GetAdminBans)
+	admin.POST("/bans", wrapper.PostAdminBans)
+	admin.DELETE("/bans", wrapper.DeleteAdminBans)
+	admin.GET("/bans/audit", wrapper.GetAdminBansAudit)
+	admin.GET("/library/export", wrapper.GetAdminLibraryExport)
+	admin.POST("/library/import", wrapper.PostAdminLibraryImport)
 }
 
 func CORS() gin.HandlerFunc {
@@ -83,7 +137,7 @@ func CORS() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
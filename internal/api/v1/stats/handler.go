@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	musicstats "github.com/HalvaPovidlo/halvabot-go/internal/music/stats"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type statsService interface {
+	TopSongs(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	TopArtists(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	TopRequesters(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	User(ctx context.Context, userID string, window musicstats.Window, n int) (*musicstats.UserStats, error)
+}
+
+type topFunc func(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+
+type Handler struct {
+	stats  statsService
+	logger *zap.Logger
+}
+
+func NewStatsHandler(stats statsService, logger *zap.Logger) *Handler {
+	return &Handler{
+		stats:  stats,
+		logger: logger,
+	}
+}
+
+func (h *Handler) GetStatsSongs(c *gin.Context, params v1.GetStatsSongsParams) {
+	h.top(c, h.stats.TopSongs, (*string)(params.Window), params.Limit)
+}
+
+func (h *Handler) GetStatsArtists(c *gin.Context, params v1.GetStatsArtistsParams) {
+	h.top(c, h.stats.TopArtists, (*string)(params.Window), params.Limit)
+}
+
+func (h *Handler) GetStatsRequesters(c *gin.Context, params v1.GetStatsRequestersParams) {
+	h.top(c, h.stats.TopRequesters, (*string)(params.Window), params.Limit)
+}
+
+func (h *Handler) GetStatsUsersId(c *gin.Context, id string, params v1.GetStatsUsersIdParams) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	window, err := parseWindow((*string)(params.Window))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	stats, err := h.stats.User(ctx, id, window, musicstats.DefaultLimit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, v1.UserStats{
+		Requests:   stats.Requests,
+		TopArtists: convertEntries(stats.TopArtists),
+		TopSongs:   convertEntries(stats.TopSongs),
+		UserId:     stats.UserID,
+		Window:     string(stats.Window),
+	})
+}
+
+func (h *Handler) top(c *gin.Context, top topFunc, windowName *string, limit *int) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	window, err := parseWindow(windowName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	n := musicstats.DefaultLimit
+	if limit != nil {
+		n = *limit
+	}
+	if n < 1 || n > musicstats.MaxLimit {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: "limit is out of range"})
+		return
+	}
+	entries, err := top(ctx, window, n)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, convertEntries(entries))
+}
+
+func (h *Handler) error(c *gin.Context, err error) {
+	if errors.Is(err, musicstats.ErrUnknownWindow) {
+		c.JSON(http.StatusBadRequest, v1.Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+}
+
+func parseWindow(name *string) (musicstats.Window, error) {
+	if name == nil {
+		return musicstats.WindowAll, nil
+	}
+	return musicstats.ParseWindow(*name)
+}
+
+func convertEntries(entries []musicstats.Entry) []v1.StatsEntry {
+	res := make([]v1.StatsEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, v1.StatsEntry{Count: e.Count, Id: e.ID, Name: e.Name})
+	}
+	return res
+}
@@ -11,12 +11,149 @@ const (
 	JWTScopes = "JWT.Scopes"
 )
 
+// Defines values for BanAuditAction.
+const (
+	BanAuditActionBan   BanAuditAction = "ban"
+	BanAuditActionUnban BanAuditAction = "unban"
+)
+
+// Defines values for BanKind.
+const (
+	BanKindArtist  BanKind = "artist"
+	BanKindChannel BanKind = "channel"
+	BanKindSong    BanKind = "song"
+)
+
+// Defines values for PlayEventType.
+const (
+	Complete PlayEventType = "complete"
+	Play     PlayEventType = "play"
+	Skip     PlayEventType = "skip"
+)
+
+// Defines values for PlaylistVisibility.
+const (
+	Guild   PlaylistVisibility = "guild"
+	Private PlaylistVisibility = "private"
+)
+
 // Defines values for SongService.
 const (
 	Unknown SongService = "unknown"
 	Youtube SongService = "youtube"
 )
 
+// Defines values for LibraryFormat.
+const (
+	Csv  LibraryFormat = "csv"
+	Json LibraryFormat = "json"
+)
+
+// Defines values for LibraryKind.
+const (
+	Playlists LibraryKind = "playlists"
+	Songs     LibraryKind = "songs"
+	Users     LibraryKind = "users"
+)
+
+// Defines values for StatsWindow.
+const (
+	All   StatsWindow = "all"
+	Day   StatsWindow = "day"
+	Month StatsWindow = "month"
+	Week  StatsWindow = "week"
+)
+
+// Songs matching the ban are not played
+type Ban struct {
+	At time.Time `json:"at"`
+
+	// ID of the admin
+	By     string  `json:"by"`
+	Kind   BanKind `json:"kind"`
+	Reason *string `json:"reason,omitempty"`
+	Value  string  `json:"value"`
+}
+
+// BanAudit defines model for BanAudit.
+type BanAudit struct {
+	Action BanAuditAction `json:"action"`
+	At     time.Time      `json:"at"`
+	By     string         `json:"by"`
+	Kind   BanKind        `json:"kind"`
+	Reason *string        `json:"reason,omitempty"`
+
+	// Number of flagged or unflagged songs
+	Songs int    `json:"songs"`
+	Value string `json:"value"`
+}
+
+// BanAuditAction defines model for BanAudit.Action.
+type BanAuditAction string
+
+// BanKind defines model for BanKind.
+type BanKind string
+
+// ImportResult defines model for ImportResult.
+type ImportResult struct {
+	Playlists int `json:"playlists"`
+	Songs     int `json:"songs"`
+	UserSongs int `json:"user_songs"`
+}
+
+// Song explicitly liked by the user
+type Like struct {
+	At    time.Time `json:"at"`
+	Id    string    `json:"id"`
+	Title string    `json:"title"`
+	Url   string    `json:"url"`
+}
+
+// Record of the playback history
+type PlayEvent struct {
+	At time.Time `json:"at"`
+
+	// Seconds
+	Duration float32 `json:"duration"`
+	GuildId  *string `json:"guild_id,omitempty"`
+
+	// Seconds played
+	Position float32 `json:"position"`
+
+	// Empty for radio songs
+	RequesterId *string       `json:"requester_id,omitempty"`
+	SongId      string        `json:"song_id"`
+	StartedAt   time.Time     `json:"started_at"`
+	Title       string        `json:"title"`
+	Type        PlayEventType `json:"type"`
+}
+
+// PlayEventType defines model for PlayEvent.Type.
+type PlayEventType string
+
+// Named list of songs in playing order
+type Playlist struct {
+	Created time.Time      `json:"created"`
+	GuildId *string        `json:"guild_id,omitempty"`
+	Name    string         `json:"name"`
+	OwnerId string         `json:"owner_id"`
+	Songs   []PlaylistSong `json:"songs"`
+	Updated time.Time      `json:"updated"`
+
+	// Private playlists are seen only by the owner, guild ones by everyone in the guild
+	Visibility PlaylistVisibility `json:"visibility"`
+}
+
+// PlaylistSong defines model for PlaylistSong.
+type PlaylistSong struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Url   string `json:"url"`
+}
+
+// Private playlists are seen only by the owner, guild ones by everyone in the guild
+type PlaylistVisibility string
+
 // The object that describes a song
 type Song struct {
 	ArtistName   string      `json:"artist_name"`
@@ -33,6 +170,34 @@ type Song struct {
 // SongService defines model for Song.Service.
 type SongService string
 
+// Song, artist or user with the number of requests
+type StatsEntry struct {
+	Count int    `json:"count"`
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+}
+
+// UserStats defines model for UserStats.
+type UserStats struct {
+	Requests   int          `json:"requests"`
+	TopArtists []StatsEntry `json:"top_artists"`
+	TopSongs   []StatsEntry `json:"top_songs"`
+	UserId     string       `json:"user_id"`
+	Window     string       `json:"window"`
+}
+
+// LibraryFormat defines model for LibraryFormat.
+type LibraryFormat string
+
+// LibraryKind defines model for LibraryKind.
+type LibraryKind string
+
+// StatsLimit defines model for StatsLimit.
+type StatsLimit = int
+
+// StatsWindow defines model for StatsWindow.
+type StatsWindow string
+
 // Error defines model for Error.
 type Error struct {
 	Msg string `json:"msg"`
@@ -48,6 +213,57 @@ type EnableMode struct {
 	Enable *bool `binding:"required" json:"enable,omitempty"`
 }
 
+// DeleteAdminBansParams defines parameters for DeleteAdminBans.
+type DeleteAdminBansParams struct {
+	Kind  BanKind `form:"kind" json:"kind"`
+	Value string  `form:"value" json:"value"`
+}
+
+// PostAdminBansJSONBody defines parameters for PostAdminBans.
+type PostAdminBansJSONBody struct {
+	Kind   BanKind `json:"kind"`
+	Reason *string `json:"reason,omitempty"`
+
+	// Song ID or URL, artist name, channel ID or URL
+	Value string `binding:"required" json:"value"`
+}
+
+// GetAdminBansAuditParams defines parameters for GetAdminBansAudit.
+type GetAdminBansAuditParams struct {
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetAdminLibraryExportParams defines parameters for GetAdminLibraryExport.
+type GetAdminLibraryExportParams struct {
+	Format *GetAdminLibraryExportParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// Part of the library, the whole library by default
+	Kind *GetAdminLibraryExportParamsKind `form:"kind,omitempty" json:"kind,omitempty"`
+}
+
+// GetAdminLibraryExportParamsFormat defines parameters for GetAdminLibraryExport.
+type GetAdminLibraryExportParamsFormat string
+
+// GetAdminLibraryExportParamsKind defines parameters for GetAdminLibraryExport.
+type GetAdminLibraryExportParamsKind string
+
+// PostAdminLibraryImportJSONBody defines parameters for PostAdminLibraryImport.
+type PostAdminLibraryImportJSONBody = map[string]interface{}
+
+// PostAdminLibraryImportParams defines parameters for PostAdminLibraryImport.
+type PostAdminLibraryImportParams struct {
+	Format *PostAdminLibraryImportParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// Part of the library, the whole library by default
+	Kind *PostAdminLibraryImportParamsKind `form:"kind,omitempty" json:"kind,omitempty"`
+}
+
+// PostAdminLibraryImportParamsFormat defines parameters for PostAdminLibraryImport.
+type PostAdminLibraryImportParamsFormat string
+
+// PostAdminLibraryImportParamsKind defines parameters for PostAdminLibraryImport.
+type PostAdminLibraryImportParamsKind string
+
 // PostAuthTokenJSONBody defines parameters for PostAuthToken.
 type PostAuthTokenJSONBody struct {
 	// Case-insensitive
@@ -62,6 +278,106 @@ type PostMusicEnqueueServiceIdentifierJSONBody struct {
 	Input string `binding:"required" json:"input"`
 }
 
+// GetMusicEventsParams defines parameters for GetMusicEvents.
+type GetMusicEventsParams struct {
+	// Inclusive start of the time range
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// Exclusive end of the time range
+	To          *time.Time `form:"to,omitempty" json:"to,omitempty"`
+	GuildId     *string    `form:"guild_id,omitempty" json:"guild_id,omitempty"`
+	RequesterId *string    `form:"requester_id,omitempty" json:"requester_id,omitempty"`
+
+	// Song ID as returned in events, e.g. youtube_dQw4w9WgXcQ
+	SongId *string                   `form:"song_id,omitempty" json:"song_id,omitempty"`
+	Type   *GetMusicEventsParamsType `form:"type,omitempty" json:"type,omitempty"`
+	Limit  *int                      `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetMusicEventsParamsType defines parameters for GetMusicEvents.
+type GetMusicEventsParamsType string
+
+// GetMusicPlaylistsParams defines parameters for GetMusicPlaylists.
+type GetMusicPlaylistsParams struct {
+	GuildId *string `form:"guild_id,omitempty" json:"guild_id,omitempty"`
+}
+
+// PostMusicPlaylistsJSONBody defines parameters for PostMusicPlaylists.
+type PostMusicPlaylistsJSONBody struct {
+	GuildId *string `json:"guild_id,omitempty"`
+
+	// Queries or URLs of songs in playing order
+	Inputs *[]string `json:"inputs,omitempty"`
+	Name   string    `binding:"required" json:"name"`
+
+	// Private playlists are seen only by the owner, guild ones by everyone in the guild
+	Visibility *PlaylistVisibility `json:"visibility,omitempty"`
+}
+
+// GetMusicPlaylistsNameParams defines parameters for GetMusicPlaylistsName.
+type GetMusicPlaylistsNameParams struct {
+	GuildId *string `form:"guild_id,omitempty" json:"guild_id,omitempty"`
+}
+
+// PutMusicPlaylistsNameJSONBody defines parameters for PutMusicPlaylistsName.
+type PutMusicPlaylistsNameJSONBody struct {
+	// IDs of the playlist songs in the new order, missing songs are removed
+	Songs *[]string `json:"songs,omitempty"`
+
+	// Private playlists are seen only by the owner, guild ones by everyone in the guild
+	Visibility *PlaylistVisibility `json:"visibility,omitempty"`
+}
+
+// PostMusicPlaylistsNameLoadParams defines parameters for PostMusicPlaylistsNameLoad.
+type PostMusicPlaylistsNameLoadParams struct {
+	GuildId *string `form:"guild_id,omitempty" json:"guild_id,omitempty"`
+}
+
+// GetStatsArtistsParams defines parameters for GetStatsArtists.
+type GetStatsArtistsParams struct {
+	// Time window, the whole time by default
+	Window *GetStatsArtistsParamsWindow `form:"window,omitempty" json:"window,omitempty"`
+	Limit  *StatsLimit                  `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetStatsArtistsParamsWindow defines parameters for GetStatsArtists.
+type GetStatsArtistsParamsWindow string
+
+// GetStatsRequestersParams defines parameters for GetStatsRequesters.
+type GetStatsRequestersParams struct {
+	// Time window, the whole time by default
+	Window *GetStatsRequestersParamsWindow `form:"window,omitempty" json:"window,omitempty"`
+	Limit  *StatsLimit                     `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetStatsRequestersParamsWindow defines parameters for GetStatsRequesters.
+type GetStatsRequestersParamsWindow string
+
+// GetStatsSongsParams defines parameters for GetStatsSongs.
+type GetStatsSongsParams struct {
+	// Time window, the whole time by default
+	Window *GetStatsSongsParamsWindow `form:"window,omitempty" json:"window,omitempty"`
+	Limit  *StatsLimit                `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetStatsSongsParamsWindow defines parameters for GetStatsSongs.
+type GetStatsSongsParamsWindow string
+
+// GetStatsUsersIdParams defines parameters for GetStatsUsersId.
+type GetStatsUsersIdParams struct {
+	// Time window, the whole time by default
+	Window *GetStatsUsersIdParamsWindow `form:"window,omitempty" json:"window,omitempty"`
+}
+
+// GetStatsUsersIdParamsWindow defines parameters for GetStatsUsersId.
+type GetStatsUsersIdParamsWindow string
+
+// PostAdminBansJSONRequestBody defines body for PostAdminBans for application/json ContentType.
+type PostAdminBansJSONRequestBody PostAdminBansJSONBody
+
+// PostAdminLibraryImportJSONRequestBody defines body for PostAdminLibraryImport for application/json ContentType.
+type PostAdminLibraryImportJSONRequestBody = PostAdminLibraryImportJSONBody
+
 // PostAuthTokenJSONRequestBody defines body for PostAuthToken for application/json ContentType.
 type PostAuthTokenJSONRequestBody PostAuthTokenJSONBody
 
@@ -71,5 +387,11 @@ type PostMusicEnqueueServiceIdentifierJSONRequestBody PostMusicEnqueueServiceIde
 // PostMusicLoopJSONRequestBody defines body for PostMusicLoop for application/json ContentType.
 type PostMusicLoopJSONRequestBody EnableMode
 
+// PostMusicPlaylistsJSONRequestBody defines body for PostMusicPlaylists for application/json ContentType.
+type PostMusicPlaylistsJSONRequestBody PostMusicPlaylistsJSONBody
+
+// PutMusicPlaylistsNameJSONRequestBody defines body for PutMusicPlaylistsName for application/json ContentType.
+type PutMusicPlaylistsNameJSONRequestBody PutMusicPlaylistsNameJSONBody
+
 // PostMusicRadioJSONRequestBody defines body for PostMusicRadio for application/json ContentType.
 type PostMusicRadioJSONRequestBody EnableMode
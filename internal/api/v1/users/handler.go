@@ -0,0 +1,44 @@
+package users
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	v1 "github.com/HalvaPovidlo/halvabot-go/internal/api/v1"
+	"github.com/HalvaPovidlo/halvabot-go/internal/api/v1/login"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type likesService interface {
+	List(ctx context.Context, userID string) ([]*pkg.Like, error)
+}
+
+type Handler struct {
+	likes  likesService
+	logger *zap.Logger
+}
+
+func NewUsersHandler(likes likesService, logger *zap.Logger) *Handler {
+	return &Handler{
+		likes:  likes,
+		logger: logger,
+	}
+}
+
+func (h *Handler) GetUsersMeLikes(c *gin.Context) {
+	ctx := contexts.WithValues(c, h.logger, "")
+	likes, err := h.likes.List(ctx, c.GetString(login.UserID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, v1.Error{Msg: err.Error()})
+		return
+	}
+	res := make([]v1.Like, 0, len(likes))
+	for _, like := range likes {
+		res = append(res, v1.Like{At: like.At, Id: like.ID.String(), Title: like.Title, Url: like.URL})
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"net/url"
 	"sync"
 	"time"
 
@@ -50,13 +51,21 @@ func (p *Player) Process(requests <-chan *SongRequest) <-chan error {
 		defer close(out)
 		for req := range requests {
 			err := p.play(req.Voice, req.URI)
-			p.files.Remove(req.URI) // this is might be bad if stream option is enabled
+			if !isRemote(req.URI) {
+				p.files.Remove(req.URI)
+			}
 			out <- err
 		}
 	}()
 	return out
 }
 
+// isRemote reports whether the song is streamed instead of played from the downloaded file
+func isRemote(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Host != ""
+}
+
 func (p *Player) Stop() {
 	if p.IsPlaying() {
 		p.done <- ErrManualStop
@@ -0,0 +1,116 @@
+package discord
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const banAuditLength = 10
+
+type Bans interface {
+	Ban(ctx context.Context, kind pkg.BanKind, value, reason, by string) (*pkg.Ban, int, error)
+	Unban(ctx context.Context, kind pkg.BanKind, value, by string) (*pkg.Ban, int, error)
+	List() []*pkg.Ban
+	Audit(ctx context.Context, limit int) ([]*pkg.BanAudit, error)
+}
+
+// banMessageHandler runs "ban song|artist|channel [value] [| reason]", "ban list" and "ban log"
+func (s *Service) banMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	if !s.isAdmin(m.Author.ID) {
+		s.sendNotAdminMessage(ctx, ds, m)
+		return
+	}
+	args, reason, _ := strings.Cut(strings.TrimPrefix(m.Content, s.prefix+ban), "|")
+	kind, value := banArgs(args)
+	switch kind {
+	case "list":
+		s.sendBansMessage(ctx, ds, m, s.bans.List())
+		return
+	case "log":
+		entries, err := s.bans.Audit(ctx, banAuditLength)
+		if err != nil {
+			s.sendBanError(ctx, ds, m, err)
+			return
+		}
+		s.sendBanAuditMessage(ctx, ds, m, entries)
+		return
+	}
+	value = s.banValue(kind, value)
+	b, n, err := s.bans.Ban(ctx, kind, value, strings.TrimSpace(reason), m.Author.ID)
+	if err != nil {
+		s.sendBanError(ctx, ds, m, err)
+		return
+	}
+	if song := s.player.NowPlaying(); song != nil && b.Match(song) {
+		s.player.Skip(ctx)
+	}
+	s.sendBanMessage(ctx, ds, m, messageBanned, b, n)
+}
+
+// unbanMessageHandler runs "unban song|artist|channel [value]"
+func (s *Service) unbanMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	if !s.isAdmin(m.Author.ID) {
+		s.sendNotAdminMessage(ctx, ds, m)
+		return
+	}
+	kind, value := banArgs(strings.TrimPrefix(m.Content, s.prefix+unban))
+	b, n, err := s.bans.Unban(ctx, kind, s.banValue(kind, value), m.Author.ID)
+	if err != nil {
+		s.sendBanError(ctx, ds, m, err)
+		return
+	}
+	s.sendBanMessage(ctx, ds, m, messageUnbanned, b, n)
+}
+
+func banArgs(args string) (pkg.BanKind, string) {
+	kind, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	return pkg.BanKind(kind), strings.TrimSpace(value)
+}
+
+// banValue returns the value or the one of the current song if the value is empty
+func (s *Service) banValue(kind pkg.BanKind, value string) string {
+	if value != "" {
+		return value
+	}
+	song := s.player.NowPlaying()
+	if song == nil {
+		return ""
+	}
+	switch kind {
+	case pkg.BanSong:
+		return song.ID.String()
+	case pkg.BanArtist:
+		return song.ArtistName
+	case pkg.BanChannel:
+		return song.ArtistURL
+	}
+	return ""
+}
+
+func (s *Service) sendBanError(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	var msg string
+	switch {
+	case errors.Is(err, moderation.ErrInvalidBan):
+		s.sendBanUsageMessage(ctx, ds, m)
+		return
+	case errors.Is(err, moderation.ErrAlreadyBanned):
+		msg = messageAlreadyBanned
+	case errors.Is(err, moderation.ErrBanNotFound):
+		msg = messageBanNotFound
+	default:
+		contexts.GetLogger(ctx).Error("ban command", zap.String("command", m.Content), zap.Error(err))
+		s.sendInternalErrorMessage(ctx, ds, m, statusLevel)
+		return
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
@@ -0,0 +1,44 @@
+package discord
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type Library interface {
+	Dedup(ctx context.Context, titles, dryRun bool) (*storage.DedupResult, error)
+}
+
+// dedupMessageHandler previews duplicate songs with "dedup [titles]" and merges them with "dedup merge [titles]"
+func (s *Service) dedupMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	if !s.isAdmin(m.Author.ID) {
+		s.sendNotAdminMessage(ctx, ds, m)
+		return
+	}
+	merge, titles := false, false
+	for _, arg := range strings.Fields(strings.TrimPrefix(m.Content, s.prefix+dedup)) {
+		switch arg {
+		case "merge":
+			merge = true
+		case "titles":
+			titles = true
+		default:
+			s.sendDedupUsageMessage(ctx, ds, m)
+			return
+		}
+	}
+	res, err := s.library.Dedup(ctx, titles, !merge)
+	if err != nil {
+		contexts.GetLogger(ctx).Error("dedup songs", zap.Bool("merge", merge), zap.Error(err))
+		s.sendInternalErrorMessage(ctx, ds, m, statusLevel)
+		return
+	}
+	s.sendDedupMessage(ctx, ds, m, res, merge, titles)
+}
@@ -0,0 +1,109 @@
+package discord
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	musiclikes "github.com/HalvaPovidlo/halvabot-go/internal/music/likes"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const (
+	likeEmoji = "❤️"
+	// likeableMessages is how many last now playing messages can be liked with the reaction
+	likeableMessages = 100
+)
+
+type Likes interface {
+	Like(ctx context.Context, userID string, song *pkg.Song) (*pkg.Like, error)
+	Unlike(ctx context.Context, userID string, song *pkg.Song) (*pkg.Song, error)
+}
+
+// likeMessageHandler adds the playing song to the author's likes
+func (s *Service) likeMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	like, err := s.likes.Like(ctx, m.Author.ID, nil)
+	if err != nil {
+		s.sendLikeError(ctx, ds, m, err)
+		return
+	}
+	s.sendLikeMessage(ctx, ds, m, messageLiked, like.Title)
+}
+
+// unlikeMessageHandler removes the playing song from the author's likes
+func (s *Service) unlikeMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	song, err := s.likes.Unlike(ctx, m.Author.ID, nil)
+	if err != nil {
+		s.sendLikeError(ctx, ds, m, err)
+		return
+	}
+	s.sendLikeMessage(ctx, ds, m, messageUnliked, song.Title)
+}
+
+// likeReactionHandler likes or unlikes the song of the now playing message
+func (s *Service) likeReactionHandler(ctx context.Context, ds *discordgo.Session, r *discordgo.MessageReaction, added bool) {
+	song := s.likeableSong(r.MessageID)
+	if song == nil {
+		return
+	}
+	var err error
+	if added {
+		_, err = s.likes.Like(ctx, r.UserID, song)
+	} else {
+		_, err = s.likes.Unlike(ctx, r.UserID, song)
+	}
+	if err != nil {
+		contexts.GetLogger(ctx).Error("like reaction",
+			zap.String("user", r.UserID),
+			zap.String("song", song.ID.String()),
+			zap.Bool("added", added),
+			zap.Error(err))
+	}
+}
+
+// rememberLikeable makes the message likeable, the oldest message is forgotten if there are too many
+func (s *Service) rememberLikeable(messageID string, song *pkg.Song) {
+	s.likeableMx.Lock()
+	defer s.likeableMx.Unlock()
+	if len(s.likeableOrder) >= likeableMessages {
+		delete(s.likeable, s.likeableOrder[0])
+		s.likeableOrder = s.likeableOrder[1:]
+	}
+	s.likeable[messageID] = song
+	s.likeableOrder = append(s.likeableOrder, messageID)
+}
+
+func (s *Service) likeableSong(messageID string) *pkg.Song {
+	s.likeableMx.Lock()
+	defer s.likeableMx.Unlock()
+	return s.likeable[messageID]
+}
+
+// voiceChannelUsers returns everyone in the voice channel except the bot
+func voiceChannelUsers(ds *discordgo.Session, guildID, channelID string) ([]string, error) {
+	guild, err := ds.State.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, voiceState := range guild.VoiceStates {
+		if voiceState.ChannelID == channelID && voiceState.UserID != ds.State.User.ID {
+			users = append(users, voiceState.UserID)
+		}
+	}
+	return users, nil
+}
+
+func (s *Service) sendLikeError(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	if errors.Is(err, musiclikes.ErrNoSong) {
+		s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageLikeNoSong), statusLevel)
+		return
+	}
+	contexts.GetLogger(ctx).Error("like command", zap.String("command", m.Content), zap.Error(err))
+	s.sendInternalErrorMessage(ctx, ds, m, statusLevel)
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	musicstats "github.com/HalvaPovidlo/halvabot-go/internal/music/stats"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/discord"
@@ -24,7 +30,50 @@ const (
 	messageLoopDisabled    = ":x: **Loop disabled**"
 	messageRadioEnabled    = ":white_check_mark: **Radio enabled**"
 	messageRadioDisabled   = ":x: **Radio disabled**"
+	messageRadioSeedEmpty  = ":x: **No songs found for this radio**"
+	messageRadioUsage      = ":radio: **Usage:** `%sradio`, `%sradio artist <name>`, `%sradio like [url]`, `%sradio user @someone` or `%sradio likes [@someone|all]`"
 	messageNotVoiceChannel = ":x: **You have to be in a voice channel to use this command**"
+	messageNotAdmin        = ":x: **Only admins can use this command**"
+	messageTooLong         = ":hourglass: **Song is too long**"
+	messageChannelBlocked  = ":no_entry: **This channel is blocked on the server**"
+	messageKeywordBlocked  = ":no_entry: **Song is blocked on the server**"
+	messageLiveNotAllowed  = ":red_circle: **Livestreams are not allowed**"
+	messageCachePurged     = ":wastebasket: **Search cache purged**"
+	messageDownloading     = ":arrow_down: **Downloading**"
+	messageDownloaded      = ":white_check_mark: **Downloaded**"
+	messageDownloadFailed  = ":x: **Download failed**"
+
+	messageLiked      = ":heart: **%s added to your likes**"
+	messageUnliked    = ":broken_heart: **%s removed from your likes**"
+	messageLikeNoSong = ":x: **Nothing is playing**"
+
+	messageBanned        = ":no_entry: **%s `%s` banned, %d songs flagged**"
+	messageUnbanned      = ":white_check_mark: **%s `%s` unbanned, %d songs unflagged**"
+	messageAlreadyBanned = ":x: **Already banned**"
+	messageBanNotFound   = ":x: **Ban not found**"
+	messageSongBanned    = ":no_entry: **Song is banned**"
+	messageBanUsage      = ":hammer: **Usage:** `%sban song|artist|channel [value] [| reason]`, `%sunban song|artist|channel [value]`, " +
+		"`%sban list` or `%sban log`"
+
+	messageDedupUsage   = ":broom: **Usage:** `%sdedup [titles]` to preview, `%sdedup merge [titles]` to merge duplicate songs"
+	messageWrappedUsage = ":gift: **Usage:** `%swrapped [year|year-month]`, for example `%swrapped 2024-12`"
+	messageStatsUsage   = ":bar_chart: **Usage:** `%stop songs|artists|requesters [day|week|month|all]` or `%sstats [@someone] [day|week|month|all]`"
+
+	messagePlaylistSaved    = ":floppy_disk: **Playlist `%s` saved with %d songs**"
+	messagePlaylistLoaded   = ":notes: **Playlist `%s`: enqueuing %d of %d songs**"
+	messagePlaylistAdded    = ":heavy_plus_sign: **%s added to `%s`**"
+	messagePlaylistRemoved  = ":heavy_minus_sign: **%s removed from `%s`**"
+	messagePlaylistShared   = ":busts_in_silhouette: **Playlist `%s` is shared with the server**"
+	messagePlaylistPrivate  = ":lock: **Playlist `%s` is private**"
+	messagePlaylistDeleted  = ":wastebasket: **Playlist `%s` deleted**"
+	messagePlaylistNotFound = ":x: **Playlist not found**"
+	messagePlaylistBadName  = ":x: **Playlist name may contain only letters, digits, - and _**"
+	messagePlaylistEmpty    = ":x: **Nothing to add to the playlist**"
+	messagePlaylistTooLong  = ":x: **Playlist is too long**"
+	messagePlaylistNoSong   = ":x: **No such song in the playlist**"
+	messagePlaylistBusy     = ":hourglass: **Too many playlists are being loaded, try again later**"
+	messagePlaylistUsage    = ":scroll: **Usage:** `%splaylist save|load|share|delete <name>`, " +
+		"`%splaylist add <name> [song]`, `%splaylist remove <name> <number>` or `%splaylist list`"
 )
 
 const (
@@ -47,8 +96,34 @@ func (s *Service) sendComplexMessage(ctx context.Context, session *dg.Session, c
 	}()
 }
 
-func (s *Service) sendSearchingMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
-	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageSearching), statusLevel)
+// sendSearchingMessage returns the context which edits the sent message with the download progress
+func (s *Service) sendSearchingMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) context.Context {
+	if s.toDelete(m.ChannelID, statusLevel) {
+		return ctx
+	}
+	logger := contexts.GetLogger(ctx)
+	msg, err := ds.ChannelMessageSend(m.ChannelID, messageSearching)
+	if err != nil {
+		logger.Error("sending message",
+			zap.String("channel", m.ChannelID),
+			zap.String("msg", messageSearching),
+			zap.Error(err))
+		return ctx
+	}
+	return youtube.WithProgressHandler(ctx, func(p youtube.Progress) {
+		var content string
+		switch {
+		case p.Err != nil:
+			content = fmt.Sprintf("%s `%s`", messageDownloadFailed, p.Title)
+		case p.Done:
+			content = fmt.Sprintf("%s `%s`", messageDownloaded, p.Title)
+		default:
+			content = fmt.Sprintf("%s `%s` %.0f%%", messageDownloading, p.Title, p.Percent())
+		}
+		if _, err := ds.ChannelMessageEdit(msg.ChannelID, msg.ID, content); err != nil {
+			logger.Error("editing message", zap.String("channel", msg.ChannelID), zap.Error(err))
+		}
+	})
 }
 
 func (s *Service) sendSkipMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
@@ -68,6 +143,23 @@ func (s *Service) sendAgeRestrictionMessage(ctx context.Context, ds *dg.Session,
 	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageAgeRestriction), statusLevel)
 }
 
+func (s *Service) sendLimitMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, limitErr *player.LimitError) {
+	var msg string
+	switch {
+	case errors.Is(limitErr, player.ErrSongTooLong):
+		msg = fmt.Sprintf("%s `max %s`", messageTooLong, limitErr.Value)
+	case errors.Is(limitErr, player.ErrChannelBlocked):
+		msg = messageChannelBlocked
+	case errors.Is(limitErr, player.ErrKeywordBlocked):
+		msg = messageKeywordBlocked
+	case errors.Is(limitErr, player.ErrLiveNotAllowed):
+		msg = messageLiveNotAllowed
+	default:
+		msg = messageKeywordBlocked
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
 func (s *Service) sendLoopMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, enabled bool) {
 	if enabled {
 		s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageLoopEnabled), statusLevel)
@@ -84,6 +176,253 @@ func (s *Service) sendRadioMessage(ctx context.Context, ds *dg.Session, m *dg.Me
 	}
 }
 
+func (s *Service) sendRadioSeedEmptyMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageRadioSeedEmpty), statusLevel)
+}
+
+func (s *Service) sendRadioUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messageRadioUsage, s.prefix, s.prefix, s.prefix, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
+func (s *Service) sendLikeMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, format, title string) {
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(fmt.Sprintf(format, title)), statusLevel)
+}
+
+func (s *Service) sendBanMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, format string, ban *pkg.Ban, n int) {
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(fmt.Sprintf(format, ban.Kind, ban.Value, n)), statusLevel)
+}
+
+func (s *Service) sendBanUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messageBanUsage, s.prefix, s.prefix, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
+func (s *Service) sendSongBannedMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, ban *pkg.Ban) {
+	msg := messageSongBanned
+	if ban.Reason != "" {
+		msg += fmt.Sprintf(" `%s`", ban.Reason)
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
+func (s *Service) sendBansMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, bans []*pkg.Ban) {
+	if len(bans) == 0 {
+		s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageBanNotFound), infoLevel)
+		return
+	}
+	fields := make([]*dg.MessageEmbedField, 0, len(bans))
+	for _, ban := range bans {
+		value := fmt.Sprintf("by <@%s>", ban.By)
+		if ban.Reason != "" {
+			value = ban.Reason + ", " + value
+		}
+		fields = append(fields, &dg.MessageEmbedField{Name: fmt.Sprintf("%s %s", ban.Kind, ban.Value), Value: value})
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{Title: "Bans", Fields: fields}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
+func (s *Service) sendBanAuditMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, entries []*pkg.BanAudit) {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("`%s` <@%s> %s %s `%s` (%d songs)",
+			e.At.Format("2006-01-02 15:04"), e.By, e.Action, e.Kind, e.Value, e.Songs))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "empty")
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{Title: "Ban log", Description: strings.Join(lines, "\n")}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
+func (s *Service) sendStatsUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messageStatsUsage, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), infoLevel)
+}
+
+// sendTopMessage sends the leaderboard, IDs of entries are mentioned if they are users
+func (s *Service) sendTopMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, title string, window musicstats.Window, entries []musicstats.Entry, users bool) {
+	lines := make([]string, 0, len(entries))
+	for i, e := range entries {
+		name := e.Name
+		if users {
+			name = fmt.Sprintf("<@%s>", e.ID)
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s `%d`", i+1, name, e.Count))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "empty")
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{Title: fmt.Sprintf("%s (%s)", title, window), Description: strings.Join(lines, "\n")}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
+func (s *Service) sendUserStatsMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, stats *musicstats.UserStats) {
+	entries := func(list []musicstats.Entry) string {
+		names := make([]string, 0, len(list))
+		for _, e := range list {
+			names = append(names, fmt.Sprintf("%s `%d`", e.Name, e.Count))
+		}
+		if len(names) == 0 {
+			return "-"
+		}
+		return strings.Join(names, "\n")
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{
+			Title:       fmt.Sprintf("Stats (%s)", stats.Window),
+			Description: fmt.Sprintf("<@%s> requested `%d` songs", stats.UserID, stats.Requests),
+			Fields: []*dg.MessageEmbedField{
+				{Name: "Songs", Value: entries(stats.TopSongs), Inline: true},
+				{Name: "Artists", Value: entries(stats.TopArtists), Inline: true},
+			},
+		}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
+func (s *Service) sendDedupUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messageDedupUsage, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
+// sendDedupMessage lists the merged or the found groups of duplicates
+func (s *Service) sendDedupMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, res *storage.DedupResult, merged, titles bool) {
+	const maxGroups = 15
+	lines := make([]string, 0, maxGroups+1)
+	for i, group := range res.Groups {
+		if i == maxGroups {
+			lines = append(lines, fmt.Sprintf("and %d more", len(res.Groups)-maxGroups))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s `x%d`", group.Reason, group.Songs[0].Title, len(group.Songs)))
+	}
+	title := "Duplicate songs"
+	footer := fmt.Sprintf("%d songs and %d user songs to merge, run %sdedup merge", res.Removed, res.UserSongs, s.prefix)
+	if titles {
+		footer += " titles"
+	}
+	if merged {
+		title = "Merged songs"
+		footer = fmt.Sprintf("%d songs and %d user songs merged", res.Removed, res.UserSongs)
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{
+			Title:       fmt.Sprintf(":broom: %s", title),
+			Description: joinOrDash(lines),
+			Footer:      &dg.MessageEmbedFooter{Text: footer},
+		}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, statusLevel)
+}
+
+func (s *Service) sendWrappedUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messageWrappedUsage, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), infoLevel)
+}
+
+// wrappedMessage is the guild recap followed by personal recaps of the users,
+// the top listeners are taken if no users are passed
+func wrappedMessage(recap *musicstats.Recap, users ...string) *dg.MessageSend {
+	guild := recap.Guild
+	listeners := make([]string, 0, len(recap.Listeners))
+	for i, e := range recap.Listeners {
+		if i == musicstats.DefaultLimit {
+			break
+		}
+		listeners = append(listeners, fmt.Sprintf("%d. <@%s> `%d min`", i+1, e.ID, e.Count))
+	}
+	embeds := []*dg.MessageEmbed{{
+		Title: fmt.Sprintf(":gift: Wrapped %s", recap.Period.Name),
+		Description: fmt.Sprintf("`%d` songs requested, `%d` minutes played, `%d` songs heard for the first time",
+			guild.Requests, guild.Minutes, guild.NewSongs),
+		Fields: append(wrappedFields(guild), &dg.MessageEmbedField{Name: "Listeners", Value: joinOrDash(listeners)}),
+	}}
+	if len(users) == 0 {
+		for i, e := range recap.Listeners {
+			if i == wrappedListeners {
+				break
+			}
+			users = append(users, e.ID)
+		}
+	}
+	for _, id := range users {
+		summary, ok := recap.Users[id]
+		if !ok {
+			continue
+		}
+		embeds = append(embeds, &dg.MessageEmbed{
+			Description: fmt.Sprintf("<@%s> requested `%d` songs, `%d` minutes played, `%d` new songs",
+				id, summary.Requests, summary.Minutes, summary.NewSongs),
+			Fields: wrappedFields(summary),
+		})
+	}
+	return &dg.MessageSend{Embeds: embeds}
+}
+
+func wrappedFields(summary *musicstats.Summary) []*dg.MessageEmbedField {
+	entries := func(list []musicstats.Entry, counts bool) string {
+		lines := make([]string, 0, len(list))
+		for i, e := range list {
+			line := fmt.Sprintf("%d. %s", i+1, e.Name)
+			if counts {
+				line += fmt.Sprintf(" `%d`", e.Count)
+			}
+			lines = append(lines, line)
+		}
+		return joinOrDash(lines)
+	}
+	return []*dg.MessageEmbedField{
+		{Name: "Top songs", Value: entries(summary.TopSongs, true), Inline: true},
+		{Name: "Top artists", Value: entries(summary.TopArtists, true), Inline: true},
+		{Name: "First requests", Value: entries(summary.FirstRequests, false)},
+	}
+}
+
+func joinOrDash(lines []string) string {
+	if len(lines) == 0 {
+		return "-"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *Service) sendPlaylistMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, format string, args ...interface{}) {
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(fmt.Sprintf(format, args...)), statusLevel)
+}
+
+func (s *Service) sendPlaylistUsageMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	msg := fmt.Sprintf(messagePlaylistUsage, s.prefix, s.prefix, s.prefix, s.prefix)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
+func (s *Service) sendPlaylistsMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, list []*pkg.Playlist) {
+	if len(list) == 0 {
+		s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messagePlaylistNotFound), infoLevel)
+		return
+	}
+	fields := make([]*dg.MessageEmbedField, 0, len(list))
+	for _, p := range list {
+		value := fmt.Sprintf("%d songs", len(p.Songs))
+		if p.OwnerID != m.Author.ID {
+			value += fmt.Sprintf(", by <@%s>", p.OwnerID)
+		} else if p.Visibility == pkg.PlaylistGuild {
+			value += ", shared"
+		}
+		fields = append(fields, &dg.MessageEmbedField{Name: p.Name, Value: value, Inline: true})
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{{Title: "Playlists", Fields: fields}},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
 func (s *Service) sendNotInVoiceWarning(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
 	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageNotVoiceChannel), statusLevel)
 }
@@ -123,7 +462,26 @@ func (s *Service) sendNowPlayingMessage(ctx context.Context, ds *dg.Session, m *
 			},
 		},
 	}
-	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+	s.sendLikeableMessage(ctx, ds, m.ChannelID, msg, infoLevel, song)
+}
+
+// sendLikeableMessage sends the message with the heart reaction which likes the song
+func (s *Service) sendLikeableMessage(ctx context.Context, ds *dg.Session, channelID string, msg *dg.MessageSend, level int, song *pkg.Song) {
+	if s.toDelete(channelID, level) {
+		return
+	}
+	go func() {
+		logger := contexts.GetLogger(ctx)
+		sent, err := ds.ChannelMessageSendComplex(channelID, msg)
+		if err != nil {
+			logger.Error("sending message", zap.String("channel", channelID), zap.Error(err))
+			return
+		}
+		s.rememberLikeable(sent.ID, song)
+		if err := ds.MessageReactionAdd(channelID, sent.ID, likeEmoji); err != nil {
+			logger.Error("adding like reaction", zap.String("channel", channelID), zap.Error(err))
+		}
+	}()
 }
 
 func (s *Service) sendRandomMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, songs []*pkg.Song) {
@@ -138,6 +496,58 @@ func (s *Service) sendRandomMessage(ctx context.Context, ds *dg.Session, m *dg.M
 	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), infoLevel)
 }
 
+func (s *Service) sendQuotaMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, stats youtube.QuotaStats, cache youtube.CacheStats) {
+	status := ":white_check_mark: available"
+	if stats.Exhausted {
+		status = ":x: exhausted, searching without API"
+	}
+	msg := &dg.MessageSend{
+		Embeds: []*dg.MessageEmbed{
+			{
+				Title: "YouTube API quota",
+				Fields: []*dg.MessageEmbedField{
+					{
+						Name:   "Used",
+						Value:  fmt.Sprintf("%d / %d", stats.Used, stats.Limit),
+						Inline: true,
+					},
+					{
+						Name:   "Status",
+						Value:  status,
+						Inline: true,
+					},
+					{
+						Name:   "Searches",
+						Value:  fmt.Sprintf("api: %d, fallback: %d", stats.Searches, stats.Fallbacks),
+						Inline: false,
+					},
+					{
+						Name: "Query cache",
+						Value: fmt.Sprintf("hit rate: %.0f%% (hits: %d, misses: %d, expired: %d)",
+							cache.HitRate()*100, cache.Hits, cache.Misses, cache.Expired),
+						Inline: false,
+					},
+					{
+						Name:   "Reset in",
+						Value:  time.Until(stats.ResetAt).Round(time.Minute).String(),
+						Inline: true,
+					},
+				},
+			},
+		},
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, msg, infoLevel)
+}
+
+func (s *Service) sendNotAdminMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate) {
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(messageNotAdmin), statusLevel)
+}
+
+func (s *Service) sendCachePurgedMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, n int) {
+	msg := fmt.Sprintf("%s `%d entries`", messageCachePurged, n)
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
+
 func (s *Service) sendInternalErrorMessage(ctx context.Context, ds *dg.Session, m *dg.MessageCreate, level int) {
 	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(discord.MessageInternalError), level)
 }
@@ -0,0 +1,193 @@
+package discord
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	musicplaylist "github.com/HalvaPovidlo/halvabot-go/internal/music/playlist"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type Playlists interface {
+	CurrentQueue() []*pkg.Song
+	Find(ctx context.Context, inputs []string) ([]*pkg.Song, error)
+	Save(ctx context.Context, ownerID, guildID, name string, songs []*pkg.Song) (*pkg.Playlist, error)
+	Add(ctx context.Context, ownerID, guildID, name string, song *pkg.Song) (*pkg.Playlist, error)
+	Remove(ctx context.Context, ownerID, name string, position int) (*pkg.PlaylistSong, error)
+	Share(ctx context.Context, ownerID, name string, visibility pkg.PlaylistVisibility) (*pkg.Playlist, error)
+	Delete(ctx context.Context, ownerID, name string) error
+	Get(ctx context.Context, userID, guildID, name string) (*pkg.Playlist, error)
+	List(ctx context.Context, userID, guildID string) ([]*pkg.Playlist, error)
+	Load(ctx context.Context, userID, guildID, channelID, name string) (*pkg.Playlist, int, error)
+}
+
+// playlistMessageHandler runs "playlist save|load|add|remove|list|share|delete" subcommands
+func (s *Service) playlistMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, statusLevel)
+	args := strings.Fields(strings.TrimPrefix(m.Content, s.prefix+playlist))
+	if len(args) == 0 {
+		s.sendPlaylistUsageMessage(ctx, ds, m)
+		return
+	}
+	if args[0] == "list" {
+		s.playlistList(ctx, ds, m)
+		return
+	}
+	if len(args) < 2 {
+		s.sendPlaylistUsageMessage(ctx, ds, m)
+		return
+	}
+	name, rest := args[1], args[2:]
+	var err error
+	switch args[0] {
+	case "save":
+		err = s.playlistSave(ctx, ds, m, name)
+	case "load":
+		err = s.playlistLoad(ctx, ds, m, name)
+	case "add":
+		err = s.playlistAdd(ctx, ds, m, name, strings.Join(rest, " "))
+	case "remove":
+		err = s.playlistRemove(ctx, ds, m, name, rest)
+	case "share":
+		err = s.playlistShare(ctx, ds, m, name)
+	case "delete":
+		if err = s.playlists.Delete(ctx, m.Author.ID, name); err == nil {
+			s.sendPlaylistMessage(ctx, ds, m, messagePlaylistDeleted, name)
+		}
+	default:
+		s.sendPlaylistUsageMessage(ctx, ds, m)
+	}
+	if err != nil {
+		s.sendPlaylistError(ctx, ds, m, err)
+	}
+}
+
+func (s *Service) playlistList(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	list, err := s.playlists.List(ctx, m.Author.ID, m.GuildID)
+	if err != nil {
+		s.sendPlaylistError(ctx, ds, m, err)
+		return
+	}
+	s.sendPlaylistsMessage(ctx, ds, m, list)
+}
+
+func (s *Service) playlistSave(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, name string) error {
+	p, err := s.playlists.Save(ctx, m.Author.ID, m.GuildID, name, s.playlists.CurrentQueue())
+	if err != nil {
+		return err
+	}
+	s.sendPlaylistMessage(ctx, ds, m, messagePlaylistSaved, p.Name, len(p.Songs))
+	return nil
+}
+
+func (s *Service) playlistLoad(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, name string) error {
+	channelID, err := findAuthorVoiceChannelID(ds, m)
+	if err != nil {
+		s.sendNotInVoiceWarning(ctx, ds, m)
+		contexts.GetLogger(ctx).Error("failed to find author's voice channel", zap.Error(err))
+		return nil
+	}
+	p, n, err := s.playlists.Load(ctx, m.Author.ID, m.GuildID, channelID, name)
+	if err != nil {
+		return err
+	}
+	s.sendPlaylistMessage(ctx, ds, m, messagePlaylistLoaded, p.Name, n, len(p.Songs))
+	return nil
+}
+
+// playlistAdd adds the found song or the current one if there is no query
+func (s *Service) playlistAdd(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, name, query string) error {
+	song := s.player.NowPlaying()
+	if query != "" {
+		songs, err := s.playlists.Find(ctx, []string{query})
+		if err != nil {
+			return err
+		}
+		song = songs[0]
+	}
+	if song == nil {
+		return musicplaylist.ErrEmpty
+	}
+	p, err := s.playlists.Add(ctx, m.Author.ID, m.GuildID, name, song)
+	if err != nil {
+		return err
+	}
+	s.sendPlaylistMessage(ctx, ds, m, messagePlaylistAdded, song.Title, p.Name)
+	return nil
+}
+
+func (s *Service) playlistRemove(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, name string, args []string) error {
+	if len(args) != 1 {
+		s.sendPlaylistUsageMessage(ctx, ds, m)
+		return nil
+	}
+	position, err := strconv.Atoi(args[0])
+	if err != nil {
+		return musicplaylist.ErrNoSong
+	}
+	song, err := s.playlists.Remove(ctx, m.Author.ID, name, position)
+	if err != nil {
+		return err
+	}
+	s.sendPlaylistMessage(ctx, ds, m, messagePlaylistRemoved, song.Title, name)
+	return nil
+}
+
+// playlistShare toggles between private and shared with the guild
+func (s *Service) playlistShare(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, name string) error {
+	p, err := s.playlists.Get(ctx, m.Author.ID, m.GuildID, name)
+	if err != nil {
+		return err
+	}
+	if p.OwnerID != m.Author.ID {
+		return musicplaylist.ErrNotFound
+	}
+	visibility := pkg.PlaylistGuild
+	if p.Visibility == pkg.PlaylistGuild {
+		visibility = pkg.PlaylistPrivate
+	}
+	if p, err = s.playlists.Share(ctx, m.Author.ID, name, visibility); err != nil {
+		return err
+	}
+	if p.Visibility == pkg.PlaylistGuild {
+		s.sendPlaylistMessage(ctx, ds, m, messagePlaylistShared, p.Name)
+	} else {
+		s.sendPlaylistMessage(ctx, ds, m, messagePlaylistPrivate, p.Name)
+	}
+	return nil
+}
+
+func (s *Service) sendPlaylistError(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	var msg string
+	switch {
+	case errors.Is(err, musicplaylist.ErrNotFound):
+		msg = messagePlaylistNotFound
+	case errors.Is(err, musicplaylist.ErrInvalidName):
+		msg = messagePlaylistBadName
+	case errors.Is(err, musicplaylist.ErrEmpty):
+		msg = messagePlaylistEmpty
+	case errors.Is(err, musicplaylist.ErrTooLong):
+		msg = messagePlaylistTooLong
+	case errors.Is(err, musicplaylist.ErrNoSong):
+		msg = messagePlaylistNoSong
+	case errors.Is(err, youtube.ErrSongNotFound):
+		msg = messageNotFound
+	case errors.Is(err, player.ErrNotConnected):
+		msg = messageNotVoiceChannel
+	case errors.Is(err, musicplaylist.ErrBusy):
+		msg = messagePlaylistBusy
+	default:
+		contexts.GetLogger(ctx).Error("playlist command", zap.String("command", m.Content), zap.Error(err))
+		s.sendInternalErrorMessage(ctx, ds, m, statusLevel)
+		return
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, strmsg(msg), statusLevel)
+}
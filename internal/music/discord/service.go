@@ -11,6 +11,9 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/moderation"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	musicradio "github.com/HalvaPovidlo/halvabot-go/internal/music/radio"
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/search/youtube"
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
@@ -28,6 +31,17 @@ const (
 	radio      = "radio"
 	disconnect = "disconnect"
 	hello      = "hello"
+	quota      = "quota"
+	purge      = "purge"
+	playlist   = "playlist"
+	like       = "like"
+	unlike     = "unlike"
+	ban        = "ban"
+	unban      = "unban"
+	top        = "top"
+	stats      = "stats"
+	wrapped    = "wrapped"
+	dedup      = "dedup"
 )
 
 type Player interface {
@@ -40,6 +54,7 @@ type Player interface {
 	Disconnect(ctx context.Context) //
 	Random(ctx context.Context, n int) ([]*pkg.Song, error)
 	SetRadio(ctx context.Context, b bool, guildID, channelID string) error
+	SeedRadio(ctx context.Context, seed musicradio.Seed, guildID, channelID string) error
 	RadioStatus() bool
 	// SubscribeOnErrors(h player.ErrorHandler)
 	// Connect(guildID, channelID string)
@@ -47,28 +62,54 @@ type Player interface {
 	// Stop()
 }
 
+type Search interface {
+	QuotaStats() youtube.QuotaStats
+	CacheStats() youtube.CacheStats
+	PurgeQueries(ctx context.Context, query string) (int, error)
+}
+
 type APIConfig struct {
 	OpenChannels   []string `json:"open,omitempty"`
 	StatusChannels []string `json:"status,omitempty"`
+	Admins         []string `json:"admins,omitempty"`
 }
 
 type Service struct {
-	player Player
-	prefix string
+	player    Player
+	search    Search
+	playlists Playlists
+	likes     Likes
+	bans      Bans
+	stats     Stats
+	library   Library
+	prefix    string
+
+	likeableMx    sync.Mutex
+	likeable      map[string]*pkg.Song // messageID song
+	likeableOrder []string
 
 	channelsMx     sync.RWMutex
 	allChannels    map[string]string   // id name
 	openChannels   map[string]struct{} // name{}
 	statusChannels map[string]struct{} // name{}
+	admins         map[string]struct{} // userID{}
 }
 
-func NewCog(player Player, prefix string, config APIConfig) *Service {
+func NewCog(player Player, search Search, playlists Playlists, likes Likes, bans Bans, stats Stats, library Library, prefix string, config APIConfig) *Service {
 	s := Service{
 		player:         player,
+		search:         search,
+		playlists:      playlists,
+		likes:          likes,
+		bans:           bans,
+		stats:          stats,
+		library:        library,
 		prefix:         prefix,
+		likeable:       make(map[string]*pkg.Song),
 		allChannels:    make(map[string]string),
 		openChannels:   make(map[string]struct{}),
 		statusChannels: make(map[string]struct{}),
+		admins:         make(map[string]struct{}),
 	}
 
 	s.channelsMx.Lock()
@@ -79,6 +120,9 @@ func NewCog(player Player, prefix string, config APIConfig) *Service {
 	for _, v := range config.StatusChannels {
 		s.statusChannels[v] = t
 	}
+	for _, v := range config.Admins {
+		s.admins[v] = t
+	}
 	s.channelsMx.Unlock()
 
 	return &s
@@ -110,7 +154,20 @@ func (s *Service) RegisterCommands(ctx context.Context, session *discordgo.Sessi
 	command.NewMessageCommand(s.prefix+radio, s.radioMessageHandler, debug).RegisterCommand(session, logger)
 	command.NewMessageCommand(s.prefix+disconnect, s.disconnectMessageHandler, debug).RegisterCommand(session, logger)
 	command.NewMessageCommand(s.prefix+hello, s.helloMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+quota, s.quotaMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+purge, s.purgeMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+playlist, s.playlistMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+like, s.likeMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+unlike, s.unlikeMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+ban, s.banMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+unban, s.unbanMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+top, s.topMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+stats, s.statsMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+wrapped, s.wrappedMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewMessageCommand(s.prefix+dedup, s.dedupMessageHandler, debug).RegisterCommand(session, logger)
+	command.NewReactionCommand(likeEmoji, s.likeReactionHandler, debug).RegisterCommand(session, logger)
 	s.updateListeningStatus(ctx, session)
+	s.postWrappedJob(ctx, session)
 }
 
 func (s *Service) helloMessageHandler(ctx context.Context, session *discordgo.Session, m *discordgo.MessageCreate) {
@@ -129,13 +186,23 @@ func (s *Service) playMessageHandler(ctx context.Context, ds *discordgo.Session,
 		logger.Error("failed to find author's voice channel", zap.Error(err))
 		return
 	}
-	s.sendSearchingMessage(ctx, ds, m)
+	ctx = s.sendSearchingMessage(ctx, ds, m)
 	song, err := s.player.Play(ctx, query, m.Author.ID, m.GuildID, id)
 	if err != nil {
 		if errors.Is(err, youtube.ErrSongNotFound) {
 			s.sendNotFoundMessage(ctx, ds, m)
 			return
 		}
+		var limitErr *player.LimitError
+		if errors.As(err, &limitErr) {
+			s.sendLimitMessage(ctx, ds, m, limitErr)
+			return
+		}
+		var banErr *moderation.BanError
+		if errors.As(err, &banErr) {
+			s.sendSongBannedMessage(ctx, ds, m, banErr.Ban)
+			return
+		}
 		if strings.Contains(err.Error(), "can't bypass age restriction") {
 			s.sendAgeRestrictionMessage(ctx, ds, m)
 			return
@@ -176,13 +243,23 @@ func (s *Service) randomMessageHandler(ctx context.Context, session *discordgo.S
 	s.sendRandomMessage(ctx, session, m, songs)
 }
 
+// radioMessageHandler toggles the radio over the whole database
+// or enables it seeded by an artist, the current song or a user
 func (s *Service) radioMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
 	s.deleteMessage(ctx, ds, m, statusLevel)
-	if s.player.RadioStatus() {
+	args := strings.Fields(strings.TrimPrefix(m.Content, s.prefix+radio))
+	if len(args) == 0 && s.player.RadioStatus() {
 		s.sendRadioMessage(ctx, ds, m, false)
 		_ = s.player.SetRadio(ctx, false, "", "")
 		return
 	}
+	var seed *musicradio.Seed
+	if len(args) > 0 {
+		if seed = s.radioSeed(ds, m, args); seed == nil {
+			s.sendRadioUsageMessage(ctx, ds, m)
+			return
+		}
+	}
 	id, err := findAuthorVoiceChannelID(ds, m)
 	logger := contexts.GetLogger(ctx)
 	if err != nil {
@@ -190,20 +267,107 @@ func (s *Service) radioMessageHandler(ctx context.Context, ds *discordgo.Session
 		logger.Error("failed to find author's voice channel", zap.Error(err))
 		return
 	}
-	err = s.player.SetRadio(ctx, true, m.GuildID, id)
-	if err != nil {
+	if seed != nil {
+		err = s.player.SeedRadio(ctx, *seed, m.GuildID, id)
+	} else {
+		err = s.player.SetRadio(ctx, true, m.GuildID, id)
+	}
+	switch {
+	case errors.Is(err, player.ErrEmptyRadioSeed):
+		s.sendRadioSeedEmptyMessage(ctx, ds, m)
+	case err != nil:
 		s.sendInternalErrorMessage(ctx, ds, m, statusLevel)
 		logger.Error("enable radio", zap.Error(err))
-	} else {
+	default:
 		s.sendRadioMessage(ctx, ds, m, true)
 	}
 }
 
+// radioSeed parses "artist <name>", "like [url]", "user [@someone]" and "likes [@someone...|all]",
+// nil is returned for anything else
+func (s *Service) radioSeed(ds *discordgo.Session, m *discordgo.MessageCreate, args []string) *musicradio.Seed {
+	switch musicradio.SeedType(args[0]) {
+	case musicradio.SeedArtist:
+		if len(args) < 2 {
+			return nil
+		}
+		return &musicradio.Seed{Type: musicradio.SeedArtist, Value: strings.Join(args[1:], " ")}
+	case musicradio.SeedLike:
+		if len(args) > 1 {
+			id := pkg.GetIDFromURL(args[1])
+			if id.ID == "" {
+				return nil
+			}
+			return &musicradio.Seed{Type: musicradio.SeedLike, Value: id.String()}
+		}
+		song := s.player.NowPlaying()
+		if song == nil {
+			return nil
+		}
+		return &musicradio.Seed{Type: musicradio.SeedLike, Value: song.ID.String()}
+	case musicradio.SeedUser:
+		user := m.Author.ID
+		if len(m.Mentions) > 0 {
+			user = m.Mentions[0].ID
+		}
+		return &musicradio.Seed{Type: musicradio.SeedUser, Value: user}
+	case musicradio.SeedLikes:
+		return &musicradio.Seed{Type: musicradio.SeedLikes, Users: likesSeedUsers(ds, m, args[1:])}
+	}
+	return nil
+}
+
+// likesSeedUsers returns the mentioned users, everyone in the author's voice channel for "all" or the author
+func likesSeedUsers(ds *discordgo.Session, m *discordgo.MessageCreate, args []string) []string {
+	if len(m.Mentions) > 0 {
+		users := make([]string, 0, len(m.Mentions))
+		for _, u := range m.Mentions {
+			users = append(users, u.ID)
+		}
+		return users
+	}
+	if len(args) > 0 && args[0] == "all" {
+		if channelID, err := findAuthorVoiceChannelID(ds, m); err == nil {
+			if users, err := voiceChannelUsers(ds, m.GuildID, channelID); err == nil && len(users) > 0 {
+				return users
+			}
+		}
+	}
+	return []string{m.Author.ID}
+}
+
 func (s *Service) disconnectMessageHandler(ctx context.Context, session *discordgo.Session, m *discordgo.MessageCreate) {
 	s.deleteMessage(ctx, session, m, statusLevel)
 	s.player.Disconnect(ctx)
 }
 
+func (s *Service) quotaMessageHandler(ctx context.Context, session *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, session, m, infoLevel)
+	s.sendQuotaMessage(ctx, session, m, s.search.QuotaStats(), s.search.CacheStats())
+}
+
+// purgeMessageHandler deletes the cached search query or the whole cache if no query is passed
+func (s *Service) purgeMessageHandler(ctx context.Context, session *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, session, m, statusLevel)
+	if !s.isAdmin(m.Author.ID) {
+		s.sendNotAdminMessage(ctx, session, m)
+		return
+	}
+	query := util.StandardizeSpaces(strings.TrimPrefix(m.Content, s.prefix+purge))
+	n, err := s.search.PurgeQueries(ctx, query)
+	if err != nil {
+		contexts.GetLogger(ctx).Error("purge search queries", zap.String("query", query), zap.Error(err))
+		s.sendInternalErrorMessage(ctx, session, m, statusLevel)
+		return
+	}
+	s.sendCachePurgedMessage(ctx, session, m, n)
+}
+
+func (s *Service) isAdmin(userID string) bool {
+	_, ok := s.admins[userID]
+	return ok
+}
+
 func (s *Service) updateListeningStatus(ctx context.Context, session *discordgo.Session) {
 	// TODO: dirty temp code
 	// better way to use channels like error chan
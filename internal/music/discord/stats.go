@@ -0,0 +1,176 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	musicstats "github.com/HalvaPovidlo/halvabot-go/internal/music/stats"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+type Stats interface {
+	TopSongs(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	TopArtists(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	TopRequesters(ctx context.Context, window musicstats.Window, n int) ([]musicstats.Entry, error)
+	User(ctx context.Context, userID string, window musicstats.Window, n int) (*musicstats.UserStats, error)
+	Wrapped(ctx context.Context, guildID string, period musicstats.Period) (*musicstats.Recap, error)
+}
+
+// wrappedListeners is the number of personal recaps posted by the job
+const wrappedListeners = 4
+
+// topMessageHandler runs "top songs|artists|requesters [day|week|month|all]"
+func (s *Service) topMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, infoLevel)
+	args := strings.Fields(strings.TrimPrefix(m.Content, s.prefix+top))
+	if len(args) == 0 || len(args) > 2 {
+		s.sendStatsUsageMessage(ctx, ds, m)
+		return
+	}
+	window, err := musicstats.ParseWindow(strings.Join(args[1:], ""))
+	if err != nil {
+		s.sendStatsUsageMessage(ctx, ds, m)
+		return
+	}
+	var (
+		entries []musicstats.Entry
+		title   string
+		users   bool
+	)
+	switch args[0] {
+	case "songs":
+		title = "Top songs"
+		entries, err = s.stats.TopSongs(ctx, window, musicstats.DefaultLimit)
+	case "artists":
+		title = "Top artists"
+		entries, err = s.stats.TopArtists(ctx, window, musicstats.DefaultLimit)
+	case "requesters":
+		title, users = "Top requesters", true
+		entries, err = s.stats.TopRequesters(ctx, window, musicstats.DefaultLimit)
+	default:
+		s.sendStatsUsageMessage(ctx, ds, m)
+		return
+	}
+	if err != nil {
+		s.sendStatsError(ctx, ds, m, err)
+		return
+	}
+	s.sendTopMessage(ctx, ds, m, title, window, entries, users)
+}
+
+// statsMessageHandler runs "stats [@someone] [day|week|month|all]"
+func (s *Service) statsMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, infoLevel)
+	user := m.Author.ID
+	if len(m.Mentions) > 0 {
+		user = m.Mentions[0].ID
+	}
+	windowName := ""
+	for _, arg := range strings.Fields(strings.TrimPrefix(m.Content, s.prefix+stats)) {
+		if !strings.HasPrefix(arg, "<@") {
+			windowName = arg
+		}
+	}
+	window, err := musicstats.ParseWindow(windowName)
+	if err != nil {
+		s.sendStatsUsageMessage(ctx, ds, m)
+		return
+	}
+	userStats, err := s.stats.User(ctx, user, window, 5)
+	if err != nil {
+		s.sendStatsError(ctx, ds, m, err)
+		return
+	}
+	s.sendUserStatsMessage(ctx, ds, m, userStats)
+}
+
+// wrappedMessageHandler runs "wrapped [year|year-month]", the current year by default
+func (s *Service) wrappedMessageHandler(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate) {
+	s.deleteMessage(ctx, ds, m, infoLevel)
+	period, err := musicstats.ParsePeriod(strings.TrimSpace(strings.TrimPrefix(m.Content, s.prefix+wrapped)), time.Now())
+	if err != nil {
+		s.sendWrappedUsageMessage(ctx, ds, m)
+		return
+	}
+	recap, err := s.stats.Wrapped(ctx, m.GuildID, period)
+	if err != nil {
+		s.sendStatsError(ctx, ds, m, err)
+		return
+	}
+	s.sendComplexMessage(ctx, ds, m.ChannelID, wrappedMessage(recap, m.Author.ID), infoLevel)
+}
+
+// postWrappedJob posts the recap of the previous month to the status channels when a month ends,
+// and the recap of the previous year when a year ends.
+// Recaps missed while the bot was offline are available with the wrapped command.
+func (s *Service) postWrappedJob(ctx context.Context, session *discordgo.Session) {
+	ticker := time.NewTicker(time.Hour)
+	month := musicstats.MonthPeriod(time.Now())
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				if now.Before(month.To) {
+					continue
+				}
+				periods := []musicstats.Period{month}
+				if now.Year() != month.From.Year() {
+					periods = append(periods, musicstats.YearPeriod(month.From.Year(), now.Location()))
+				}
+				for _, period := range periods {
+					s.postWrapped(ctx, session, period)
+				}
+				month = musicstats.MonthPeriod(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) postWrapped(ctx context.Context, session *discordgo.Session, period musicstats.Period) {
+	logger := contexts.GetLogger(ctx).With(zap.String("period", period.Name))
+	for _, guild := range session.State.Guilds {
+		channels, err := session.GuildChannels(guild.ID)
+		if err != nil {
+			logger.Error("get guild channels", zap.String("guild", guild.ID), zap.Error(err))
+			continue
+		}
+		var recap *musicstats.Recap
+		for _, channel := range channels {
+			s.channelsMx.RLock()
+			_, status := s.statusChannels[channel.Name]
+			s.channelsMx.RUnlock()
+			if !status || channel.Type != discordgo.ChannelTypeGuildText {
+				continue
+			}
+			if recap == nil {
+				if recap, err = s.stats.Wrapped(ctx, guild.ID, period); err != nil {
+					logger.Error("build wrapped", zap.String("guild", guild.ID), zap.Error(err))
+					break
+				}
+				if recap.Guild.Requests == 0 {
+					break
+				}
+			}
+			if _, err := session.ChannelMessageSendComplex(channel.ID, wrappedMessage(recap)); err != nil {
+				logger.Error("post wrapped", zap.String("channel", channel.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *Service) sendStatsError(ctx context.Context, ds *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	if errors.Is(err, musicstats.ErrUnknownWindow) {
+		s.sendStatsUsageMessage(ctx, ds, m)
+		return
+	}
+	contexts.GetLogger(ctx).Error("stats command", zap.String("command", m.Content), zap.Error(err))
+	s.sendInternalErrorMessage(ctx, ds, m, infoLevel)
+}
@@ -0,0 +1,173 @@
+package library
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvColumn is a field with the csv tag, index is the path through embedded structs
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns returns the tagged fields of the struct type, embedded structs are flattened
+func csvColumns(t reflect.Type) []csvColumn {
+	var res []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for _, c := range csvColumns(field.Type) {
+				res = append(res, csvColumn{name: c.name, index: append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("csv"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		res = append(res, csvColumn{name: name, index: []int{i}})
+	}
+	return res
+}
+
+// writeCSV writes the slice of structs with the header of their csv tags
+func writeCSV(w io.Writer, rows interface{}) error {
+	v := reflect.ValueOf(rows)
+	columns := csvColumns(v.Type().Elem())
+	writer := csv.NewWriter(w)
+	header := make([]string, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	if err := writer.Write(header); err != nil {
+		return errors.Wrap(err, "write header")
+	}
+	record := make([]string, len(columns))
+	for i := 0; i < v.Len(); i++ {
+		row := v.Index(i)
+		for j, c := range columns {
+			s, err := formatField(row.FieldByIndex(c.index))
+			if err != nil {
+				return errors.Wrapf(err, "row %d column %s", i+1, c.name)
+			}
+			record[j] = s
+		}
+		if err := writer.Write(record); err != nil {
+			return errors.Wrapf(err, "write row %d", i+1)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSV appends the rows to the slice pointed by out, columns are matched by the header
+func readCSV(r io.Reader, out interface{}) error {
+	slice := reflect.ValueOf(out).Elem()
+	byName := make(map[string]csvColumn)
+	for _, c := range csvColumns(slice.Type().Elem()) {
+		byName[c.name] = c
+	}
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "read header")
+	}
+	columns := make([]*csvColumn, len(header))
+	for i, name := range header {
+		if c, ok := byName[strings.TrimSpace(name)]; ok {
+			columns[i] = &c
+		}
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read line %d", line)
+		}
+		row := reflect.New(slice.Type().Elem()).Elem()
+		for i, s := range record {
+			if i >= len(columns) || columns[i] == nil {
+				continue
+			}
+			if err := parseField(row.FieldByIndex(columns[i].index), s); err != nil {
+				return errors.Wrapf(err, "line %d column %s", line, columns[i].name)
+			}
+		}
+		slice.Set(reflect.Append(slice, row))
+	}
+}
+
+func formatField(v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	}
+	return "", errors.Errorf("unsupported type %s", v.Type())
+}
+
+func parseField(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package library
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// Kind is a part of the library, empty kind is the whole library which is supported by JSON only
+type Kind string
+
+const (
+	KindSongs     Kind = "songs"
+	KindUsers     Kind = "users"
+	KindPlaylists Kind = "playlists"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, use json or csv")
+	ErrUnknownKind   = errors.New("unknown kind, use songs, users or playlists")
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+type libraryStorage interface {
+	GetAllSongs(ctx context.Context) ([]*pkg.Song, error)
+	SetSong(ctx context.Context, song *pkg.Song) error
+	GetUsers(ctx context.Context) ([]string, error)
+	GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error)
+	SetUserSong(ctx context.Context, song *pkg.Song, user string) error
+	GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error)
+	SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error
+}
+
+type SongRecord struct {
+	ID string `csv:"id" json:"id"`
+	pkg.Song
+}
+
+// UserSongRecord is the number of requests of the song by the user
+type UserSongRecord struct {
+	User string `csv:"user" json:"user"`
+	SongRecord
+}
+
+// PlaylistRecord is a song of the playlist in CSV, an empty playlist is a record without a song
+type PlaylistRecord struct {
+	OwnerID    string                 `csv:"owner_id"`
+	Name       string                 `csv:"name"`
+	GuildID    string                 `csv:"guild_id"`
+	Visibility pkg.PlaylistVisibility `csv:"visibility"`
+	Created    time.Time              `csv:"created"`
+	Updated    time.Time              `csv:"updated"`
+	Position   int                    `csv:"position"`
+	SongID     string                 `csv:"song_id"`
+	Title      string                 `csv:"title"`
+	URL        string                 `csv:"url"`
+}
+
+// Library is the JSON document of the export
+type Library struct {
+	Songs     []SongRecord     `json:"songs,omitempty"`
+	UserSongs []UserSongRecord `json:"user_songs,omitempty"`
+	Playlists []*pkg.Playlist  `json:"playlists,omitempty"`
+}
+
+// ImportResult is the number of upserted entries of every kind
+type ImportResult struct {
+	Songs     int `json:"songs"`
+	UserSongs int `json:"user_songs"`
+	Playlists int `json:"playlists"`
+}
+
+// Service exports the library for backups and imports it back into any storage backend
+type Service struct {
+	storage libraryStorage
+}
+
+func NewService(storage libraryStorage) *Service {
+	return &Service{storage: storage}
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatJSON, FormatCSV:
+		return f, nil
+	case "":
+		return FormatJSON, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func ParseKind(s string) (Kind, error) {
+	switch k := Kind(s); k {
+	case KindSongs, KindUsers, KindPlaylists, "":
+		return k, nil
+	}
+	return "", ErrUnknownKind
+}
+
+// Export writes the kind of the library in the format
+func (s *Service) Export(ctx context.Context, w io.Writer, format Format, kind Kind) error {
+	if format == FormatCSV && kind == "" {
+		return errors.Wrap(ErrUnknownKind, "csv needs the kind")
+	}
+	var lib Library
+	var err error
+	if kind == "" || kind == KindSongs {
+		if lib.Songs, err = s.exportSongs(ctx); err != nil {
+			return err
+		}
+	}
+	if kind == "" || kind == KindUsers {
+		if lib.UserSongs, err = s.exportUserSongs(ctx); err != nil {
+			return err
+		}
+	}
+	if kind == "" || kind == KindPlaylists {
+		if lib.Playlists, err = s.storage.GetPlaylists(ctx, &pkg.PlaylistFilter{}); err != nil {
+			return errors.Wrap(err, "get playlists")
+		}
+		sort.Slice(lib.Playlists, func(i, j int) bool {
+			return pkg.PlaylistKey(lib.Playlists[i].OwnerID, lib.Playlists[i].Name) <
+				pkg.PlaylistKey(lib.Playlists[j].OwnerID, lib.Playlists[j].Name)
+		})
+	}
+
+	if format == FormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return errors.Wrap(encoder.Encode(&lib), "encode json")
+	}
+	switch kind {
+	case KindSongs:
+		return writeCSV(w, lib.Songs)
+	case KindUsers:
+		return writeCSV(w, lib.UserSongs)
+	default:
+		return writeCSV(w, playlistRecords(lib.Playlists))
+	}
+}
+
+// Import upserts the entries read in the format, entries with the same keys are replaced
+func (s *Service) Import(ctx context.Context, r io.Reader, format Format, kind Kind) (*ImportResult, error) {
+	var lib Library
+	switch {
+	case format == FormatJSON:
+		if err := json.NewDecoder(r).Decode(&lib); err != nil {
+			return nil, errors.Wrapf(ErrInvalidRecord, "decode json: %s", err)
+		}
+		if kind != "" && kind != KindSongs {
+			lib.Songs = nil
+		}
+		if kind != "" && kind != KindUsers {
+			lib.UserSongs = nil
+		}
+		if kind != "" && kind != KindPlaylists {
+			lib.Playlists = nil
+		}
+	case kind == KindSongs:
+		if err := readCSV(r, &lib.Songs); err != nil {
+			return nil, errors.Wrap(ErrInvalidRecord, err.Error())
+		}
+	case kind == KindUsers:
+		if err := readCSV(r, &lib.UserSongs); err != nil {
+			return nil, errors.Wrap(ErrInvalidRecord, err.Error())
+		}
+	case kind == KindPlaylists:
+		var records []PlaylistRecord
+		if err := readCSV(r, &records); err != nil {
+			return nil, errors.Wrap(ErrInvalidRecord, err.Error())
+		}
+		lib.Playlists = playlistsFromRecords(records)
+	default:
+		return nil, errors.Wrap(ErrUnknownKind, "csv needs the kind")
+	}
+	if err := validate(&lib); err != nil {
+		return nil, err
+	}
+
+	res := &ImportResult{}
+	for i := range lib.Songs {
+		if err := s.storage.SetSong(ctx, lib.Songs[i].song()); err != nil {
+			return res, errors.Wrapf(err, "set song %s", lib.Songs[i].ID)
+		}
+		res.Songs++
+	}
+	for i := range lib.UserSongs {
+		record := &lib.UserSongs[i]
+		if err := s.storage.SetUserSong(ctx, record.song(), record.User); err != nil {
+			return res, errors.Wrapf(err, "set song %s of %s", record.ID, record.User)
+		}
+		res.UserSongs++
+	}
+	for _, playlist := range lib.Playlists {
+		if err := s.storage.SetPlaylist(ctx, playlist); err != nil {
+			return res, errors.Wrapf(err, "set playlist %s", pkg.PlaylistKey(playlist.OwnerID, playlist.Name))
+		}
+		res.Playlists++
+	}
+	return res, nil
+}
+
+func (s *Service) exportSongs(ctx context.Context) ([]SongRecord, error) {
+	songs, err := s.storage.GetAllSongs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get all songs")
+	}
+	res := make([]SongRecord, 0, len(songs))
+	for _, song := range songs {
+		res = append(res, newSongRecord(song))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+func (s *Service) exportUserSongs(ctx context.Context) ([]UserSongRecord, error) {
+	users, err := s.storage.GetUsers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get users")
+	}
+	sort.Strings(users)
+	res := make([]UserSongRecord, 0)
+	for _, user := range users {
+		songs, err := s.storage.GetUserSongs(ctx, user)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get songs of %s", user)
+		}
+		records := make([]UserSongRecord, 0, len(songs))
+		for _, song := range songs {
+			records = append(records, UserSongRecord{User: user, SongRecord: newSongRecord(song)})
+		}
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].ID < records[j].ID
+		})
+		res = append(res, records...)
+	}
+	return res, nil
+}
+
+func newSongRecord(song *pkg.Song) SongRecord {
+	return SongRecord{ID: song.ID.String(), Song: *song}
+}
+
+// song returns the stored song, the ID is taken from the URL if it is missing
+func (r *SongRecord) song() *pkg.Song {
+	song := r.Song
+	song.ID = pkg.ParseSongID(r.ID)
+	if r.ID == "" {
+		song.ID = pkg.GetIDFromURL(song.URL)
+	}
+	return &song
+}
+
+func validate(lib *Library) error {
+	for i := range lib.Songs {
+		if lib.Songs[i].song().ID.ID == "" {
+			return errors.Wrapf(ErrInvalidRecord, "song %d has neither id nor url", i+1)
+		}
+	}
+	for i := range lib.UserSongs {
+		if lib.UserSongs[i].User == "" || lib.UserSongs[i].song().ID.ID == "" {
+			return errors.Wrapf(ErrInvalidRecord, "user song %d has no user or id", i+1)
+		}
+	}
+	for i, playlist := range lib.Playlists {
+		if playlist.OwnerID == "" || pkg.NormalizePlaylistName(playlist.Name) != playlist.Name {
+			return errors.Wrapf(ErrInvalidRecord, "playlist %d has no owner or a wrong name %q", i+1, playlist.Name)
+		}
+		if playlist.Visibility == "" {
+			playlist.Visibility = pkg.PlaylistPrivate
+		}
+		if playlist.Songs == nil {
+			playlist.Songs = []pkg.PlaylistSong{}
+		}
+	}
+	return nil
+}
+
+func playlistRecords(playlists []*pkg.Playlist) []PlaylistRecord {
+	res := make([]PlaylistRecord, 0, len(playlists))
+	for _, p := range playlists {
+		record := PlaylistRecord{
+			OwnerID:    p.OwnerID,
+			Name:       p.Name,
+			GuildID:    p.GuildID,
+			Visibility: p.Visibility,
+			Created:    p.Created,
+			Updated:    p.Updated,
+		}
+		if len(p.Songs) == 0 {
+			res = append(res, record)
+			continue
+		}
+		for i, song := range p.Songs {
+			record.Position = i + 1
+			record.SongID = song.ID.String()
+			record.Title = song.Title
+			record.URL = song.URL
+			res = append(res, record)
+		}
+	}
+	return res
+}
+
+// playlistsFromRecords groups the records by playlist, songs are ordered by position
+func playlistsFromRecords(records []PlaylistRecord) []*pkg.Playlist {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Position < records[j].Position
+	})
+	byKey := make(map[string]*pkg.Playlist)
+	res := make([]*pkg.Playlist, 0)
+	for _, r := range records {
+		key := pkg.PlaylistKey(r.OwnerID, r.Name)
+		p, ok := byKey[key]
+		if !ok {
+			p = &pkg.Playlist{
+				Name:       r.Name,
+				OwnerID:    r.OwnerID,
+				GuildID:    r.GuildID,
+				Visibility: r.Visibility,
+				Songs:      []pkg.PlaylistSong{},
+				Created:    r.Created,
+				Updated:    r.Updated,
+			}
+			byKey[key] = p
+			res = append(res, p)
+		}
+		if r.SongID == "" && r.URL == "" {
+			continue
+		}
+		id := pkg.ParseSongID(r.SongID)
+		if r.SongID == "" {
+			id = pkg.GetIDFromURL(r.URL)
+		}
+		p.Songs = append(p.Songs, pkg.PlaylistSong{ID: id, Title: r.Title, URL: r.URL})
+	}
+	return res
+}
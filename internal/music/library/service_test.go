@@ -0,0 +1,108 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func testLibrary(t *testing.T) *memory.Client {
+	t.Helper()
+	ctx := context.Background()
+	client := memory.NewMemoryClient()
+	at := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	song := &pkg.Song{
+		ID:         pkg.SongID{ID: "y6120QOlsfU", Service: pkg.ServiceYouTube},
+		Title:      "Darude - Sandstorm, \"live\"",
+		URL:        "https://www.youtube.com/watch?v=y6120QOlsfU",
+		Service:    pkg.ServiceYouTube,
+		ArtistName: "Darude",
+		Playbacks:  7,
+		LastPlay:   at,
+		Banned:     true,
+	}
+	userSong := *song
+	userSong.Playbacks = 3
+	playlist := &pkg.Playlist{
+		Name:       "mix",
+		OwnerID:    "user",
+		Visibility: pkg.PlaylistGuild,
+		Songs:      []pkg.PlaylistSong{pkg.NewPlaylistSong(song), {ID: pkg.SongID{ID: "dQw4w9WgXcQ", Service: pkg.ServiceYouTube}, Title: "b"}},
+		Created:    at,
+		Updated:    at,
+	}
+	empty := &pkg.Playlist{Name: "empty", OwnerID: "user", Visibility: pkg.PlaylistPrivate, Songs: []pkg.PlaylistSong{}}
+	for _, err := range []error{
+		client.SetSong(ctx, song),
+		client.SetUserSong(ctx, &userSong, "user"),
+		client.SetPlaylist(ctx, playlist),
+		client.SetPlaylist(ctx, empty),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return client
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := NewService(testLibrary(t))
+	tests := []struct {
+		format Format
+		kinds  []Kind
+	}{
+		{format: FormatJSON, kinds: []Kind{""}},
+		{format: FormatCSV, kinds: []Kind{KindSongs, KindUsers, KindPlaylists}},
+	}
+	for _, tt := range tests {
+		target := memory.NewMemoryClient()
+		for _, kind := range tt.kinds {
+			var buf bytes.Buffer
+			if err := source.Export(ctx, &buf, tt.format, kind); err != nil {
+				t.Fatalf("%s %s: export: %v", tt.format, kind, err)
+			}
+			if _, err := NewService(target).Import(ctx, &buf, tt.format, kind); err != nil {
+				t.Fatalf("%s %s: import: %v", tt.format, kind, err)
+			}
+		}
+		for _, kind := range tt.kinds {
+			var want, got bytes.Buffer
+			_ = source.Export(ctx, &want, tt.format, kind)
+			_ = NewService(target).Export(ctx, &got, tt.format, kind)
+			if want.String() != got.String() {
+				t.Fatalf("%s %s: got after import\n%s\nwanted\n%s", tt.format, kind, got.String(), want.String())
+			}
+		}
+	}
+}
+
+func TestImportUpsert(t *testing.T) {
+	ctx := context.Background()
+	client := testLibrary(t)
+	csv := "id,title,url,playbacks\n" +
+		"youtube_y6120QOlsfU,Sandstorm,,10\n" +
+		",New,https://youtu.be/dQw4w9WgXcQ,1\n"
+	res, err := NewService(client).Import(ctx, strings.NewReader(csv), FormatCSV, KindSongs)
+	if err != nil || res.Songs != 2 {
+		t.Fatalf("got %+v %v, wanted 2 songs", res, err)
+	}
+	song, err := client.GetSongByID(ctx, pkg.SongID{ID: "y6120QOlsfU", Service: pkg.ServiceYouTube})
+	if err != nil || song.Playbacks != 10 || song.Title != "Sandstorm" {
+		t.Fatalf("got %+v %v, wanted the song replaced", song, err)
+	}
+	if _, err := client.GetSongByID(ctx, pkg.SongID{ID: "dQw4w9WgXcQ", Service: pkg.ServiceYouTube}); err != nil {
+		t.Fatalf("got %v, wanted the song identified by the url", err)
+	}
+
+	_, err = NewService(client).Import(ctx, strings.NewReader("title\nno id\n"), FormatCSV, KindSongs)
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("got %v, wanted invalid record", err)
+	}
+}
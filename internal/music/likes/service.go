@@ -0,0 +1,70 @@
+package likes
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+var ErrNoSong = errors.New("nothing to like")
+
+type likesStorage interface {
+	SetLike(ctx context.Context, user string, like *pkg.Like) error
+	DeleteLike(ctx context.Context, user string, id pkg.SongID) error
+	GetLikes(ctx context.Context, user string) ([]*pkg.Like, error)
+}
+
+type musicPlayer interface {
+	NowPlaying() *pkg.Song
+}
+
+// Service keeps songs explicitly liked by users.
+// The likes are played by the radio seeded with them.
+type Service struct {
+	storage likesStorage
+	player  musicPlayer
+}
+
+func NewService(storage likesStorage, player musicPlayer) *Service {
+	return &Service{
+		storage: storage,
+		player:  player,
+	}
+}
+
+// Like adds the song to the user's likes, the playing song is liked if song is nil
+func (s *Service) Like(ctx context.Context, userID string, song *pkg.Song) (*pkg.Like, error) {
+	if song == nil {
+		song = s.player.NowPlaying()
+	}
+	if song == nil {
+		return nil, ErrNoSong
+	}
+	like := pkg.NewLike(song, time.Now())
+	if err := s.storage.SetLike(ctx, userID, like); err != nil {
+		return nil, errors.Wrapf(err, "like %s", song.ID.String())
+	}
+	return like, nil
+}
+
+// Unlike removes the song from the user's likes, the playing song is removed if song is nil
+func (s *Service) Unlike(ctx context.Context, userID string, song *pkg.Song) (*pkg.Song, error) {
+	if song == nil {
+		song = s.player.NowPlaying()
+	}
+	if song == nil {
+		return nil, ErrNoSong
+	}
+	if err := s.storage.DeleteLike(ctx, userID, song.ID); err != nil {
+		return nil, errors.Wrapf(err, "unlike %s", song.ID.String())
+	}
+	return song, nil
+}
+
+// List returns the user's likes, newest first
+func (s *Service) List(ctx context.Context, userID string) ([]*pkg.Like, error) {
+	return s.storage.GetLikes(ctx, userID)
+}
@@ -0,0 +1,177 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const defaultAuditLimit = 50
+
+var (
+	ErrBanned        = errors.New("song is banned")
+	ErrInvalidBan    = errors.New("invalid ban")
+	ErrBanNotFound   = errors.New("ban not found")
+	ErrAlreadyBanned = errors.New("already banned")
+)
+
+// BanError describes which ban the song matches
+type BanError struct {
+	Song *pkg.Song
+	Ban  *pkg.Ban
+}
+
+func (e *BanError) Error() string {
+	return fmt.Sprintf("%s: %s (%s %s)", ErrBanned.Error(), e.Song.ID.String(), e.Ban.Kind, e.Ban.Value)
+}
+
+func (e *BanError) Unwrap() error {
+	return ErrBanned
+}
+
+type banStorage interface {
+	SetBan(ctx context.Context, ban *pkg.Ban) error
+	DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error
+	GetBans(ctx context.Context) ([]*pkg.Ban, error)
+	AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error
+	GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error)
+	FlagSongs(ctx context.Context, ban *pkg.Ban, banned bool, kept ...*pkg.Ban) (int, error)
+}
+
+// Service keeps banned songs, artists and channels in memory.
+// Every change flags the stored songs and is written to the audit trail.
+type Service struct {
+	storage banStorage
+
+	mx   sync.RWMutex
+	bans map[string]*pkg.Ban
+}
+
+func NewService(ctx context.Context, storage banStorage) (*Service, error) {
+	bans, err := storage.GetBans(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get bans")
+	}
+	s := &Service{
+		storage: storage,
+		bans:    make(map[string]*pkg.Ban, len(bans)),
+	}
+	for _, ban := range bans {
+		s.bans[pkg.BanKey(ban.Kind, ban.Value)] = ban
+	}
+	return s, nil
+}
+
+// Check returns BanError if the song matches any ban
+func (s *Service) Check(song *pkg.Song) error {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	for _, ban := range s.bans {
+		if ban.Match(song) {
+			return &BanError{Song: song, Ban: ban}
+		}
+	}
+	return nil
+}
+
+// Ban adds the ban and flags matching songs, the number of flagged songs is returned
+func (s *Service) Ban(ctx context.Context, kind pkg.BanKind, value, reason, by string) (*pkg.Ban, int, error) {
+	value = pkg.NormalizeBanValue(kind, value)
+	if value == "" {
+		return nil, 0, ErrInvalidBan
+	}
+	key := pkg.BanKey(kind, value)
+	ban := &pkg.Ban{Kind: kind, Value: value, Reason: reason, By: by, At: time.Now()}
+	// the ban is reserved before it is stored, so concurrent bans of the same value fail
+	s.mx.Lock()
+	if _, ok := s.bans[key]; ok {
+		s.mx.Unlock()
+		return nil, 0, ErrAlreadyBanned
+	}
+	s.bans[key] = ban
+	s.mx.Unlock()
+
+	if err := s.storage.SetBan(ctx, ban); err != nil {
+		s.mx.Lock()
+		delete(s.bans, key)
+		s.mx.Unlock()
+		return nil, 0, errors.Wrapf(err, "set ban %s", key)
+	}
+
+	n, err := s.storage.FlagSongs(ctx, ban, true)
+	if err != nil {
+		return ban, n, errors.Wrapf(err, "flag songs of %s", key)
+	}
+	return ban, n, s.audit(ctx, pkg.BanAdded, ban, by, n)
+}
+
+// Unban removes the ban and unflags the songs which match no other ban
+func (s *Service) Unban(ctx context.Context, kind pkg.BanKind, value, by string) (*pkg.Ban, int, error) {
+	key := pkg.BanKey(kind, pkg.NormalizeBanValue(kind, value))
+	s.mx.Lock()
+	ban, ok := s.bans[key]
+	if !ok {
+		s.mx.Unlock()
+		return nil, 0, ErrBanNotFound
+	}
+	delete(s.bans, key)
+	s.mx.Unlock()
+
+	if err := s.storage.DeleteBan(ctx, ban.Kind, ban.Value); err != nil {
+		s.mx.Lock()
+		s.bans[key] = ban
+		s.mx.Unlock()
+		return nil, 0, errors.Wrapf(err, "delete ban %s", key)
+	}
+
+	// songs covered by other bans stay flagged
+	n, err := s.storage.FlagSongs(ctx, ban, false, s.List()...)
+	if err != nil {
+		return ban, n, errors.Wrapf(err, "unflag songs of %s", key)
+	}
+	return ban, n, s.audit(ctx, pkg.BanRemoved, ban, by, n)
+}
+
+// List returns all bans, newest first
+func (s *Service) List() []*pkg.Ban {
+	s.mx.RLock()
+	res := make([]*pkg.Ban, 0, len(s.bans))
+	for _, ban := range s.bans {
+		res = append(res, ban)
+	}
+	s.mx.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].At.After(res[j].At)
+	})
+	return res
+}
+
+// Audit returns up to limit last changes of the bans, 50 by default
+func (s *Service) Audit(ctx context.Context, limit int) ([]*pkg.BanAudit, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	return s.storage.GetBanAudit(ctx, limit)
+}
+
+func (s *Service) audit(ctx context.Context, action pkg.BanAction, ban *pkg.Ban, by string, songs int) error {
+	entry := &pkg.BanAudit{
+		Action: action,
+		Kind:   ban.Kind,
+		Value:  ban.Value,
+		Reason: ban.Reason,
+		By:     by,
+		At:     time.Now(),
+		Songs:  songs,
+	}
+	if err := s.storage.AddBanAudit(ctx, entry); err != nil {
+		return errors.Wrap(err, "add ban audit entry")
+	}
+	return nil
+}
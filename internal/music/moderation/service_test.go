@@ -0,0 +1,148 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func TestServiceBan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := memory.NewMemoryClient()
+	meme := &pkg.Song{ID: pkg.SongID{ID: "aaaaaaaaaaa", Service: pkg.ServiceYouTube}, ArtistName: "Earrape"}
+	other := &pkg.Song{ID: pkg.SongID{ID: "bbbbbbbbbbb", Service: pkg.ServiceYouTube}, ArtistName: "Earrape"}
+	fine := &pkg.Song{ID: pkg.SongID{ID: "ccccccccccc", Service: pkg.ServiceYouTube}, ArtistName: "Darude"}
+	for _, song := range []*pkg.Song{meme, other, fine} {
+		if err := backend.SetSong(ctx, song); err != nil {
+			t.Fatal(err)
+		}
+	}
+	songs, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(ctx, songs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the index of songs is loaded in background
+	for deadline := time.Now().Add(time.Second); len(songs.SongsIndex()) != 3; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("songs index is not loaded")
+		}
+	}
+
+	if _, n, err := s.Ban(ctx, pkg.BanArtist, "earrape", "too loud", "admin"); err != nil || n != 2 {
+		t.Fatalf("got %d flagged %v, wanted 2 songs of the artist", n, err)
+	}
+	if _, n, err := s.Ban(ctx, pkg.BanSong, meme.ID.String(), "", "admin"); err != nil || n != 0 {
+		t.Fatalf("got %d flagged %v, wanted the already flagged song banned", n, err)
+	}
+	if _, _, err := s.Ban(ctx, pkg.BanArtist, "Earrape", "", "admin"); !errors.Is(err, ErrAlreadyBanned) {
+		t.Fatalf("got %v, wanted ErrAlreadyBanned", err)
+	}
+	var banErr *BanError
+	if err := s.Check(other); !errors.As(err, &banErr) || banErr.Ban.Reason != "too loud" {
+		t.Fatalf("got %v, wanted the artist ban", err)
+	}
+	if index := songs.SongsIndex(); len(index) != 1 || index[0].ID != fine.ID {
+		t.Fatalf("got index %v, wanted only the song which is not banned", index)
+	}
+
+	if _, n, err := s.Unban(ctx, pkg.BanArtist, "EARRAPE", "admin"); err != nil || n != 1 {
+		t.Fatalf("got %d unflagged %v, wanted the song not banned by itself", n, err)
+	}
+	if err := s.Check(meme); !errors.Is(err, ErrBanned) {
+		t.Fatalf("got %v, wanted the song still banned", err)
+	}
+	if index := songs.SongsIndex(); len(index) != 2 {
+		t.Fatalf("got index %v, wanted the banned song still excluded", index)
+	}
+	audit, err := s.Audit(ctx, 0)
+	if err != nil || len(audit) != 3 || audit[0].Action != pkg.BanRemoved {
+		t.Fatalf("got audit %v %v, wanted 3 entries starting from the unban", audit, err)
+	}
+}
+
+// gatedBans holds the first stored ban until the gate is closed
+type gatedBans struct {
+	banStorage
+	stored chan struct{}
+	gate   chan struct{}
+	err    error
+
+	mx     sync.Mutex
+	sets   int
+	audits int
+}
+
+func (b *gatedBans) SetBan(context.Context, *pkg.Ban) error {
+	b.mx.Lock()
+	b.sets++
+	first := b.sets == 1
+	b.mx.Unlock()
+	if first {
+		close(b.stored)
+		<-b.gate
+	}
+	return b.err
+}
+
+func (b *gatedBans) FlagSongs(context.Context, *pkg.Ban, bool, ...*pkg.Ban) (int, error) {
+	return 0, nil
+}
+
+func (b *gatedBans) AddBanAudit(context.Context, *pkg.BanAudit) error {
+	b.mx.Lock()
+	b.audits++
+	b.mx.Unlock()
+	return nil
+}
+
+func TestServiceConcurrentBan(t *testing.T) {
+	ctx := context.Background()
+	bans := &gatedBans{stored: make(chan struct{}), gate: make(chan struct{})}
+	s := &Service{storage: bans, bans: make(map[string]*pkg.Ban)}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := s.Ban(ctx, pkg.BanArtist, "Earrape", "", "first")
+		done <- err
+	}()
+	<-bans.stored
+	if _, _, err := s.Ban(ctx, pkg.BanArtist, "earrape", "", "second"); !errors.Is(err, ErrAlreadyBanned) {
+		t.Fatalf("got %v, wanted ErrAlreadyBanned while the first ban is stored", err)
+	}
+	close(bans.gate)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if bans.sets != 1 || bans.audits != 1 {
+		t.Fatalf("got %d stored bans and %d audit entries, wanted one of each", bans.sets, bans.audits)
+	}
+}
+
+func TestServiceBanRollback(t *testing.T) {
+	ctx := context.Background()
+	bans := &gatedBans{stored: make(chan struct{}), gate: make(chan struct{}), err: errors.New("unavailable")}
+	close(bans.gate)
+	s := &Service{storage: bans, bans: make(map[string]*pkg.Ban)}
+
+	if _, _, err := s.Ban(ctx, pkg.BanArtist, "Earrape", "", "admin"); err == nil {
+		t.Fatal("got no error, wanted the storage error")
+	}
+	if list := s.List(); len(list) != 0 {
+		t.Fatalf("got bans %v, wanted the failed ban rolled back", list)
+	}
+	bans.err = nil
+	if _, _, err := s.Ban(ctx, pkg.BanArtist, "Earrape", "", "admin"); err != nil {
+		t.Fatalf("got %v, wanted the ban retried", err)
+	}
+}
@@ -0,0 +1,118 @@
+package player
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+// historiesTTL is how often requested songs of every user are read again for the song seeded radio
+const historiesTTL = 24 * time.Hour
+
+type historiesStorage interface {
+	GetUsers(ctx context.Context) ([]string, error)
+	GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error)
+}
+
+// userHistories caches songs requested by every user, the radio seeded by a song finds related songs in them.
+// Histories are read from storage once per historiesTTL, requests are added to them in between.
+type userHistories struct {
+	storage historiesStorage
+
+	mx     sync.Mutex
+	loaded time.Time
+	songs  map[string]map[pkg.SongID]*pkg.Song // nil until loaded
+}
+
+func newUserHistories(storage historiesStorage) *userHistories {
+	return &userHistories{storage: storage}
+}
+
+// get returns copies of the histories, they are loaded without holding the lock
+func (h *userHistories) get(ctx context.Context) (map[string][]*pkg.Song, error) {
+	h.mx.Lock()
+	fresh := h.songs != nil && time.Since(h.loaded) < historiesTTL
+	h.mx.Unlock()
+	if !fresh {
+		songs, err := h.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		h.mx.Lock()
+		h.songs = songs
+		h.loaded = time.Now()
+		h.mx.Unlock()
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	res := make(map[string][]*pkg.Song, len(h.songs))
+	for user, songs := range h.songs {
+		history := make([]*pkg.Song, 0, len(songs))
+		for _, song := range songs {
+			s := *song
+			history = append(history, &s)
+		}
+		res[user] = history
+	}
+	return res, nil
+}
+
+// add records the request of the user, nothing is recorded until the histories are loaded
+func (h *userHistories) add(user string, song *pkg.Song) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.songs == nil {
+		return
+	}
+	songs, ok := h.songs[user]
+	if !ok {
+		songs = make(map[pkg.SongID]*pkg.Song)
+		h.songs[user] = songs
+	}
+	s, ok := songs[song.ID]
+	if !ok {
+		s = historySong(song)
+		s.Playbacks = 0
+		songs[song.ID] = s
+	}
+	s.Playbacks++
+	if song.LastPlay.After(s.LastPlay) {
+		s.LastPlay = song.LastPlay
+	}
+}
+
+func (h *userHistories) load(ctx context.Context) (map[string]map[pkg.SongID]*pkg.Song, error) {
+	users, err := h.storage.GetUsers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get users")
+	}
+	res := make(map[string]map[pkg.SongID]*pkg.Song, len(users))
+	for _, user := range users {
+		songs, err := h.storage.GetUserSongs(ctx, user)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get songs of %s", user)
+		}
+		history := make(map[pkg.SongID]*pkg.Song, len(songs))
+		for _, song := range songs {
+			history[song.ID] = historySong(song)
+		}
+		res[user] = history
+	}
+	return res, nil
+}
+
+// historySong is the song without the fields unused by the radio
+func historySong(song *pkg.Song) *pkg.Song {
+	return &pkg.Song{
+		ID:         song.ID,
+		ArtistName: song.ArtistName,
+		ArtistURL:  song.ArtistURL,
+		Playbacks:  song.Playbacks,
+		LastPlay:   song.LastPlay,
+	}
+}
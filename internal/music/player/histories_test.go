@@ -0,0 +1,59 @@
+package player
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type fakeHistories struct {
+	songs map[string][]*pkg.Song
+	reads int
+}
+
+func (f *fakeHistories) GetUsers(ctx context.Context) ([]string, error) {
+	res := make([]string, 0, len(f.songs))
+	for user := range f.songs {
+		res = append(res, user)
+	}
+	return res, nil
+}
+
+func (f *fakeHistories) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	f.reads++
+	return f.songs[user], nil
+}
+
+func TestUserHistories(t *testing.T) {
+	ctx := context.Background()
+	first, second := testSong("first", ""), testSong("second", "")
+	storage := &fakeHistories{songs: map[string][]*pkg.Song{"user": {{ID: first.ID, Playbacks: 2}}}}
+	h := newUserHistories(storage)
+
+	h.add("user", second) // not loaded yet, the storage has it
+	histories, err := h.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories["user"]) != 1 || storage.reads != 1 {
+		t.Fatalf("got %v after %d reads, wanted the stored history", histories, storage.reads)
+	}
+
+	h.add("user", first)
+	h.add("other", second)
+	histories, err = h.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.reads != 1 {
+		t.Fatalf("got %d reads, wanted the cached histories", storage.reads)
+	}
+	if len(histories["user"]) != 1 || histories["user"][0].Playbacks != 3 || len(histories["other"]) != 1 {
+		t.Fatalf("got %v, wanted the requests added", histories)
+	}
+	histories["user"][0].Playbacks = 100
+	if again, _ := h.get(ctx); again["user"][0].Playbacks != 3 {
+		t.Fatal("cached history was changed through the copy")
+	}
+}
@@ -0,0 +1,88 @@
+package player
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/radio"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+var (
+	ErrSongTooLong    = errors.New("song is too long")
+	ErrChannelBlocked = errors.New("channel is blocked")
+	ErrKeywordBlocked = errors.New("song contains blocked keyword")
+	ErrLiveNotAllowed = errors.New("livestreams are not allowed")
+)
+
+// Limits restrict songs that can be enqueued
+type Limits struct {
+	MaxDuration      int      `json:"max_duration,omitempty"`     // seconds, 0 - unlimited
+	BlockedChannels  []string `json:"blocked_channels,omitempty"` // channel IDs, URLs or names
+	BlockedKeywords  []string `json:"blocked_keywords,omitempty"` // case-insensitive parts of the title
+	AllowLivestreams bool     `json:"allow_livestreams,omitempty"`
+}
+
+type Config struct {
+	Limits Limits            `json:"limits"`
+	Guilds map[string]Limits `json:"guilds,omitempty"` // guildID - limits overriding the default ones
+	Radio  radio.Config      `json:"radio"`
+}
+
+// LimitError describes which limit the song violates
+type LimitError struct {
+	Song  *pkg.Song
+	Value string
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Err.Error(), e.Song.ID.String(), e.Value)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Config) limits(guildID string) Limits {
+	if l, ok := c.Guilds[guildID]; ok {
+		return l
+	}
+	return c.Limits
+}
+
+// Check returns LimitError if the song can't be enqueued
+func (l *Limits) Check(song *pkg.Song) error {
+	if song.Live && !l.AllowLivestreams {
+		return &LimitError{Song: song, Value: song.URL, Err: ErrLiveNotAllowed}
+	}
+	if l.MaxDuration > 0 && song.Duration > float64(l.MaxDuration) {
+		max := time.Duration(l.MaxDuration) * time.Second
+		return &LimitError{Song: song, Value: max.String(), Err: ErrSongTooLong}
+	}
+	for _, channel := range l.BlockedChannels {
+		if matchChannel(song, channel) {
+			return &LimitError{Song: song, Value: channel, Err: ErrChannelBlocked}
+		}
+	}
+	title := strings.ToLower(song.Title)
+	for _, keyword := range l.BlockedKeywords {
+		if keyword != "" && strings.Contains(title, strings.ToLower(keyword)) {
+			return &LimitError{Song: song, Value: keyword, Err: ErrKeywordBlocked}
+		}
+	}
+	return nil
+}
+
+func matchChannel(song *pkg.Song, channel string) bool {
+	if channel == "" {
+		return false
+	}
+	if strings.EqualFold(song.ArtistName, channel) || song.ArtistURL == channel {
+		return true
+	}
+	return song.ArtistURL != "" && strings.HasSuffix(song.ArtistURL, "/"+channel)
+}
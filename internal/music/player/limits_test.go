@@ -0,0 +1,72 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	type test struct {
+		name   string
+		limits Limits
+		song   pkg.Song
+		err    error
+	}
+
+	testCases := []test{
+		{
+			name:   "no limits",
+			limits: Limits{AllowLivestreams: true},
+			song:   pkg.Song{Title: "Ten hours loop", Duration: 36000, Live: true},
+		},
+		{
+			name:   "too long",
+			limits: Limits{MaxDuration: 600},
+			song:   pkg.Song{Title: "Ten hours loop", Duration: 36000},
+			err:    ErrSongTooLong,
+		},
+		{
+			name:   "short enough",
+			limits: Limits{MaxDuration: 600},
+			song:   pkg.Song{Title: "Song", Duration: 212},
+		},
+		{
+			name:   "livestream",
+			limits: Limits{},
+			song:   pkg.Song{Title: "lofi radio", Live: true},
+			err:    ErrLiveNotAllowed,
+		},
+		{
+			name:   "blocked channel id",
+			limits: Limits{BlockedChannels: []string{"UCuAXFkgsw1L7xaCfnd5JJOw"}},
+			song:   pkg.Song{Title: "Song", ArtistURL: "https://youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw"},
+			err:    ErrChannelBlocked,
+		},
+		{
+			name:   "blocked channel name",
+			limits: Limits{BlockedChannels: []string{"rick astley"}},
+			song:   pkg.Song{Title: "Song", ArtistName: "Rick Astley"},
+			err:    ErrChannelBlocked,
+		},
+		{
+			name:   "blocked keyword",
+			limits: Limits{BlockedKeywords: []string{"EARRAPE"}},
+			song:   pkg.Song{Title: "Never gonna give you up earrape"},
+			err:    ErrKeywordBlocked,
+		},
+	}
+
+	for i := range testCases {
+		tc := &testCases[i]
+		err := tc.limits.Check(&tc.song)
+		if tc.err == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, wanted %v", tc.name, err, tc.err)
+		}
+	}
+}
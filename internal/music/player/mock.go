@@ -71,6 +71,10 @@ func (m *MockPlayer) NowPlaying() *pkg.Song {
 	}
 }
 
+func (m *MockPlayer) Queue() []*pkg.Song {
+	return nil
+}
+
 func (m *MockPlayer) SongStatus() pkg.SessionStats {
 	return pkg.SessionStats{
 		Pos:      111,
@@ -19,6 +19,20 @@ import (
 var ErrNotConnected = errors.New("player not connected")
 var ErrQueueEmpty = errors.New("queue is empty")
 
+// StreamError is reported when the song stream can't be played even after it was re-resolved
+type StreamError struct {
+	Song *pkg.Song
+	Err  error
+}
+
+func (e *StreamError) Error() string {
+	return "stream of " + e.Song.ID.String() + " is unavailable: " + e.Err.Error()
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
 type MediaPlayer interface {
 	Process(requests <-chan *audio.SongRequest) <-chan error
 	Stats() pkg.SessionStats
@@ -33,8 +47,22 @@ type VoiceClient interface {
 	Disconnect() error
 }
 
+// StreamRefresher makes sure that the song stream is playable right before the playback
+type StreamRefresher interface {
+	RefreshStream(ctx context.Context, song *pkg.Song) (*pkg.Song, error)
+}
+
 type ErrorHandler func(err error)
 
+type EventHandler func(e *pkg.PlayEvent)
+
+// playback is the song sent to the audio player
+type playback struct {
+	song    *pkg.Song
+	guildID string
+	started time.Time
+}
+
 type commandType int
 
 const (
@@ -46,6 +74,7 @@ const (
 	disconnect
 	shuffle
 	loop
+	start
 )
 
 func (c commandType) String() string {
@@ -66,6 +95,8 @@ func (c commandType) String() string {
 		return "shuffle"
 	case loop:
 		return "loop"
+	case start:
+		return "start"
 	}
 	return ""
 }
@@ -77,27 +108,41 @@ type command struct {
 	entry     *pkg.Song
 	loop      bool
 	logger    *zap.Logger
+
+	// start command is the result of the song preparation
+	err        error
+	generation int
 }
 
 // Player all public methods are concurrent and
 // most private methods are designed to be synchronous
 type Player struct {
-	voice VoiceClient
-	audio MediaPlayer
+	ctx     context.Context
+	voice   VoiceClient
+	audio   MediaPlayer
+	streams StreamRefresher
 
 	currentLock   sync.Mutex
 	current       *pkg.Song
 	isWaited      bool
+	preparing     bool
+	generation    int // invalidates the song being prepared on skip or stop
 	queue         Queue
 	errs          chan error
 	commands      chan *command
 	errorHandlers chan ErrorHandler
+
+	playing         *playback
+	eventHandlersMx sync.Mutex
+	eventHandlers   []EventHandler
 }
 
-func NewPlayer(ctx context.Context, voice VoiceClient, audio MediaPlayer) *Player {
+func NewPlayer(ctx context.Context, voice VoiceClient, audio MediaPlayer, streams StreamRefresher) *Player {
 	p := Player{
-		voice: voice,
-		audio: audio,
+		ctx:     ctx,
+		voice:   voice,
+		audio:   audio,
+		streams: streams,
 	}
 	p.commands, p.errs = p.processCommands(ctx)
 	p.errorHandlers = p.processErrors(p.errs)
@@ -106,25 +151,25 @@ func NewPlayer(ctx context.Context, voice VoiceClient, audio MediaPlayer) *Playe
 
 // Play next song and enqueue input
 func (p *Player) Play(ctx context.Context, s *pkg.Song) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:   play,
 		entry:  s,
 		logger: contexts.GetLogger(ctx),
-	}
+	})
 }
 
 func (p *Player) Skip(ctx context.Context) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:   skip,
 		logger: contexts.GetLogger(ctx),
-	}
+	})
 }
 
 func (p *Player) Stop(ctx context.Context) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:   stop,
 		logger: contexts.GetLogger(ctx),
-	}
+	})
 }
 
 func (p *Player) LoopStatus() bool {
@@ -132,26 +177,34 @@ func (p *Player) LoopStatus() bool {
 }
 
 func (p *Player) SetLoop(ctx context.Context, b bool) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:   loop,
 		loop:   b,
 		logger: contexts.GetLogger(ctx),
-	}
+	})
 }
 
 func (p *Player) Connect(ctx context.Context, guildID, channelID string) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:      connect,
 		guildID:   guildID,
 		channelID: channelID,
 		logger:    contexts.GetLogger(ctx),
-	}
+	})
 }
 
 func (p *Player) Disconnect(ctx context.Context) {
-	p.commands <- &command{
+	p.send(&command{
 		Type:   disconnect,
 		logger: contexts.GetLogger(ctx),
+	})
+}
+
+// send passes the command to the commands loop, the command is dropped once the player is stopped
+func (p *Player) send(c *command) {
+	select {
+	case p.commands <- c:
+	case <-p.ctx.Done():
 	}
 }
 
@@ -161,6 +214,11 @@ func (p *Player) NowPlaying() *pkg.Song {
 	return p.current
 }
 
+// Queue returns songs waiting after the current one
+func (p *Player) Queue() []*pkg.Song {
+	return p.queue.Entries()
+}
+
 func (p *Player) setNowPlaying(s *pkg.Song) {
 	p.currentLock.Lock()
 	defer p.currentLock.Unlock()
@@ -189,10 +247,10 @@ func (p *Player) processCommands(ctx context.Context) (chan *command, chan error
 	commands := make(chan *command)
 	out := make(chan error)
 	go func() {
+		// commands is not closed, the background senders drop their commands once ctx is done
 		defer func() {
 			close(requests)
 			close(out)
-			close(commands)
 		}()
 
 		for {
@@ -202,9 +260,10 @@ func (p *Player) processCommands(ctx context.Context) (chan *command, chan error
 					out <- err
 				}
 			case err := <-playerErrors:
+				p.finishPlayback(err)
 				if err == nil || errors.Is(err, audio.ErrManualStop) || errors.Is(err, io.EOF) {
 					go func() {
-						p.commands <- &command{Type: next}
+						p.send(&command{Type: next})
 					}()
 				}
 				if err != nil {
@@ -234,13 +293,22 @@ func (p *Player) processCommand(c *command, requests chan *audio.SongRequest) er
 	}
 	switch c.Type {
 	case play:
-		return p.processPlay(c.entry, requests, c.logger)
+		return p.processPlay(c.entry, c.logger)
 	case next:
-		return p.processNext(requests)
+		return p.processNext(c.logger)
 	case loop:
 		p.queue.SetLoop(c.loop)
 	case skip:
-		p.audio.Stop()
+		if p.preparing {
+			p.cancelPreparing()
+			go func() {
+				p.send(&command{Type: next, logger: c.logger})
+			}()
+		} else {
+			p.audio.Stop()
+		}
+	case start:
+		return p.processStart(c, requests)
 	case stop:
 		p.reset()
 	case disconnect:
@@ -254,32 +322,128 @@ func (p *Player) processCommand(c *command, requests chan *audio.SongRequest) er
 	return nil
 }
 
-func (p *Player) processPlay(entry *pkg.Song, requests chan *audio.SongRequest, logger *zap.Logger) error {
+func (p *Player) processPlay(entry *pkg.Song, logger *zap.Logger) error {
 	if !p.voice.IsConnected() {
 		return ErrNotConnected
 	}
 	logger.Debug("adding to queue", zap.String("title", entry.Title))
 	p.queue.Add(entry)
-	if !p.audio.IsPlaying() {
+	if !p.audio.IsPlaying() && !p.preparing {
 		s := p.queue.Next()
 		p.setNowPlaying(s)
 		logger.Debug("pushing song req")
-		requests <- requestFromEntry(s, p.voice.Connection())
+		p.pushRequest(s, logger)
 	}
 	return nil
 }
 
-func (p *Player) processNext(out chan *audio.SongRequest) error {
+// pushRequest prepares the song stream in background not to block the commands processing.
+// The prepared song comes back as the start command.
+func (p *Player) pushRequest(s *pkg.Song, logger *zap.Logger) {
+	p.preparing = true
+	p.generation++
+	generation := p.generation
+	go func() {
+		ctx := contexts.WithLogger(p.ctx, logger)
+		refreshed, err := p.streams.RefreshStream(ctx, s)
+		if err != nil {
+			refreshed = s
+		}
+		p.send(&command{
+			Type:       start,
+			entry:      refreshed,
+			err:        err,
+			generation: generation,
+			logger:     logger,
+		})
+	}()
+}
+
+// processStart sends the prepared song to the audio player.
+// Unplayable song is skipped and reported as StreamError.
+func (p *Player) processStart(c *command, out chan *audio.SongRequest) error {
+	if !p.preparing || c.generation != p.generation {
+		c.logger.Debug("prepared song is outdated", zap.String("title", c.entry.Title))
+		return nil
+	}
+	p.preparing = false
+	if c.err != nil {
+		p.setNowPlaying(nil)
+		p.queue.SetLoop(false)
+		go func() {
+			p.send(&command{Type: next, logger: c.logger})
+		}()
+		return &StreamError{Song: c.entry, Err: c.err}
+	}
+	if !p.voice.IsConnected() {
+		p.setNowPlaying(nil)
+		return nil
+	}
+	p.setNowPlaying(c.entry)
+	conn := p.voice.Connection()
+	p.playing = &playback{song: c.entry, guildID: conn.GuildID, started: time.Now()}
+	p.emit(p.playing.event(pkg.EventPlay, p.playing.started, 0))
+	out <- requestFromEntry(c.entry, conn)
+	return nil
+}
+
+// finishPlayback records the end of the song with the audio player result
+func (p *Player) finishPlayback(err error) {
+	if p.playing == nil {
+		return
+	}
+	t := pkg.EventSkip
+	if err == nil || errors.Is(err, io.EOF) {
+		t = pkg.EventComplete
+	}
+	p.emit(p.playing.event(t, time.Now(), p.audio.Stats().Pos))
+	p.playing = nil
+}
+
+func (p *Player) SubscribeOnEvents(h EventHandler) {
+	p.eventHandlersMx.Lock()
+	p.eventHandlers = append(p.eventHandlers, h)
+	p.eventHandlersMx.Unlock()
+}
+
+func (p *Player) emit(e *pkg.PlayEvent) {
+	p.eventHandlersMx.Lock()
+	defer p.eventHandlersMx.Unlock()
+	for _, h := range p.eventHandlers {
+		go h(e)
+	}
+}
+
+func (b *playback) event(t pkg.EventType, at time.Time, pos float64) *pkg.PlayEvent {
+	return &pkg.PlayEvent{
+		Type:        t,
+		SongID:      b.song.ID,
+		Title:       b.song.Title,
+		RequesterID: b.song.RequesterID,
+		GuildID:     b.guildID,
+		StartedAt:   b.started,
+		At:          at,
+		Position:    pos,
+		Duration:    b.song.Duration,
+	}
+}
+
+func (p *Player) cancelPreparing() {
+	p.preparing = false
+	p.generation++
+}
+
+func (p *Player) processNext(logger *zap.Logger) error {
 	if !p.voice.IsConnected() {
 		p.setNowPlaying(nil)
 		return nil
 	}
-	if p.audio.IsPlaying() {
+	if p.audio.IsPlaying() || p.preparing {
 		return nil
 	}
 	if s := p.queue.Next(); s != nil {
 		p.setNowPlaying(s)
-		out <- requestFromEntry(s, p.voice.Connection())
+		p.pushRequest(s, logger)
 		return nil
 	}
 	p.setNowPlaying(nil)
@@ -309,6 +473,7 @@ func (p *Player) processConnect(gID, cID string) error {
 }
 
 func (p *Player) reset() {
+	p.cancelPreparing()
 	p.queue.Clear()
 	p.audio.Stop()
 }
@@ -337,9 +502,12 @@ func (p *Player) processErrors(errs <-chan error) chan ErrorHandler {
 
 func (p *Player) tryNextAfterTimeout(d time.Duration) {
 	go func() {
-		time.Sleep(d)
-		p.commands <- &command{
-			Type: next,
+		select {
+		case <-time.After(d):
+			p.send(&command{
+				Type: next,
+			})
+		case <-p.ctx.Done():
 		}
 	}()
 }
@@ -0,0 +1,212 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/audio"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+var errUnplayable = errors.New("unplayable")
+
+type fakeVoice struct{}
+
+func (v *fakeVoice) Connection() *discordgo.VoiceConnection {
+	return &discordgo.VoiceConnection{GuildID: "guild", ChannelID: "channel"}
+}
+func (v *fakeVoice) Connect(guildID, channelID string) error { return nil }
+func (v *fakeVoice) IsConnected() bool                       { return true }
+func (v *fakeVoice) Disconnect() error                       { return nil }
+
+// fakeAudio reports the played streams and plays each of them until Stop
+type fakeAudio struct {
+	played chan string
+	stop   chan struct{}
+
+	mx      sync.Mutex
+	playing bool
+}
+
+func newFakeAudio() *fakeAudio {
+	return &fakeAudio{
+		played: make(chan string, 10),
+		stop:   make(chan struct{}, 1),
+	}
+}
+
+func (a *fakeAudio) Process(requests <-chan *audio.SongRequest) <-chan error {
+	out := make(chan error)
+	go func() {
+		defer close(out)
+		for req := range requests {
+			a.setPlaying(true)
+			a.played <- req.URI
+			<-a.stop
+			a.setPlaying(false)
+			out <- audio.ErrManualStop
+		}
+	}()
+	return out
+}
+
+func (a *fakeAudio) setPlaying(b bool) {
+	a.mx.Lock()
+	a.playing = b
+	a.mx.Unlock()
+}
+
+func (a *fakeAudio) Stats() pkg.SessionStats { return pkg.SessionStats{} }
+
+func (a *fakeAudio) IsPlaying() bool {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return a.playing
+}
+
+func (a *fakeAudio) Stop() {
+	if a.IsPlaying() {
+		select {
+		case a.stop <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// fakeStreams re-resolves the streams, songs without the stream are unplayable.
+// Refreshing of the song from gates waits until its channel is closed or ctx is done.
+type fakeStreams struct {
+	gates     map[string]chan struct{}
+	cancelled chan string // songs which refresh was cancelled
+}
+
+func (f *fakeStreams) RefreshStream(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	if gate, ok := f.gates[song.ID.ID]; ok {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			if f.cancelled != nil {
+				f.cancelled <- song.ID.ID
+			}
+			return nil, ctx.Err()
+		}
+	}
+	if song.StreamURL == "" {
+		return nil, errUnplayable
+	}
+	refreshed := *song
+	refreshed.StreamURL += "?refreshed"
+	return &refreshed, nil
+}
+
+func newTestPlayer(t *testing.T, streams StreamRefresher) (*Player, *fakeAudio) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return newTestPlayerContext(ctx, streams)
+}
+
+func newTestPlayerContext(ctx context.Context, streams StreamRefresher) (*Player, *fakeAudio) {
+	a := newFakeAudio()
+	return NewPlayer(ctx, &fakeVoice{}, a, streams), a
+}
+
+func testSong(id, stream string) *pkg.Song {
+	return &pkg.Song{Title: id, ID: pkg.SongID{ID: id, Service: pkg.ServiceYouTube}, StreamURL: stream}
+}
+
+func waitPlayed(t *testing.T, a *fakeAudio, want string) {
+	t.Helper()
+	select {
+	case uri := <-a.played:
+		if uri != want {
+			t.Fatalf("played %q, wanted %q", uri, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%q was not played", want)
+	}
+}
+
+func TestPlayerSkipsUnplayableStream(t *testing.T) {
+	p, a := newTestPlayer(t, &fakeStreams{})
+	errs := make(chan error, 10)
+	p.SubscribeOnErrors(func(err error) { errs <- err })
+	ctx := context.Background()
+
+	p.Play(ctx, testSong("broken", ""))
+	p.Play(ctx, testSong("fine", "stream"))
+
+	waitPlayed(t, a, "stream?refreshed")
+	select {
+	case err := <-errs:
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) || streamErr.Song.ID.ID != "broken" || !errors.Is(err, errUnplayable) {
+			t.Fatalf("got %v, wanted stream error of the broken song", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream error was not reported")
+	}
+	if now := p.NowPlaying(); now == nil || now.ID.ID != "fine" {
+		t.Fatalf("now playing %v, wanted the fine song", now)
+	}
+}
+
+func TestPlayerRefreshDoesNotBlockCommands(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+	p, a := newTestPlayer(t, &fakeStreams{gates: map[string]chan struct{}{"slow": gate}})
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		p.Play(ctx, testSong("slow", "slow"))
+		p.Play(ctx, testSong("next", "next"))
+		p.Skip(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("commands are blocked by the stream refresh")
+	}
+
+	// The slow song is skipped while it is being prepared
+	waitPlayed(t, a, "next?refreshed")
+}
+
+func TestPlayerShutdown(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+	ctx, cancel := context.WithCancel(context.Background())
+	streams := &fakeStreams{gates: map[string]chan struct{}{"slow": gate}, cancelled: make(chan string, 1)}
+	p, _ := newTestPlayerContext(ctx, streams)
+
+	p.Play(context.Background(), testSong("slow", "slow"))
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		// The commands are dropped instead of blocking forever
+		p.Play(context.Background(), testSong("next", "next"))
+		p.Skip(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("commands are blocked after the shutdown")
+	}
+	select {
+	case id := <-streams.cancelled:
+		if id != "slow" {
+			t.Fatalf("refresh of %q was cancelled, wanted the slow song", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream refresh was not cancelled on shutdown")
+	}
+}
@@ -10,6 +10,7 @@ import (
 )
 
 type Queue struct {
+	mx      sync.Mutex
 	entries []*pkg.Song
 	current *pkg.Song
 
@@ -18,7 +19,10 @@ type Queue struct {
 }
 
 func (q *Queue) Next() *pkg.Song {
-	if q.LoopStatus() {
+	loop := q.LoopStatus()
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if loop {
 		return q.current
 	}
 	if len(q.entries) == 0 {
@@ -30,15 +34,21 @@ func (q *Queue) Next() *pkg.Song {
 }
 
 func (q *Queue) Add(e *pkg.Song) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
 	q.entries = append(q.entries, e)
 }
 
 func (q *Queue) Clear() {
+	q.mx.Lock()
 	q.entries = nil
+	q.mx.Unlock()
 	q.SetLoop(false)
 }
 
 func (q *Queue) IsEmpty() bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
 	return len(q.entries) == 0
 }
 
@@ -55,12 +65,21 @@ func (q *Queue) LoopStatus() bool {
 }
 
 func (q *Queue) Front() *pkg.Song {
+	q.mx.Lock()
+	defer q.mx.Unlock()
 	if len(q.entries) == 0 {
 		return nil
 	}
 	return q.entries[0]
 }
 
+// Entries returns a copy of the songs waiting in the queue
+func (q *Queue) Entries() []*pkg.Song {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return append([]*pkg.Song(nil), q.entries...)
+}
+
 func requestFromEntry(e *pkg.Song, connection *discordgo.VoiceConnection) *audio.SongRequest {
 	return &audio.SongRequest{
 		Voice: connection,
@@ -10,39 +10,76 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/HalvaPovidlo/halvabot-go/internal/music/audio"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/radio"
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 )
 
-const maxRadioSongDuration = 10000
+var ErrEmptyRadioSeed = errors.New("no songs found for the radio seed")
 
-type Firestore interface {
+const (
+	maxRadioSongDuration = 10000
+	// radioAttempts is how many songs are tried before the radio gives up
+	radioAttempts = 10
+	// enqueueBuffer is how many requested songs may wait to be passed to the player
+	enqueueBuffer = 100
+)
+
+type Storage interface {
 	UpsertSongIncPlaybacks(ctx context.Context, new *pkg.Song) (int, error)
-	IncrementUserRequests(ctx context.Context, song *pkg.Song, userID string)
-	GetRandomSongs(ctx context.Context, n int) ([]*pkg.Song, error)
+	IncrementUserRequests(ctx context.Context, song *pkg.Song, userID string) error
+	GetRandomSongs(ctx context.Context, n int, filter *pkg.SongFilter) ([]*pkg.Song, error)
+	GetSong(ctx context.Context, id pkg.SongID) (*pkg.Song, error)
+	SongsIndex() []*pkg.Song
+	GetUsers(ctx context.Context) ([]string, error)
+	GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error)
+	GetLikes(ctx context.Context, user string) ([]*pkg.Like, error)
+	AddEvent(ctx context.Context, event *pkg.PlayEvent) error
+	GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error)
+}
+
+// Bans refuses songs banned by admins
+type Bans interface {
+	Check(song *pkg.Song) error
 }
 
 type YouTube interface {
 	FindSong(ctx context.Context, query string) (*pkg.Song, error)
+	LoadSongInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error)
 	EnsureStreamInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error)
+	RefreshStream(ctx context.Context, song *pkg.Song) (*pkg.Song, error)
 }
 
 type Service struct {
 	*Player
-	storage Firestore
-	youtube YouTube
+	storage   Storage
+	youtube   YouTube
+	bans      Bans
+	config    Config
+	radio     *radio.Radio
+	histories *userHistories
+	enqueue   chan playRequest
 
 	radioMutex sync.Mutex
 	isRadio    bool
+	radioPool  []*pkg.Song // songs of the seeded radio, nil for the whole database
 }
 
-func NewMusicService(ctx context.Context, storage Firestore, youtube YouTube, voice VoiceClient, audio MediaPlayer) *Service {
+func NewMusicService(ctx context.Context, storage Storage, youtube YouTube, bans Bans, voice VoiceClient, audio MediaPlayer, config Config) *Service {
 	s := &Service{
-		Player:  NewPlayer(ctx, voice, audio),
-		storage: storage,
-		youtube: youtube,
+		Player:    NewPlayer(ctx, voice, audio, youtube),
+		storage:   storage,
+		youtube:   youtube,
+		bans:      bans,
+		config:    config,
+		radio:     radio.NewRadio(config.Radio),
+		histories: newUserHistories(storage),
+		enqueue:   make(chan playRequest, enqueueBuffer),
 	}
+	go s.processEnqueue(ctx)
 	s.Player.SubscribeOnErrors(s.handleError)
+	s.Player.SubscribeOnEvents(s.recordEvent)
+	s.loadRadioHistory(ctx)
 	return s
 }
 
@@ -54,7 +91,18 @@ func (s *Service) Play(ctx context.Context, query, userID, guildID, channelID st
 	contexts.GetLogger(ctx).Info("finding song")
 	song, err := s.youtube.FindSong(ctx, query)
 	if err != nil {
-		return nil, errors.Wrap(err, "find and load song from youtube")
+		return nil, errors.Wrap(err, "find song on youtube")
+	}
+	limits := s.config.limits(s.guildID(guildID))
+	if err := limits.Check(song); err != nil {
+		return nil, err
+	}
+	if err := s.bans.Check(song); err != nil {
+		return nil, err
+	}
+	song, err = s.youtube.EnsureStreamInfo(ctx, song)
+	if err != nil {
+		return nil, errors.Wrap(err, "load song from youtube")
 	}
 
 	if channelID != "" || guildID != "" {
@@ -62,24 +110,92 @@ func (s *Service) Play(ctx context.Context, query, userID, guildID, channelID st
 	}
 
 	song.LastPlay = time.Now()
+	song.RequesterID = userID
 	_, err = s.storage.UpsertSongIncPlaybacks(ctx, song)
 	if err != nil {
 		err = errors.Wrap(err, "upsert song with increment")
 	}
 
 	if userID != "" {
-		s.storage.IncrementUserRequests(ctx, song, userID)
+		if err := s.storage.IncrementUserRequests(ctx, song, userID); err != nil {
+			contexts.GetLogger(ctx).Error("increment user requests", zap.String("user", userID), zap.Error(err))
+		}
+		s.histories.add(userID, song)
 	}
 
-	go s.Player.Play(ctx, song)
+	s.enqueue <- playRequest{ctx: ctx, song: song}
 	return song, err
 }
 
+type playRequest struct {
+	ctx  context.Context
+	song *pkg.Song
+}
+
+// processEnqueue passes requested songs to the player in the order of requests without blocking the callers
+func (s *Service) processEnqueue(ctx context.Context) {
+	for {
+		select {
+		case r := <-s.enqueue:
+			s.Player.Play(r.ctx, r.song)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (s *Service) Random(ctx context.Context, n int) ([]*pkg.Song, error) {
-	return s.storage.GetRandomSongs(ctx, n)
+	return s.storage.GetRandomSongs(ctx, n, nil)
 }
 
+// SetRadio enables the radio over the whole database or disables it
 func (s *Service) SetRadio(ctx context.Context, b bool, guildID, channelID string) error {
+	return s.enableRadio(ctx, b, nil, guildID, channelID)
+}
+
+// SeedRadio enables the radio playing only songs related to the seed
+func (s *Service) SeedRadio(ctx context.Context, seed radio.Seed, guildID, channelID string) error {
+	pool, err := s.radioSeedPool(ctx, seed)
+	if err != nil {
+		return err
+	}
+	if len(pool) == 0 {
+		return ErrEmptyRadioSeed
+	}
+	return s.enableRadio(ctx, true, pool, guildID, channelID)
+}
+
+func (s *Service) radioSeedPool(ctx context.Context, seed radio.Seed) ([]*pkg.Song, error) {
+	switch seed.Type {
+	case radio.SeedArtist:
+		return radio.ByArtist(s.storage.SongsIndex(), seed.Value), nil
+	case radio.SeedUser:
+		songs, err := s.storage.GetUserSongs(ctx, seed.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get songs of %s", seed.Value)
+		}
+		return radio.TopTracks(songs), nil
+	case radio.SeedLike:
+		histories, err := s.histories.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return radio.Similar(pkg.ParseSongID(seed.Value), histories), nil
+	case radio.SeedLikes:
+		likes := make(map[string][]*pkg.Like, len(seed.Users))
+		for _, user := range seed.Users {
+			userLikes, err := s.storage.GetLikes(ctx, user)
+			if err != nil {
+				return nil, errors.Wrapf(err, "get likes of %s", user)
+			}
+			likes[user] = userLikes
+		}
+		return radio.Liked(likes), nil
+	}
+	return nil, errors.Errorf("unknown radio seed %s", seed.Type)
+}
+
+func (s *Service) enableRadio(ctx context.Context, b bool, pool []*pkg.Song, guildID, channelID string) error {
 	if !b {
 		s.setRadio(b)
 		return nil
@@ -90,7 +206,10 @@ func (s *Service) SetRadio(ctx context.Context, b bool, guildID, channelID strin
 		}
 		s.Player.Connect(ctx, guildID, channelID)
 	}
-	s.setRadio(b)
+	s.radioMutex.Lock()
+	s.isRadio = b
+	s.radioPool = pool
+	s.radioMutex.Unlock()
 	if s.NowPlaying() == nil {
 		return s.playRandomSong(ctx)
 	}
@@ -104,24 +223,68 @@ func (s *Service) setRadio(b bool) {
 }
 
 func (s *Service) playRandomSong(ctx context.Context) error {
-	songs, err := s.storage.GetRandomSongs(ctx, 1)
-	if err != nil {
-		return errors.Wrap(err, "get 1 random song from bd")
+	s.radioMutex.Lock()
+	pool := s.radioPool
+	s.radioMutex.Unlock()
+	if pool == nil {
+		pool = s.storage.SongsIndex()
+	}
+	// a small seeded pool may have no playable songs at all
+	ids := s.radio.Pick(s.guildID(""), pool, radioAttempts)
+	if len(ids) == 0 {
+		return errors.New("no preloaded songs")
 	}
-	song := songs[0]
-	if song.StreamURL == "" {
-		song, err = s.youtube.EnsureStreamInfo(ctx, song)
+	for _, id := range ids {
+		song, err := s.loadRadioSong(ctx, id)
 		if err != nil {
-			contexts.GetLogger(ctx).Error("ensure stream info for radio", zap.Error(err))
-			return s.playRandomSong(ctx)
-		}
-		if song.Duration > maxRadioSongDuration {
-			contexts.GetLogger(ctx).Info("too long song found - skipping")
-			return s.playRandomSong(ctx)
+			contexts.GetLogger(ctx).Info("radio song skipped", zap.String("id", id.String()), zap.Error(err))
+			continue
 		}
+		s.Player.Play(ctx, song)
+		return nil
 	}
-	s.Player.Play(ctx, song)
-	return nil
+	return errors.New("no playable radio songs")
+}
+
+func (s *Service) loadRadioSong(ctx context.Context, id pkg.SongID) (*pkg.Song, error) {
+	song, err := s.storage.GetSong(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get radio song from bd")
+	}
+	song.RequesterID = "" // radio songs are requested by nobody
+	if err := s.bans.Check(song); err != nil {
+		return nil, err
+	}
+	if song.StreamURL != "" {
+		return song, nil
+	}
+	song, err = s.youtube.LoadSongInfo(ctx, song)
+	if err != nil {
+		return nil, errors.Wrap(err, "load song info")
+	}
+	limits := s.config.limits(s.guildID(""))
+	if err := limits.Check(song); err != nil {
+		return nil, err
+	}
+	if song.Duration > maxRadioSongDuration {
+		return nil, ErrSongTooLong
+	}
+	song, err = s.youtube.EnsureStreamInfo(ctx, song)
+	if err != nil {
+		return nil, errors.Wrap(err, "ensure stream info")
+	}
+	return song, nil
+}
+
+// guildID returns the passed guild or the guild where the player is connected
+func (s *Service) guildID(guildID string) string {
+	if guildID != "" {
+		return guildID
+	}
+	if conn := s.Player.voice.Connection(); conn != nil {
+		return conn.GuildID
+	}
+	return ""
 }
 
 func (s *Service) RadioStatus() bool {
@@ -141,11 +304,39 @@ func (s *Service) handleError(err error) {
 		}
 		return
 	}
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		contexts.GetLogger(context.Background()).Error("song skipped",
+			zap.String("id", streamErr.Song.ID.String()),
+			zap.Error(streamErr.Err))
+		return
+	}
 	if !errors.Is(err, audio.ErrManualStop) && !errors.Is(err, io.EOF) {
 		s.setRadio(false)
 	}
 }
 
+// loadRadioHistory feeds recent plays to the radio before it picks anything,
+// so it knows requesters and avoids repeats right after restart
+func (s *Service) loadRadioHistory(ctx context.Context) {
+	n, err := s.radio.LoadHistory(ctx, s.storage)
+	if err != nil {
+		contexts.GetLogger(ctx).Error("load radio history", zap.Error(err))
+		return
+	}
+	contexts.GetLogger(ctx).Info("radio history loaded", zap.Int("events", n))
+}
+
+func (s *Service) recordEvent(e *pkg.PlayEvent) {
+	s.radio.Record(e)
+	if err := s.storage.AddEvent(context.Background(), e); err != nil {
+		contexts.GetLogger(context.Background()).Error("add play event",
+			zap.String("type", string(e.Type)),
+			zap.String("id", e.SongID.String()),
+			zap.Error(err))
+	}
+}
+
 func (s *Service) SubscribeOnErrors(h ErrorHandler) {
 	s.Player.SubscribeOnErrors(func(err error) {
 		if errors.Is(err, io.EOF) || errors.Is(err, audio.ErrManualStop) || errors.Is(err, ErrQueueEmpty) {
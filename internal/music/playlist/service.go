@@ -0,0 +1,346 @@
+package playlist
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/player"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const (
+	maxSongs = 500
+	// loadJobs is how many playlists may be enqueued in background at once
+	loadJobs = 4
+)
+
+var (
+	ErrNotFound    = errors.New("playlist not found")
+	ErrInvalidName = errors.New("invalid playlist name")
+	ErrEmpty       = errors.New("no songs to save")
+	ErrTooLong     = errors.New("playlist is too long")
+	ErrNoSong      = errors.New("no such song in the playlist")
+	ErrVisibility  = errors.New("unknown playlist visibility")
+	ErrBusy        = errors.New("too many playlists are being loaded")
+)
+
+type playlistStorage interface {
+	GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error)
+	SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error
+	DeletePlaylist(ctx context.Context, ownerID, name string) error
+	GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error)
+}
+
+type musicPlayer interface {
+	Play(ctx context.Context, query, userID, guildID, channelID string) (*pkg.Song, error)
+	NowPlaying() *pkg.Song
+	Queue() []*pkg.Song
+}
+
+type search interface {
+	FindSong(ctx context.Context, query string) (*pkg.Song, error)
+}
+
+// loadJob is the rest of the playlist enqueued in background after its first song
+type loadJob struct {
+	ctx      context.Context
+	playlist string
+	userID   string
+	songs    []pkg.PlaylistSong
+}
+
+// Service manages named playlists of users.
+// Owners can change their playlists, shared ones can be loaded by everyone in the guild.
+type Service struct {
+	storage playlistStorage
+	player  musicPlayer
+	search  search
+
+	ctx   context.Context
+	slots chan struct{} // a slot is taken by every loading playlist
+	loads chan loadJob
+}
+
+func NewService(ctx context.Context, storage playlistStorage, player musicPlayer, search search) *Service {
+	s := &Service{
+		storage: storage,
+		player:  player,
+		search:  search,
+		ctx:     ctx,
+		slots:   make(chan struct{}, loadJobs),
+		loads:   make(chan loadJob, loadJobs),
+	}
+	go s.processLoads(ctx)
+	return s
+}
+
+// CurrentQueue returns the playing song followed by the queue
+func (s *Service) CurrentQueue() []*pkg.Song {
+	songs := s.player.Queue()
+	if now := s.player.NowPlaying(); now != nil {
+		songs = append([]*pkg.Song{now}, songs...)
+	}
+	return songs
+}
+
+// Find returns the songs found by every input in the same order
+func (s *Service) Find(ctx context.Context, inputs []string) ([]*pkg.Song, error) {
+	songs := make([]*pkg.Song, 0, len(inputs))
+	for _, input := range inputs {
+		song, err := s.search.FindSong(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "find %q", input)
+		}
+		songs = append(songs, song)
+	}
+	return songs, nil
+}
+
+// Save creates the owner's playlist or replaces songs of the existing one
+func (s *Service) Save(ctx context.Context, ownerID, guildID, name string, songs []*pkg.Song) (*pkg.Playlist, error) {
+	if len(songs) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(songs) > maxSongs {
+		return nil, ErrTooLong
+	}
+	p, err := s.getOrNew(ctx, ownerID, guildID, name)
+	if err != nil {
+		return nil, err
+	}
+	p.Songs = make([]pkg.PlaylistSong, 0, len(songs))
+	for _, song := range songs {
+		p.Songs = append(p.Songs, pkg.NewPlaylistSong(song))
+	}
+	return p, s.set(ctx, p)
+}
+
+// Add appends the song to the owner's playlist, the playlist is created if needed
+func (s *Service) Add(ctx context.Context, ownerID, guildID, name string, song *pkg.Song) (*pkg.Playlist, error) {
+	p, err := s.getOrNew(ctx, ownerID, guildID, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Songs) >= maxSongs {
+		return nil, ErrTooLong
+	}
+	p.Songs = append(p.Songs, pkg.NewPlaylistSong(song))
+	return p, s.set(ctx, p)
+}
+
+// Remove deletes the song at the position counted from 1
+func (s *Service) Remove(ctx context.Context, ownerID, name string, position int) (*pkg.PlaylistSong, error) {
+	p, err := s.own(ctx, ownerID, name)
+	if err != nil {
+		return nil, err
+	}
+	if position < 1 || position > len(p.Songs) {
+		return nil, ErrNoSong
+	}
+	removed := p.Songs[position-1]
+	p.Songs = append(p.Songs[:position-1], p.Songs[position:]...)
+	return &removed, s.set(ctx, p)
+}
+
+// Reorder keeps only the listed songs in the listed order
+func (s *Service) Reorder(ctx context.Context, ownerID, name string, ids []pkg.SongID) (*pkg.Playlist, error) {
+	p, err := s.own(ctx, ownerID, name)
+	if err != nil {
+		return nil, err
+	}
+	songs := make(map[pkg.SongID]pkg.PlaylistSong, len(p.Songs))
+	for _, song := range p.Songs {
+		songs[song.ID] = song
+	}
+	p.Songs = make([]pkg.PlaylistSong, 0, len(ids))
+	for _, id := range ids {
+		song, ok := songs[id]
+		if !ok {
+			return nil, ErrNoSong
+		}
+		p.Songs = append(p.Songs, song)
+	}
+	return p, s.set(ctx, p)
+}
+
+// Share changes who can see and load the playlist
+func (s *Service) Share(ctx context.Context, ownerID, name string, visibility pkg.PlaylistVisibility) (*pkg.Playlist, error) {
+	if visibility != pkg.PlaylistPrivate && visibility != pkg.PlaylistGuild {
+		return nil, ErrVisibility
+	}
+	p, err := s.own(ctx, ownerID, name)
+	if err != nil {
+		return nil, err
+	}
+	p.Visibility = visibility
+	return p, s.set(ctx, p)
+}
+
+func (s *Service) Delete(ctx context.Context, ownerID, name string) error {
+	if _, err := s.own(ctx, ownerID, name); err != nil {
+		return err
+	}
+	return s.storage.DeletePlaylist(ctx, ownerID, pkg.NormalizePlaylistName(name))
+}
+
+// Get returns the user's playlist or the one shared in the guild with the same name.
+// Playlists shared in any guild are looked up if guildID is empty.
+func (s *Service) Get(ctx context.Context, userID, guildID, name string) (*pkg.Playlist, error) {
+	p, err := s.own(ctx, userID, name)
+	if !errors.Is(err, ErrNotFound) {
+		return p, err
+	}
+	shared, err := s.storage.GetPlaylists(ctx, &pkg.PlaylistFilter{GuildID: guildID, Visibility: pkg.PlaylistGuild})
+	if err != nil {
+		return nil, errors.Wrap(err, "get shared playlists")
+	}
+	name = pkg.NormalizePlaylistName(name)
+	for _, p := range shared {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns the user's playlists followed by playlists shared in the guild by others
+func (s *Service) List(ctx context.Context, userID, guildID string) ([]*pkg.Playlist, error) {
+	res, err := s.storage.GetPlaylists(ctx, &pkg.PlaylistFilter{OwnerID: userID})
+	if err != nil {
+		return nil, errors.Wrap(err, "get user playlists")
+	}
+	if guildID == "" {
+		return res, nil
+	}
+	shared, err := s.storage.GetPlaylists(ctx, &pkg.PlaylistFilter{GuildID: guildID, Visibility: pkg.PlaylistGuild})
+	if err != nil {
+		return nil, errors.Wrap(err, "get shared playlists")
+	}
+	for _, p := range shared {
+		if p.OwnerID != userID {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// Load enqueues songs of the playlist through the player as if the user requested them.
+// The first playable song is enqueued right away, so a missing connection is reported,
+// the rest are enqueued in background. The player joins the channel only if both guildID
+// and channelID are set, otherwise the current connection is used.
+// Returns the number of songs being enqueued, songs which can't be played are skipped.
+func (s *Service) Load(ctx context.Context, userID, guildID, channelID, name string) (*pkg.Playlist, int, error) {
+	p, err := s.Get(ctx, userID, guildID, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return p, 0, ErrBusy
+	}
+	if channelID == "" {
+		guildID = ""
+	}
+	logger := contexts.GetLogger(ctx)
+	for i, song := range p.Songs {
+		played, err := s.player.Play(ctx, song.URL, userID, guildID, channelID)
+		if errors.Is(err, player.ErrNotConnected) {
+			<-s.slots
+			return p, 0, err
+		}
+		if err != nil {
+			logger.Warn("playlist song skipped", zap.String("playlist", p.Name), zap.String("id", song.ID.String()), zap.Error(err))
+		}
+		if played == nil {
+			continue
+		}
+		rest := p.Songs[i+1:]
+		if len(rest) == 0 {
+			<-s.slots
+			return p, 1, nil
+		}
+		s.loads <- loadJob{
+			ctx:      contexts.WithLogger(s.ctx, logger),
+			playlist: p.Name,
+			userID:   userID,
+			songs:    rest,
+		}
+		return p, 1 + len(rest), nil
+	}
+	<-s.slots
+	return p, 0, nil
+}
+
+// processLoads enqueues the rest of the playlists one by one in the order of loading
+func (s *Service) processLoads(ctx context.Context) {
+	for {
+		select {
+		case job := <-s.loads:
+			s.load(job)
+			<-s.slots
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) load(job loadJob) {
+	logger := contexts.GetLogger(job.ctx)
+	for _, song := range job.songs {
+		if job.ctx.Err() != nil {
+			return
+		}
+		_, err := s.player.Play(job.ctx, song.URL, job.userID, "", "")
+		if errors.Is(err, player.ErrNotConnected) {
+			logger.Warn("playlist loading stopped", zap.String("playlist", job.playlist), zap.Error(err))
+			return
+		}
+		if err != nil {
+			logger.Warn("playlist song skipped", zap.String("playlist", job.playlist), zap.String("id", song.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// own returns the playlist if it belongs to the user
+func (s *Service) own(ctx context.Context, ownerID, name string) (*pkg.Playlist, error) {
+	name = pkg.NormalizePlaylistName(name)
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	p, err := s.storage.GetPlaylist(ctx, ownerID, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get playlist %s", name)
+	}
+	return p, nil
+}
+
+func (s *Service) getOrNew(ctx context.Context, ownerID, guildID, name string) (*pkg.Playlist, error) {
+	p, err := s.own(ctx, ownerID, name)
+	if !errors.Is(err, ErrNotFound) {
+		return p, err
+	}
+	return &pkg.Playlist{
+		Name:       pkg.NormalizePlaylistName(name),
+		OwnerID:    ownerID,
+		GuildID:    guildID,
+		Visibility: pkg.PlaylistPrivate,
+		Created:    time.Now(),
+	}, nil
+}
+
+func (s *Service) set(ctx context.Context, p *pkg.Playlist) error {
+	p.Updated = time.Now()
+	if err := s.storage.SetPlaylist(ctx, p); err != nil {
+		return errors.Wrapf(err, "set playlist %s", p.Name)
+	}
+	return nil
+}
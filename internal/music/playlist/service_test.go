@@ -0,0 +1,172 @@
+package playlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type fakePlayer struct {
+	mx       sync.Mutex
+	now      *pkg.Song
+	queue    []*pkg.Song
+	played   []string
+	channels []string // guild/channel of every request
+	// gates hold the requests of the query until the channel is closed
+	gates map[string]chan struct{}
+}
+
+func (p *fakePlayer) Play(ctx context.Context, query, userID, guildID, channelID string) (*pkg.Song, error) {
+	if gate, ok := p.gates[query]; ok {
+		<-gate
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.played = append(p.played, query)
+	p.channels = append(p.channels, guildID+"/"+channelID)
+	return &pkg.Song{URL: query}, nil
+}
+
+// wait returns the requests once there are n of them
+func (p *fakePlayer) wait(t *testing.T, n int) ([]string, []string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		p.mx.Lock()
+		played, channels := append([]string(nil), p.played...), append([]string(nil), p.channels...)
+		p.mx.Unlock()
+		if len(played) >= n {
+			return played, channels
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v requests, wanted %d", played, n)
+		}
+	}
+}
+
+func (p *fakePlayer) NowPlaying() *pkg.Song {
+	return p.now
+}
+
+func (p *fakePlayer) Queue() []*pkg.Song {
+	return p.queue
+}
+
+func testSong(id string) *pkg.Song {
+	return &pkg.Song{
+		ID:    pkg.SongID{ID: id, Service: pkg.ServiceYouTube},
+		Title: "song " + id,
+		URL:   "https://www.youtube.com/watch?v=" + id,
+	}
+}
+
+func TestServiceSaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	player := &fakePlayer{now: testSong("aaaaaaaaaaa"), queue: []*pkg.Song{testSong("bbbbbbbbbbb")}}
+	s := NewService(ctx, memory.NewMemoryClient(), player, nil)
+
+	if _, err := s.Save(ctx, "owner", "guild", "Friday-Night", s.CurrentQueue()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(ctx, "owner", "guild", "friday-night", testSong("ccccccccccc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Load(ctx, "other", "guild", "channel", "friday-night"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v loading a private playlist, wanted ErrNotFound", err)
+	}
+
+	if _, err := s.Share(ctx, "owner", "friday-night", pkg.PlaylistGuild); err != nil {
+		t.Fatal(err)
+	}
+	p, n, err := s.Load(ctx, "other", "guild", "channel", "friday-night")
+	if err != nil {
+		t.Fatal(err)
+	}
+	played, channels := player.wait(t, 3)
+	if n != 3 || len(played) != 3 || played[0] != p.Songs[0].URL || played[2] != testSong("ccccccccccc").URL {
+		t.Fatalf("got %d enqueued %v, wanted the queue and the added song in order", n, played)
+	}
+	if channels[0] != "guild/channel" || channels[1] != "/" {
+		t.Fatalf("got channels %v, wanted the first song to join the channel and the rest to use it", channels)
+	}
+	if list, err := s.List(ctx, "other", "guild"); err != nil || len(list) != 1 {
+		t.Fatalf("got %v %v, wanted the shared playlist", list, err)
+	}
+}
+
+func TestServiceEdit(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(ctx, memory.NewMemoryClient(), &fakePlayer{}, nil)
+	songs := []*pkg.Song{testSong("aaaaaaaaaaa"), testSong("bbbbbbbbbbb"), testSong("ccccccccccc")}
+	if _, err := s.Save(ctx, "owner", "", "gym", nil); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("got %v saving nothing, wanted ErrEmpty", err)
+	}
+	if _, err := s.Save(ctx, "owner", "", "bad name", songs); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("got %v, wanted ErrInvalidName", err)
+	}
+	if _, err := s.Save(ctx, "owner", "", "gym", songs); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Remove(ctx, "owner", "gym", 2)
+	if err != nil || removed.ID != songs[1].ID {
+		t.Fatalf("got %v %v, wanted the second song removed", removed, err)
+	}
+	if _, err := s.Remove(ctx, "owner", "gym", 3); !errors.Is(err, ErrNoSong) {
+		t.Fatalf("got %v, wanted ErrNoSong", err)
+	}
+
+	p, err := s.Reorder(ctx, "owner", "gym", []pkg.SongID{songs[2].ID, songs[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Songs) != 2 || p.Songs[0].ID != songs[2].ID || p.Songs[1].ID != songs[0].ID {
+		t.Fatalf("got %v, wanted reversed songs", p.Songs)
+	}
+
+	if err := s.Delete(ctx, "other", "gym"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v deleting someone else's playlist, wanted ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "owner", "gym"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "owner", "", "gym"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, wanted ErrNotFound", err)
+	}
+}
+
+func TestServiceLoadUsesConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	songs := []*pkg.Song{testSong("aaaaaaaaaaa"), testSong("bbbbbbbbbbb")}
+	// the first songs are enqueued right away, the rest wait in background and keep the slots
+	gate := make(chan struct{})
+	player := &fakePlayer{gates: map[string]chan struct{}{songs[1].URL: gate}}
+	s := NewService(ctx, memory.NewMemoryClient(), player, nil)
+	for i := 0; i <= loadJobs; i++ {
+		if _, err := s.Save(ctx, "owner", "guild", fmt.Sprintf("list-%d", i), songs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < loadJobs; i++ {
+		if _, n, err := s.Load(ctx, "owner", "guild", "", fmt.Sprintf("list-%d", i)); err != nil || n != 2 {
+			t.Fatalf("got %d, %v", n, err)
+		}
+	}
+	if _, _, err := s.Load(ctx, "owner", "guild", "", fmt.Sprintf("list-%d", loadJobs)); !errors.Is(err, ErrBusy) {
+		t.Fatalf("got %v, wanted ErrBusy", err)
+	}
+	close(gate)
+	_, channels := player.wait(t, 2*loadJobs)
+	for _, c := range channels {
+		if c != "/" {
+			t.Fatalf("got channels %v, wanted the current connection", channels)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package radio
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	defaultRepeatHours = 12
+	// historyWindow is how far back play events are loaded on start, requesters of older plays are forgotten
+	historyWindow = 90 * 24 * time.Hour
+	historyLimit  = 10000
+)
+
+// eventsStorage is the log of play events recorded by the player
+type eventsStorage interface {
+	GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error)
+}
+
+type Config struct {
+	Strategy    string            `json:"strategy,omitempty"`     // uniform, weighted (default), recency or balanced
+	RepeatHours int               `json:"repeat_hours,omitempty"` // recency window, 12 by default
+	Guilds      map[string]string `json:"guilds,omitempty"`       // guildID - strategy overriding the default one
+}
+
+// Radio picks songs with the strategy configured for the guild.
+// It remembers the play history to know recent plays and requesters of every song.
+type Radio struct {
+	config     Config
+	strategies map[string]Strategy
+
+	rngMx sync.Mutex
+	rng   *rand.Rand
+
+	historyMx  sync.RWMutex
+	played     map[pkg.SongID]time.Time
+	requesters map[pkg.SongID]map[string]int
+}
+
+func NewRadio(config Config) *Radio {
+	return newRadio(config, rand.NewSource(time.Now().UnixNano()))
+}
+
+func newRadio(config Config, src rand.Source) *Radio {
+	hours := config.RepeatHours
+	if hours <= 0 {
+		hours = defaultRepeatHours
+	}
+	return &Radio{
+		config: config,
+		strategies: map[string]Strategy{
+			StrategyUniform:  Uniform{},
+			StrategyWeighted: Weighted{},
+			StrategyRecency:  Recency{Window: time.Duration(hours) * time.Hour},
+			StrategyBalanced: Balanced{},
+		},
+		rng:        rand.New(src),
+		played:     make(map[pkg.SongID]time.Time),
+		requesters: make(map[pkg.SongID]map[string]int),
+	}
+}
+
+// Strategy returns the strategy of the guild, unknown names fall back to weighted
+func (r *Radio) Strategy(guildID string) Strategy {
+	name, ok := r.config.Guilds[guildID]
+	if !ok {
+		name = r.config.Strategy
+	}
+	if s, ok := r.strategies[name]; ok {
+		return s
+	}
+	return r.strategies[StrategyWeighted]
+}
+
+// Record adds the play event to the history, other events are ignored
+func (r *Radio) Record(e *pkg.PlayEvent) {
+	if e.Type != pkg.EventPlay {
+		return
+	}
+	r.historyMx.Lock()
+	defer r.historyMx.Unlock()
+	if e.At.After(r.played[e.SongID]) {
+		r.played[e.SongID] = e.At
+	}
+	if e.RequesterID == "" {
+		return
+	}
+	if _, ok := r.requesters[e.SongID]; !ok {
+		r.requesters[e.SongID] = make(map[string]int)
+	}
+	r.requesters[e.SongID][e.RequesterID]++
+}
+
+// LoadHistory records the logged play events, so recent plays and requesters of songs survive restarts.
+// Radio plays are logged too, although they don't update the stored songs.
+func (r *Radio) LoadHistory(ctx context.Context, events eventsStorage) (int, error) {
+	logged, err := events.GetEvents(ctx, &pkg.EventFilter{
+		From:  time.Now().Add(-historyWindow),
+		Types: []pkg.EventType{pkg.EventPlay},
+		Limit: historyLimit,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "get play events")
+	}
+	for _, e := range logged {
+		r.Record(e)
+	}
+	return len(logged), nil
+}
+
+// Pick returns up to n distinct songs from the index chosen by the guild strategy
+func (r *Radio) Pick(guildID string, index []*pkg.Song, n int) []pkg.SongID {
+	candidates := r.candidates(index)
+	weights := r.Strategy(guildID).Weights(candidates, time.Now())
+	if sum(weights) == 0 {
+		// everything is excluded, better repeat than stay silent
+		weights = Uniform{}.Weights(candidates, time.Now())
+	}
+
+	r.rngMx.Lock()
+	defer r.rngMx.Unlock()
+	res := make([]pkg.SongID, 0, n)
+	for len(res) < n {
+		i := r.sample(weights)
+		if i < 0 {
+			break
+		}
+		res = append(res, candidates[i].Song.ID)
+		weights[i] = 0
+	}
+	return res
+}
+
+func (r *Radio) candidates(index []*pkg.Song) []Candidate {
+	r.historyMx.RLock()
+	defer r.historyMx.RUnlock()
+	res := make([]Candidate, 0, len(index))
+	for _, song := range index {
+		c := Candidate{Song: song, Requester: topRequester(r.requesters[song.ID])}
+		if played := r.played[song.ID]; played.After(song.LastPlay) {
+			// radio plays do not update the stored song
+			copied := *song
+			copied.LastPlay = played
+			c.Song = &copied
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// sample returns the index chosen with probability proportional to its weight or -1 if all weights are zero
+func (r *Radio) sample(weights []float64) int {
+	total := sum(weights)
+	if total <= 0 {
+		return -1
+	}
+	x := r.rng.Float64() * total
+	last := -1
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		last = i
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return last
+}
+
+func topRequester(counts map[string]int) string {
+	top, max := "", 0
+	for user, n := range counts {
+		if n > max || (n == max && user < top) {
+			top, max = user, n
+		}
+	}
+	return top
+}
+
+func sum(weights []float64) float64 {
+	total := 0.0
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	return total
+}
@@ -0,0 +1,221 @@
+package radio
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func testIndex(playbacks ...int) []*pkg.Song {
+	res := make([]*pkg.Song, 0, len(playbacks))
+	for i, p := range playbacks {
+		id := pkg.SongID{ID: string(rune('a' + i)), Service: pkg.ServiceYouTube}
+		res = append(res, &pkg.Song{ID: id, Playbacks: p})
+	}
+	return res
+}
+
+func countPicks(r *Radio, guildID string, index []*pkg.Song, rounds int) map[pkg.SongID]int {
+	counts := make(map[pkg.SongID]int)
+	for i := 0; i < rounds; i++ {
+		for _, id := range r.Pick(guildID, index, 1) {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+func TestRadioWeighted(t *testing.T) {
+	r := newRadio(Config{Strategy: StrategyWeighted}, rand.NewSource(1))
+	index := testIndex(1, 99)
+	counts := countPicks(r, "", index, 1000)
+	if counts[index[0].ID] > 50 {
+		t.Fatalf("one-off song picked %d times out of 1000", counts[index[0].ID])
+	}
+}
+
+func TestRadioRecency(t *testing.T) {
+	r := newRadio(Config{Strategy: StrategyRecency, RepeatHours: 2}, rand.NewSource(1))
+	index := testIndex(100, 1, 1)
+	r.Record(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: index[0].ID, At: time.Now().Add(-time.Hour)})
+	if counts := countPicks(r, "", index, 200); counts[index[0].ID] != 0 {
+		t.Fatalf("recently played song picked %d times", counts[index[0].ID])
+	}
+
+	// everything played recently, the radio still plays something
+	for _, song := range index {
+		r.Record(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: song.ID, At: time.Now()})
+	}
+	if got := r.Pick("", index, 1); len(got) != 1 {
+		t.Fatalf("got %v, wanted a song", got)
+	}
+}
+
+func TestRadioBalanced(t *testing.T) {
+	r := newRadio(Config{Strategy: StrategyUniform, Guilds: map[string]string{"guild": StrategyBalanced}}, rand.NewSource(1))
+	index := testIndex(1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	// the first user requested nine songs, the second only one
+	for i, song := range index {
+		user := "first"
+		if i == 0 {
+			user = "second"
+		}
+		r.Record(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: song.ID, RequesterID: user})
+	}
+	counts := countPicks(r, "guild", index, 1000)
+	if n := counts[index[0].ID]; n < 400 || n > 600 {
+		t.Fatalf("second user's song picked %d times out of 1000, wanted about half", n)
+	}
+	counts = countPicks(r, "other", index, 1000)
+	if n := counts[index[0].ID]; n > 200 {
+		t.Fatalf("uniform picked one of 10 songs %d times out of 1000", n)
+	}
+}
+
+type fakeEvents struct {
+	events []*pkg.PlayEvent
+	filter *pkg.EventFilter
+}
+
+func (f *fakeEvents) GetEvents(_ context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	f.filter = filter
+	return f.events, nil
+}
+
+func TestRadioLoadHistory(t *testing.T) {
+	index := testIndex(100, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	// the first user requested most of the songs, the most played one was just played by the radio
+	events := &fakeEvents{events: []*pkg.PlayEvent{
+		{Type: pkg.EventPlay, SongID: index[0].ID, At: time.Now().Add(-time.Hour)},
+	}}
+	for i, song := range index[1:] {
+		user := "first"
+		if i == 0 {
+			user = "second"
+		}
+		events.events = append(events.events, &pkg.PlayEvent{
+			Type: pkg.EventPlay, SongID: song.ID, RequesterID: user, At: time.Now().Add(-72 * time.Hour),
+		})
+	}
+
+	// the radio after restart knows only the logged events
+	recency := newRadio(Config{Strategy: StrategyRecency, RepeatHours: 2}, rand.NewSource(1))
+	n, err := recency.LoadHistory(context.Background(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(events.events) {
+		t.Fatalf("loaded %d events, wanted %d", n, len(events.events))
+	}
+	if len(events.filter.Types) != 1 || events.filter.Types[0] != pkg.EventPlay || events.filter.From.IsZero() {
+		t.Fatalf("got filter %+v, wanted recent plays", events.filter)
+	}
+	if n := countPicks(recency, "", index, 200)[index[0].ID]; n != 0 {
+		t.Fatalf("song played by the radio an hour ago picked %d times", n)
+	}
+
+	balanced := newRadio(Config{Strategy: StrategyBalanced}, rand.NewSource(1))
+	if _, err := balanced.LoadHistory(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	// only the requested songs, the song played by the radio has no requester
+	counts := countPicks(balanced, "", index[1:], 1000)
+	if n := counts[index[1].ID]; n < 400 || n > 600 {
+		t.Fatalf("second user's song picked %d times out of 1000, wanted about half", n)
+	}
+}
+
+func TestRadioPickDistinct(t *testing.T) {
+	r := newRadio(Config{}, rand.NewSource(1))
+	index := testIndex(1, 2, 3)
+	got := r.Pick("", index, 5)
+	if len(got) != 3 {
+		t.Fatalf("got %v, wanted all 3 songs", got)
+	}
+	seen := make(map[pkg.SongID]bool)
+	for _, id := range got {
+		if seen[id] {
+			t.Fatalf("song %v picked twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	index := testIndex(1, 1, 1, 1)
+	histories := map[string][]*pkg.Song{
+		"first":  {index[0], index[1], index[2]},
+		"second": {index[0], index[1]},
+		"third":  {index[2], index[3]},
+	}
+	got := Similar(index[0].ID, histories)
+	counts := make(map[pkg.SongID]int)
+	for _, song := range got {
+		counts[song.ID] = song.Playbacks
+	}
+	want := map[pkg.SongID]int{index[1].ID: 2, index[2].ID: 1}
+	if len(counts) != len(want) {
+		t.Fatalf("got %v, wanted %v", counts, want)
+	}
+	for id, n := range want {
+		if counts[id] != n {
+			t.Fatalf("got %v, wanted %v", counts, want)
+		}
+	}
+}
+
+func TestLiked(t *testing.T) {
+	index := testIndex(1, 1, 1)
+	like := func(song *pkg.Song) *pkg.Like { return pkg.NewLike(song, time.Now()) }
+	got := Liked(map[string][]*pkg.Like{
+		"first":  {like(index[0]), like(index[1])},
+		"second": {like(index[1])},
+	})
+	counts := make(map[pkg.SongID]int)
+	for _, song := range got {
+		counts[song.ID] = song.Playbacks
+	}
+	if len(counts) != 2 || counts[index[0].ID] != 1 || counts[index[1].ID] != 2 {
+		t.Fatalf("got %v, wanted the union counted by likes", counts)
+	}
+}
+
+func TestByArtist(t *testing.T) {
+	index := testIndex(1, 1, 1, 1)
+	index[0].ArtistName = "Darude"
+	index[1].ArtistName = "darude"
+	index[2].ArtistName, index[2].ArtistURL = "Darude - Topic", "https://youtube.com/channel/UCxxxxxxxxxxxxxxxxxxxxxx"
+	index[3].ArtistName = "a-ha"
+
+	if got := ByArtist(index, " DARUDE "); len(got) != 2 || got[0] != index[0] || got[1] != index[1] {
+		t.Fatalf("got %v, wanted songs of the artist matched by name", got)
+	}
+	if got := ByArtist(index, index[2].ArtistURL); len(got) != 1 || got[0] != index[2] {
+		t.Fatalf("got %v, wanted the song of the channel", got)
+	}
+	if got := ByArtist(index, "Sandstorm"); len(got) != 0 {
+		t.Fatalf("got %v, wanted no songs", got)
+	}
+}
+
+func TestTopTracks(t *testing.T) {
+	playbacks := make([]int, 0, topTracksNumber+10)
+	for i := 0; i < topTracksNumber+10; i++ {
+		playbacks = append(playbacks, i)
+	}
+	history := testIndex(playbacks...)
+
+	got := TopTracks(history)
+	if len(got) != topTracksNumber {
+		t.Fatalf("got %d songs, wanted %d", len(got), topTracksNumber)
+	}
+	if got[0].Playbacks != topTracksNumber+9 || got[len(got)-1].Playbacks != 10 {
+		t.Fatalf("got %d..%d playbacks, wanted the most requested songs first", got[0].Playbacks, got[len(got)-1].Playbacks)
+	}
+	if history[0].Playbacks != 0 {
+		t.Fatal("history was reordered")
+	}
+}
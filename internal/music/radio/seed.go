@@ -0,0 +1,112 @@
+package radio
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const topTracksNumber = 50
+
+type SeedType string
+
+const (
+	SeedArtist SeedType = "artist"
+	SeedLike   SeedType = "like"
+	SeedUser   SeedType = "user"
+	SeedLikes  SeedType = "likes"
+)
+
+// Seed narrows the radio down to songs related to Value:
+// the artist name, the song ID or the user ID depending on Type.
+// The likes seed uses Users instead.
+type Seed struct {
+	Type  SeedType
+	Value string
+	Users []string
+}
+
+// ByArtist returns songs of the artist matched by name or URL
+func ByArtist(index []*pkg.Song, artist string) []*pkg.Song {
+	artist = strings.TrimSpace(artist)
+	res := make([]*pkg.Song, 0)
+	for _, song := range index {
+		if strings.EqualFold(song.ArtistName, artist) || (song.ArtistURL != "" && song.ArtistURL == artist) {
+			res = append(res, song)
+		}
+	}
+	return res
+}
+
+// Similar returns songs requested by the same users as the song.
+// Playbacks of the result are the number of users who requested both songs.
+func Similar(id pkg.SongID, histories map[string][]*pkg.Song) []*pkg.Song {
+	similar := make(map[pkg.SongID]*pkg.Song)
+	for _, songs := range histories {
+		if !contains(songs, id) {
+			continue
+		}
+		for _, song := range songs {
+			if song.ID == id {
+				continue
+			}
+			s, ok := similar[song.ID]
+			if !ok {
+				s = &pkg.Song{ID: song.ID, ArtistName: song.ArtistName, ArtistURL: song.ArtistURL}
+				similar[song.ID] = s
+			}
+			s.Playbacks++
+			if song.LastPlay.After(s.LastPlay) {
+				s.LastPlay = song.LastPlay
+			}
+		}
+	}
+	res := make([]*pkg.Song, 0, len(similar))
+	for _, song := range similar {
+		res = append(res, song)
+	}
+	return res
+}
+
+// TopTracks returns the most requested songs of the user history
+func TopTracks(songs []*pkg.Song) []*pkg.Song {
+	res := append([]*pkg.Song(nil), songs...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Playbacks > res[j].Playbacks
+	})
+	if len(res) > topTracksNumber {
+		res = res[:topTracksNumber]
+	}
+	return res
+}
+
+// Liked returns the union of the users' likes.
+// Playbacks of the result are the number of users who liked the song.
+func Liked(likes map[string][]*pkg.Like) []*pkg.Song {
+	liked := make(map[pkg.SongID]*pkg.Song)
+	for _, userLikes := range likes {
+		for _, like := range userLikes {
+			s, ok := liked[like.ID]
+			if !ok {
+				s = &pkg.Song{ID: like.ID, Title: like.Title, URL: like.URL}
+				liked[like.ID] = s
+			}
+			s.Playbacks++
+		}
+	}
+	res := make([]*pkg.Song, 0, len(liked))
+	for _, song := range liked {
+		res = append(res, song)
+	}
+	return res
+}
+
+func contains(songs []*pkg.Song, id pkg.SongID) bool {
+	for _, song := range songs {
+		if song.ID == id {
+			return true
+		}
+	}
+	return false
+}
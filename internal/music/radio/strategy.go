@@ -0,0 +1,96 @@
+package radio
+
+import (
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	StrategyUniform  = "uniform"
+	StrategyWeighted = "weighted"
+	StrategyRecency  = "recency"
+	StrategyBalanced = "balanced"
+)
+
+// Candidate is a song the radio can play
+type Candidate struct {
+	Song      *pkg.Song
+	Requester string // the user who requested the song most often, empty if unknown
+}
+
+// Strategy decides how likely every candidate is to be played next.
+// Weights returns the relative chance for each candidate, zero excludes the candidate.
+type Strategy interface {
+	Weights(candidates []Candidate, now time.Time) []float64
+}
+
+// Uniform gives every song the same chance
+type Uniform struct{}
+
+func (Uniform) Weights(candidates []Candidate, _ time.Time) []float64 {
+	w := make([]float64, len(candidates))
+	for i := range w {
+		w[i] = 1
+	}
+	return w
+}
+
+// Weighted plays songs proportionally to their playbacks,
+// so a one-off request is rarely heard while favorites come back often.
+type Weighted struct{}
+
+func (Weighted) Weights(candidates []Candidate, _ time.Time) []float64 {
+	w := make([]float64, len(candidates))
+	for i := range candidates {
+		w[i] = playbacks(&candidates[i])
+	}
+	return w
+}
+
+// Recency is Weighted which never repeats a song within Window
+// and penalizes songs played shortly before that.
+type Recency struct {
+	Window time.Duration
+}
+
+func (r Recency) Weights(candidates []Candidate, now time.Time) []float64 {
+	w := make([]float64, len(candidates))
+	for i := range candidates {
+		if candidates[i].Song.LastPlay.IsZero() {
+			w[i] = playbacks(&candidates[i])
+			continue
+		}
+		since := now.Sub(candidates[i].Song.LastPlay)
+		if since < r.Window {
+			continue
+		}
+		// from 1/2 right after the window up to 1 for long forgotten songs
+		w[i] = playbacks(&candidates[i]) * float64(since) / float64(since+r.Window)
+	}
+	return w
+}
+
+// Balanced gives every requester the same share of the radio,
+// within the share songs are weighted by playbacks.
+// Songs with unknown requesters share one more slot.
+type Balanced struct{}
+
+func (Balanced) Weights(candidates []Candidate, _ time.Time) []float64 {
+	total := make(map[string]float64)
+	for i := range candidates {
+		total[candidates[i].Requester] += playbacks(&candidates[i])
+	}
+	w := make([]float64, len(candidates))
+	for i := range candidates {
+		w[i] = playbacks(&candidates[i]) / total[candidates[i].Requester]
+	}
+	return w
+}
+
+func playbacks(c *Candidate) float64 {
+	if c.Song.Playbacks < 1 {
+		return 1
+	}
+	return float64(c.Song.Playbacks)
+}
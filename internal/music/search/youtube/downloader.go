@@ -2,9 +2,13 @@ package youtube
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/kkdai/youtube/v2"
 	"github.com/kkdai/youtube/v2/downloader"
@@ -14,26 +18,123 @@ import (
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 )
 
+const (
+	defaultWorkers   = 2
+	partialSuffix    = ".part"
+	progressInterval = 2 * time.Second
+)
+
 type filesCache interface {
 	Add(path string)
 	Remove(path string)
 }
 
+// loadedCache is the files cache which accounts the size of completed downloads
+type loadedCache interface {
+	Loaded(path string)
+}
+
+// Progress of the song download
+type Progress struct {
+	VideoID    string
+	Title      string
+	Downloaded int64
+	Total      int64
+	Done       bool
+	Err        error
+}
+
+func (p Progress) Percent() float64 {
+	if p.Done {
+		return 100
+	}
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Downloaded) / float64(p.Total) * 100
+}
+
+type ProgressHandler func(p Progress)
+
+type progressKey struct{}
+
+// WithProgressHandler returns the context which subscribes on progress of downloads started with it
+func WithProgressHandler(parent context.Context, h ProgressHandler) context.Context {
+	return context.WithValue(parent, progressKey{}, h)
+}
+
+func progressHandler(ctx context.Context) ProgressHandler {
+	h, _ := ctx.Value(progressKey{}).(ProgressHandler)
+	return h
+}
+
+type job struct {
+	video  *youtube.Video
+	format *youtube.Format
+	path   string
+	logger *zap.Logger
+	pins   int // files cache references taken by the downloads of the job, guarded by inflightMx
+
+	done chan struct{}
+	err  error
+
+	handlersMx sync.Mutex
+	handlers   []ProgressHandler
+}
+
+func (j *job) subscribe(h ProgressHandler) {
+	if h == nil {
+		return
+	}
+	j.handlersMx.Lock()
+	j.handlers = append(j.handlers, h)
+	j.handlersMx.Unlock()
+}
+
+func (j *job) publish(p Progress) {
+	j.handlersMx.Lock()
+	handlers := make([]ProgressHandler, len(j.handlers))
+	copy(handlers, j.handlers)
+	j.handlersMx.Unlock()
+	for _, h := range handlers {
+		h(p)
+	}
+}
+
+// Downloader loads songs in the pool of workers.
+// Concurrent downloads of the same file are deduplicated and interrupted downloads are resumed.
 type Downloader struct {
+	ctx    context.Context
 	loaded filesCache
 	downloader.Downloader
+
+	jobs       chan *job
+	inflightMx sync.Mutex
+	inflight   map[string]*job
 }
 
-func NewDownloader(client youtube.Client, outputDir string, cache filesCache) *Downloader {
-	return &Downloader{
+func NewDownloader(ctx context.Context, client youtube.Client, outputDir string, cache filesCache, workers int) *Downloader {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	dl := &Downloader{
+		ctx:    ctx,
 		loaded: cache,
 		Downloader: downloader.Downloader{
 			Client:    client,
 			OutputDir: outputDir,
 		},
+		jobs:     make(chan *job),
+		inflight: make(map[string]*job),
 	}
+	for i := 0; i < workers; i++ {
+		go dl.worker(ctx)
+	}
+	return dl
 }
 
+// Download schedules the download and returns without waiting for it.
+// Use Wait to make sure that the file is ready.
 func (dl *Downloader) Download(ctx context.Context, v *youtube.Video, format *youtube.Format, outputFile string) error {
 	destinationFile, err := dl.getOutputFile(outputFile)
 	if err != nil {
@@ -42,35 +143,117 @@ func (dl *Downloader) Download(ctx context.Context, v *youtube.Video, format *yo
 	logger := contexts.GetLogger(ctx)
 	dl.loaded.Add(destinationFile)
 
-	var out *os.File
-	_, err = os.Stat(destinationFile)
-	switch err {
-	case nil:
+	dl.inflightMx.Lock()
+	if j, ok := dl.inflight[destinationFile]; ok {
+		j.pins++
+		j.subscribe(progressHandler(ctx))
+		dl.inflightMx.Unlock()
+		logger.Info("file is already downloading", zap.String("name", destinationFile))
+		return nil
+	}
+	if _, err := os.Stat(destinationFile); err == nil {
+		dl.inflightMx.Unlock()
 		logger.Info("file is already downloaded", zap.String("name", destinationFile))
+		if h := progressHandler(ctx); h != nil {
+			h(Progress{VideoID: v.ID, Title: v.Title, Done: true})
+		}
 		return nil
-	default:
-		if errors.Is(err, os.ErrNotExist) {
-			out, err = os.Create(destinationFile)
-			if err != nil {
-				dl.loaded.Remove(destinationFile)
-				return err
-			}
-		} else {
-			dl.loaded.Remove(destinationFile)
-			return err
+	}
+	j := &job{
+		video:  v,
+		format: format,
+		path:   destinationFile,
+		logger: logger,
+		pins:   1,
+		done:   make(chan struct{}),
+	}
+	j.subscribe(progressHandler(ctx))
+	dl.inflight[destinationFile] = j
+	dl.inflightMx.Unlock()
+
+	go func() {
+		select {
+		case dl.jobs <- j:
+		case <-dl.ctx.Done():
+			dl.finish(j, errors.Wrap(dl.ctx.Err(), "downloader stopped"))
 		}
+	}()
+	return nil
+}
+
+// Wait blocks until the file is downloaded. It returns immediately if the file is not being downloaded.
+func (dl *Downloader) Wait(ctx context.Context, path string) error {
+	dl.inflightMx.Lock()
+	j, ok := dl.inflight[path]
+	dl.inflightMx.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (dl *Downloader) worker(ctx context.Context) {
+	for {
+		select {
+		case j := <-dl.jobs:
+			dl.finish(j, dl.process(ctx, j))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// finish completes the job. It is removed from inflight before the final progress is published,
+// so every subscriber gets it and the later downloads of the path don't join the finished job.
+func (dl *Downloader) finish(j *job, err error) {
+	dl.inflightMx.Lock()
+	delete(dl.inflight, j.path)
+	pins := j.pins
+	dl.inflightMx.Unlock()
+
+	j.err = err
+	if err != nil {
+		// Every deduplicated download pinned the file
+		for i := 0; i < pins; i++ {
+			dl.loaded.Remove(j.path)
+		}
+		j.logger.Error("download failed", zap.String("filename", j.path), zap.Error(err))
+	} else if c, ok := dl.loaded.(loadedCache); ok {
+		c.Loaded(j.path)
+	}
+	j.publish(Progress{VideoID: j.video.ID, Title: j.video.Title, Done: err == nil, Err: err})
+	close(j.done)
+}
+
+func (dl *Downloader) process(ctx context.Context, j *job) error {
+	partial := j.path + partialSuffix
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "open partial file")
 	}
 	defer out.Close()
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return errors.Wrap(err, "seek partial file")
+	}
 
-	logger.Info("downloading video",
-		zap.String("title", v.Title),
-		zap.String("codec", format.MimeType),
-		zap.String("filename", destinationFile))
-	if err := dl.videoDLWorker(ctx, out, v, format); err != nil {
-		dl.loaded.Remove(destinationFile)
+	j.logger.Info("downloading video",
+		zap.String("title", j.video.Title),
+		zap.String("codec", j.format.MimeType),
+		zap.String("filename", j.path),
+		zap.Int64("resume_from", offset))
+	if err := dl.videoDLWorker(ctx, out, offset, j); err != nil {
 		return err
 	}
-	return nil
+	if err := out.Close(); err != nil {
+		return errors.Wrap(err, "close partial file")
+	}
+	return os.Rename(partial, j.path)
 }
 
 func (dl *Downloader) getOutputFile(outputFile string) (string, error) {
@@ -83,34 +266,76 @@ func (dl *Downloader) getOutputFile(outputFile string) (string, error) {
 	return outputFile, nil
 }
 
-func (dl *Downloader) videoDLWorker(ctx context.Context, out *os.File, video *youtube.Video, format *youtube.Format) error {
-	stream, size, err := dl.GetStreamContext(ctx, video, format)
+func (dl *Downloader) videoDLWorker(ctx context.Context, out *os.File, offset int64, j *job) error {
+	streamURL, err := dl.GetStreamURLContext(ctx, j.video, j.format)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "get stream url")
+	}
+	total := j.format.ContentLength
+	if total > 0 && offset >= total {
+		return nil
 	}
 
-	reader := stream
-	prog := &progress{contentLength: float64(size)}
-	mw := io.MultiWriter(out, prog)
-	_, err = io.Copy(mw, reader)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, http.NoBody)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "create stream request")
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	client := dl.Client.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "get stream")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The range is ignored, download from scratch
+		if offset > 0 {
+			if err := out.Truncate(0); err != nil {
+				return errors.Wrap(err, "truncate partial file")
+			}
+			if _, err := out.Seek(0, io.SeekStart); err != nil {
+				return errors.Wrap(err, "seek partial file")
+			}
+			offset = 0
+		}
+	default:
+		return errors.Errorf("unexpected stream status %d", resp.StatusCode)
+	}
+	if total <= 0 && resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+
+	prog := &progress{
+		job:     j,
+		current: Progress{VideoID: j.video.ID, Title: j.video.Title, Downloaded: offset, Total: total},
+	}
+	_, err = io.Copy(io.MultiWriter(out, prog), resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "copy stream")
 	}
 	return nil
 }
 
 type progress struct {
-	contentLength     float64
-	totalWrittenBytes float64
-	downloadLevel     float64
+	job       *job
+	current   Progress
+	published time.Time
 }
 
 func (dl *progress) Write(p []byte) (n int, err error) {
 	n = len(p)
-	dl.totalWrittenBytes += float64(n)
-	currentPercent := (dl.totalWrittenBytes / dl.contentLength) * 100
-	if (dl.downloadLevel <= currentPercent) && (dl.downloadLevel < 100) {
-		dl.downloadLevel++
+	dl.current.Downloaded += int64(n)
+	if time.Since(dl.published) >= progressInterval {
+		dl.published = time.Now()
+		dl.job.publish(dl.current)
 	}
 	return
 }
@@ -0,0 +1,126 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ytdl "github.com/kkdai/youtube/v2"
+	"github.com/kkdai/youtube/v2/downloader"
+)
+
+// pinsCounter counts the references of the files
+type pinsCounter struct {
+	mx   sync.Mutex
+	pins map[string]int
+}
+
+func (c *pinsCounter) Add(path string) {
+	c.mx.Lock()
+	c.pins[path]++
+	c.mx.Unlock()
+}
+
+func (c *pinsCounter) Remove(path string) {
+	c.mx.Lock()
+	c.pins[path]--
+	c.mx.Unlock()
+}
+
+func (c *pinsCounter) get(path string) int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.pins[path]
+}
+
+// newTestDownloader returns the downloader without workers, so the scheduled jobs wait until the test starts one
+func newTestDownloader(ctx context.Context, t *testing.T) (*Downloader, *pinsCounter) {
+	t.Helper()
+	cache := &pinsCounter{pins: make(map[string]int)}
+	return &Downloader{
+		ctx:    ctx,
+		loaded: cache,
+		Downloader: downloader.Downloader{
+			Client:    ytdl.Client{},
+			OutputDir: t.TempDir(),
+		},
+		jobs:     make(chan *job),
+		inflight: make(map[string]*job),
+	}, cache
+}
+
+func waitProgress(t *testing.T, progress <-chan Progress) Progress {
+	t.Helper()
+	select {
+	case p := <-progress:
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("final progress was not published")
+	}
+	return Progress{}
+}
+
+func TestDownloaderFailedDeduplicatedDownloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dl, cache := newTestDownloader(ctx, t)
+	video := &ytdl.Video{ID: "y6120QOlsfU", Title: "song"}
+
+	progress := make(chan Progress, 10)
+	handler := WithProgressHandler(ctx, func(p Progress) { progress <- p })
+	// The format without the stream url can't be downloaded
+	for i := 0; i < 2; i++ {
+		if err := dl.Download(handler, video, &ytdl.Format{}, "song.m4a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := dl.getOutputFile("song.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pins := cache.get(path); pins != 2 {
+		t.Fatalf("got %d pins, wanted 2", pins)
+	}
+
+	go dl.worker(ctx)
+	for i := 0; i < 2; i++ {
+		if p := waitProgress(t, progress); p.Err == nil || p.Done {
+			t.Fatalf("got %+v, wanted the failed download", p)
+		}
+	}
+	if pins := cache.get(path); pins != 0 {
+		t.Fatalf("got %d pins after the failure, wanted 0", pins)
+	}
+	if err := dl.Wait(ctx, path); err != nil {
+		t.Fatalf("failed download is still in flight: %v", err)
+	}
+}
+
+func TestDownloaderStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dl, cache := newTestDownloader(ctx, t)
+	video := &ytdl.Video{ID: "y6120QOlsfU", Title: "song"}
+
+	progress := make(chan Progress, 1)
+	handler := WithProgressHandler(context.Background(), func(p Progress) { progress <- p })
+	if err := dl.Download(handler, video, &ytdl.Format{}, "song.m4a"); err != nil {
+		t.Fatal(err)
+	}
+	path, err := dl.getOutputFile("song.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if p := waitProgress(t, progress); !errors.Is(p.Err, context.Canceled) {
+		t.Fatalf("got %v, wanted the stopped download", p.Err)
+	}
+	if pins := cache.get(path); pins != 0 {
+		t.Fatalf("got %d pins after the stop, wanted 0", pins)
+	}
+	if err := dl.Wait(context.Background(), path); err != nil {
+		t.Fatalf("stopped download is still in flight: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package youtube
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/util"
+)
+
+const defaultQueryTTL = 30 * 24 * time.Hour
+
+type queryStorage interface {
+	GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error)
+	SetQuery(ctx context.Context, query *pkg.SearchQuery) error
+	DeleteQueries(ctx context.Context, query string) (int, error)
+}
+
+// CacheStats describes how often search queries are answered from the cache
+type CacheStats struct {
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+	Expired int `json:"expired"`
+	Purged  int `json:"purged"`
+}
+
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses + s.Expired
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// queryCache is a persistent query->SongID cache in front of the YouTube search
+type queryCache struct {
+	storage queryStorage
+	ttl     time.Duration
+	now     func() time.Time
+
+	statsMx sync.Mutex
+	stats   CacheStats
+}
+
+func newQueryCache(storage queryStorage, ttl time.Duration) *queryCache {
+	if ttl <= 0 {
+		ttl = defaultQueryTTL
+	}
+	return &queryCache{
+		storage: storage,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// get returns cached song ID for the query. Expired entries are returned with fresh=false
+// so that they still can be used when the search API is not available.
+func (c *queryCache) get(ctx context.Context, query string) (id pkg.SongID, fresh, ok bool) {
+	q, err := c.storage.GetQuery(ctx, normalizeQuery(query))
+	if err != nil || q == nil || q.ID.ID == "" {
+		c.count(func(s *CacheStats) { s.Misses++ })
+		return pkg.SongID{}, false, false
+	}
+	if c.now().Sub(q.Updated) > c.ttl {
+		c.count(func(s *CacheStats) { s.Expired++ })
+		return q.ID, false, true
+	}
+	c.count(func(s *CacheStats) { s.Hits++ })
+	return q.ID, true, true
+}
+
+func (c *queryCache) set(ctx context.Context, query string, id pkg.SongID) {
+	err := c.storage.SetQuery(ctx, &pkg.SearchQuery{
+		Query:   normalizeQuery(query),
+		ID:      id,
+		Updated: c.now(),
+	})
+	if err != nil {
+		contexts.GetLogger(ctx).Error("save search query", zap.String("query", query), zap.Error(err))
+	}
+}
+
+func (c *queryCache) purge(ctx context.Context, query string) (int, error) {
+	if query != "" {
+		query = normalizeQuery(query)
+	}
+	n, err := c.storage.DeleteQueries(ctx, query)
+	c.count(func(s *CacheStats) { s.Purged += n })
+	return n, err
+}
+
+func (c *queryCache) Stats() CacheStats {
+	c.statsMx.Lock()
+	defer c.statsMx.Unlock()
+	return c.stats
+}
+
+func (c *queryCache) count(f func(s *CacheStats)) {
+	c.statsMx.Lock()
+	f(&c.stats)
+	c.statsMx.Unlock()
+}
+
+func normalizeQuery(query string) string {
+	return strings.ToLower(util.StandardizeSpaces(query))
+}
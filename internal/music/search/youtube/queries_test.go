@@ -0,0 +1,128 @@
+package youtube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type fakeQueries struct {
+	queries map[string]*pkg.SearchQuery
+}
+
+func (f *fakeQueries) GetQuery(_ context.Context, query string) (*pkg.SearchQuery, error) {
+	return f.queries[query], nil
+}
+
+func (f *fakeQueries) SetQuery(_ context.Context, query *pkg.SearchQuery) error {
+	f.queries[query.Query] = query
+	return nil
+}
+
+func (f *fakeQueries) DeleteQueries(_ context.Context, query string) (int, error) {
+	if query == "" {
+		n := len(f.queries)
+		f.queries = make(map[string]*pkg.SearchQuery)
+		return n, nil
+	}
+	if _, ok := f.queries[query]; !ok {
+		return 0, nil
+	}
+	delete(f.queries, query)
+	return 1, nil
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  string
+	}{
+		{query: "Sandstorm", want: "sandstorm"},
+		{query: "  Darude   -\tSandstorm \n", want: "darude - sandstorm"},
+		{query: "ПЕСНЯ", want: "песня"},
+		{query: "", want: ""},
+	}
+	for _, tc := range testCases {
+		if got := normalizeQuery(tc.query); got != tc.want {
+			t.Errorf("normalizeQuery(%q) = %q, wanted %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestQueryCacheGet(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := newQueryCache(&fakeQueries{queries: make(map[string]*pkg.SearchQuery)}, 24*time.Hour)
+	c.now = func() time.Time { return now }
+	id := pkg.SongID{ID: "y6120QOlsfU", Service: pkg.ServiceYouTube}
+	c.set(ctx, "Darude  Sandstorm", id)
+
+	testCases := []struct {
+		name  string
+		query string
+		after time.Duration
+		fresh bool
+		ok    bool
+	}{
+		{name: "hit", query: "Darude  Sandstorm", after: time.Hour, fresh: true, ok: true},
+		{name: "normalized hit", query: " darude sandstorm ", after: time.Hour, fresh: true, ok: true},
+		{name: "expired", query: "darude sandstorm", after: 25 * time.Hour, ok: true},
+		{name: "miss", query: "sandstorm remix", after: time.Hour},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.now = func() time.Time { return now.Add(tc.after) }
+			got, fresh, ok := c.get(ctx, tc.query)
+			if fresh != tc.fresh || ok != tc.ok {
+				t.Fatalf("got fresh=%v ok=%v, wanted fresh=%v ok=%v", fresh, ok, tc.fresh, tc.ok)
+			}
+			if ok && got != id {
+				t.Fatalf("got %v, wanted %v", got, id)
+			}
+		})
+	}
+	want := CacheStats{Hits: 2, Misses: 1, Expired: 1}
+	if got := c.Stats(); got != want {
+		t.Fatalf("got %+v, wanted %+v", got, want)
+	}
+	if rate := c.Stats().HitRate(); rate != 0.5 {
+		t.Fatalf("got hit rate %v, wanted 0.5", rate)
+	}
+}
+
+func TestQueryCachePurge(t *testing.T) {
+	ctx := context.Background()
+	c := newQueryCache(&fakeQueries{queries: make(map[string]*pkg.SearchQuery)}, 0)
+	for _, query := range []string{"sandstorm", "never gonna give you up", "darude"} {
+		c.set(ctx, query, pkg.SongID{ID: query, Service: pkg.ServiceYouTube})
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "normalized query", query: "  SANDSTORM", want: 1},
+		{name: "already purged", query: "sandstorm", want: 0},
+		{name: "all", query: "", want: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := c.purge(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tc.want {
+				t.Fatalf("got %d purged, wanted %d", n, tc.want)
+			}
+		})
+	}
+	if _, _, ok := c.get(ctx, "darude"); ok {
+		t.Fatal("purged query is still cached")
+	}
+	if purged := c.Stats().Purged; purged != 3 {
+		t.Fatalf("got %d purged in stats, wanted 3", purged)
+	}
+}
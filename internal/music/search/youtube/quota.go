@@ -0,0 +1,119 @@
+package youtube
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	defaultQuotaLimit = 10000
+	searchListCost    = 100
+)
+
+// QuotaStats describes YouTube Data API usage since the last daily quota reset
+type QuotaStats struct {
+	Used      int       `json:"used"`
+	Limit     int       `json:"limit"`
+	Searches  int       `json:"searches"`
+	Fallbacks int       `json:"fallbacks"`
+	Exhausted bool      `json:"exhausted"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+type quota struct {
+	sync.Mutex
+	stats QuotaStats
+}
+
+func newQuota(limit int) *quota {
+	if limit <= 0 {
+		limit = defaultQuotaLimit
+	}
+	return &quota{
+		stats: QuotaStats{
+			Limit:   limit,
+			ResetAt: nextQuotaReset(time.Now()),
+		},
+	}
+}
+
+// available reports whether it makes sense to call the API
+func (q *quota) available() bool {
+	q.Lock()
+	defer q.Unlock()
+	q.resetIfExpired()
+	return !q.stats.Exhausted
+}
+
+// spend counts the call, the quota is exhausted once the daily limit is used up
+func (q *quota) spend(units int) {
+	q.Lock()
+	q.resetIfExpired()
+	q.stats.Used += units
+	q.stats.Searches++
+	if q.stats.Used >= q.stats.Limit {
+		q.stats.Exhausted = true
+	}
+	q.Unlock()
+}
+
+func (q *quota) exhaust() {
+	q.Lock()
+	q.stats.Exhausted = true
+	q.Unlock()
+}
+
+func (q *quota) fallback() {
+	q.Lock()
+	q.stats.Fallbacks++
+	q.Unlock()
+}
+
+func (q *quota) Stats() QuotaStats {
+	q.Lock()
+	defer q.Unlock()
+	q.resetIfExpired()
+	return q.stats
+}
+
+func (q *quota) resetIfExpired() {
+	now := time.Now()
+	if now.Before(q.stats.ResetAt) {
+		return
+	}
+	q.stats = QuotaStats{
+		Limit:   q.stats.Limit,
+		ResetAt: nextQuotaReset(now),
+	}
+}
+
+// nextQuotaReset returns the next midnight in Pacific Time when YouTube resets daily quotas
+func nextQuotaReset(now time.Time) time.Time {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		loc = time.FixedZone("PT", -8*60*60)
+	}
+	now = now.In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+}
+
+func isQuotaError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code != http.StatusForbidden && apiErr.Code != http.StatusTooManyRequests {
+		return false
+	}
+	for _, e := range apiErr.Errors {
+		switch e.Reason {
+		case "quotaExceeded", "dailyLimitExceeded", "rateLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
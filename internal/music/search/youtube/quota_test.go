@@ -0,0 +1,52 @@
+package youtube
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsQuotaError(t *testing.T) {
+	apiError := func(code int, reason string) error {
+		return &googleapi.Error{Code: code, Errors: []googleapi.ErrorItem{{Reason: reason}}}
+	}
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "quota exceeded", err: apiError(http.StatusForbidden, "quotaExceeded"), want: true},
+		{name: "daily limit", err: apiError(http.StatusForbidden, "dailyLimitExceeded"), want: true},
+		{name: "rate limit", err: apiError(http.StatusTooManyRequests, "rateLimitExceeded"), want: true},
+		{name: "wrapped", err: errors.Wrap(apiError(http.StatusForbidden, "quotaExceeded"), "youtube search list"), want: true},
+		{name: "forbidden", err: apiError(http.StatusForbidden, "forbidden")},
+		{name: "other status", err: apiError(http.StatusBadRequest, "quotaExceeded")},
+		{name: "not api error", err: errors.New("quotaExceeded")},
+		{name: "no error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isQuotaError(tc.err); got != tc.want {
+				t.Fatalf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQuotaExhaustedByUsage(t *testing.T) {
+	q := newQuota(2 * searchListCost)
+	for i := 0; i < 2; i++ {
+		if !q.available() {
+			t.Fatalf("quota is not available after %d searches", i)
+		}
+		q.spend(searchListCost)
+	}
+	if q.available() {
+		t.Fatal("quota is available after the limit is used up")
+	}
+	if stats := q.Stats(); !stats.Exhausted || stats.Used != stats.Limit {
+		t.Fatalf("got %+v, wanted the exhausted quota", stats)
+	}
+}
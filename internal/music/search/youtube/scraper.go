@@ -0,0 +1,153 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	// Search results page filtered by videos only
+	searchPageURL = "https://www.youtube.com/results?sp=EgIQAQ%253D%253D&search_query="
+	watchPageURL  = "https://www.youtube.com/watch?v="
+)
+
+var (
+	initialDataPattern = regexp.MustCompile(`(?:var ytInitialData|window\["ytInitialData"\])\s*=\s*`)
+)
+
+// scrapeSong searches the song on the YouTube results page without using the Data API quota
+func (y *YouTube) scrapeSong(ctx context.Context, query string) (*pkg.Song, error) {
+	body, err := y.getPage(ctx, searchPageURL+url.QueryEscape(query))
+	if err != nil {
+		return nil, errors.Wrap(err, "search page")
+	}
+
+	var initial interface{}
+	if err := decodeAssigned(body, initialDataPattern, &initial); err != nil {
+		return nil, errors.Wrap(err, "ytInitialData")
+	}
+	renderer := findVideoRenderer(initial)
+	if renderer == nil {
+		return nil, ErrSongNotFound
+	}
+	return songFromRenderer(renderer)
+}
+
+func (y *YouTube) getPage(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	client := y.ytdl.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "get")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, errors.Wrap(err, "read")
+}
+
+// decodeAssigned decodes the JSON object assigned in the page script right after the pattern.
+// The decoder stops at the end of the object, so braces and semicolons inside strings don't cut it.
+func decodeAssigned(body []byte, pattern *regexp.Regexp, v interface{}) error {
+	loc := pattern.FindIndex(body)
+	if loc == nil {
+		return errors.New("not found on the page")
+	}
+	if err := json.NewDecoder(bytes.NewReader(body[loc[1]:])).Decode(v); err != nil {
+		return errors.Wrap(err, "decode")
+	}
+	return nil
+}
+
+// findVideoRenderer returns the first "videoRenderer" object in depth-first order
+func findVideoRenderer(node interface{}) map[string]interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if r, ok := v["videoRenderer"].(map[string]interface{}); ok {
+			if _, ok := r["videoId"].(string); ok {
+				return r
+			}
+		}
+		if contents, ok := v["contents"]; ok {
+			if r := findVideoRenderer(contents); r != nil {
+				return r
+			}
+		}
+		for k, child := range v {
+			if k == "contents" {
+				continue
+			}
+			if r := findVideoRenderer(child); r != nil {
+				return r
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if r := findVideoRenderer(child); r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+func songFromRenderer(r map[string]interface{}) (*pkg.Song, error) {
+	videoID, _ := r["videoId"].(string)
+	if videoID == "" {
+		return nil, ErrSongNotFound
+	}
+	song := songFromID(videoID)
+	song.Title = firstRunText(r["title"])
+	if owner, ok := r["ownerText"].(map[string]interface{}); ok {
+		song.ArtistName = firstRunText(owner)
+		if channelID := browseID(owner); channelID != "" {
+			song.ArtistURL = channelPrefix + channelID
+		}
+	}
+	return song, nil
+}
+
+func firstRunText(node interface{}) string {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	runs, ok := m["runs"].([]interface{})
+	if !ok || len(runs) == 0 {
+		return ""
+	}
+	run, _ := runs[0].(map[string]interface{})
+	text, _ := run["text"].(string)
+	return text
+}
+
+func browseID(owner map[string]interface{}) string {
+	runs, ok := owner["runs"].([]interface{})
+	if !ok || len(runs) == 0 {
+		return ""
+	}
+	run, _ := runs[0].(map[string]interface{})
+	endpoint, _ := run["navigationEndpoint"].(map[string]interface{})
+	browse, _ := endpoint["browseEndpoint"].(map[string]interface{})
+	id, _ := browse["browseId"].(string)
+	return id
+}
@@ -0,0 +1,73 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ytdl "github.com/kkdai/youtube/v2"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func readPage(t *testing.T, name string) []byte {
+	t.Helper()
+	page, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestScrapeSong(t *testing.T) {
+	testCases := []struct {
+		name     string
+		page     []byte
+		want     *pkg.Song
+		notFound bool
+	}{
+		{
+			name: "first video",
+			page: readPage(t, "search.html"),
+			want: &pkg.Song{
+				Title:      "Darude - Sandstorm };",
+				URL:        videoPrefix + "y6120QOlsfU",
+				Service:    pkg.ServiceYouTube,
+				ArtistName: "Darude",
+				ArtistURL:  channelPrefix + "UCLN4fLM5wo1wQpIRx8-QOTA",
+				ID:         pkg.SongID{ID: "y6120QOlsfU", Service: pkg.ServiceYouTube},
+			},
+		},
+		{name: "no videos", page: readPage(t, "search_empty.html"), notFound: true},
+		{name: "no initial data", page: []byte("<html><body>Our systems have detected unusual traffic</body></html>")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(tc.page)), Request: req}, nil
+			})}
+			y := NewYouTubeClient(&ytdl.Client{HTTPClient: client}, nil, nil, nil, Config{})
+			song, err := y.scrapeSong(context.Background(), "sandstorm")
+			switch {
+			case tc.want != nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *song != *tc.want {
+					t.Fatalf("got %+v, wanted %+v", song, tc.want)
+				}
+			case tc.notFound:
+				if !errors.Is(err, ErrSongNotFound) {
+					t.Fatalf("got %v, wanted ErrSongNotFound", err)
+				}
+			case err == nil || errors.Is(err, ErrSongNotFound):
+				t.Fatalf("got %v, wanted the parsing error", err)
+			}
+		})
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"path/filepath"
 	"sort"
+	"time"
 
 	ytdl "github.com/kkdai/youtube/v2"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"google.golang.org/api/youtube/v3"
 
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
 )
 
 const (
@@ -26,22 +29,32 @@ var (
 )
 
 type Config struct {
-	Download  bool   `json:"download"`
-	OutputDir string `json:"output"`
+	Download      bool   `json:"download"`
+	OutputDir     string `json:"output"`
+	QuotaLimit    int    `json:"quota_limit,omitempty"`
+	QueryTTLHours int    `json:"query_ttl_hours,omitempty"`
+	CacheSizeMB   int64  `json:"cache_size_mb,omitempty"` // disk budget for downloaded songs, 0 - no cache
+	Workers       int    `json:"download_workers,omitempty"`
 }
 
 type YouTube struct {
 	ytdl    *ytdl.Client
 	youtube *youtube.Service
 	loader  *Downloader
+	queries *queryCache
+	quota   *quota
+	videos  *videoCache
 	config  Config
 }
 
-func NewYouTubeClient(ytdl *ytdl.Client, yt *youtube.Service, loader *Downloader, config Config) *YouTube {
+func NewYouTubeClient(ytdl *ytdl.Client, yt *youtube.Service, loader *Downloader, queries queryStorage, config Config) *YouTube {
 	return &YouTube{
 		ytdl:    ytdl,
 		youtube: yt,
 		loader:  loader,
+		queries: newQueryCache(queries, time.Duration(config.QueryTTLHours)*time.Hour),
+		quota:   newQuota(config.QuotaLimit),
+		videos:  newVideoCache(),
 		config:  config,
 	}
 }
@@ -89,12 +102,55 @@ func getYTDLImages(ts ytdl.Thumbnails) (string, string) {
 }
 
 func (y *YouTube) findSong(ctx context.Context, query string) (*pkg.Song, error) {
+	if id := pkg.GetIDFromURL(query); id.ID != "" {
+		return songFromID(id.ID), nil
+	}
+
+	cached, fresh, ok := y.queries.get(ctx, query)
+	if ok && fresh {
+		return songFromID(cached.ID), nil
+	}
+
+	logger := contexts.GetLogger(ctx)
+	if y.quota.available() {
+		song, err := y.searchSong(ctx, query)
+		if !isQuotaError(err) {
+			if err == nil {
+				y.queries.set(ctx, query, song.ID)
+			}
+			return song, err
+		}
+		y.quota.exhaust()
+		logger.Warn("youtube api quota exhausted, fallback to scraping", zap.Error(err))
+	}
+
+	// An expired entry is still better than scraping
+	if ok {
+		return songFromID(cached.ID), nil
+	}
+	y.quota.fallback()
+	song, err := y.scrapeSong(ctx, query)
+	if err != nil {
+		if errors.Is(err, ErrSongNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "scrape youtube search")
+	}
+	y.queries.set(ctx, query, song.ID)
+	return song, nil
+}
+
+func (y *YouTube) searchSong(ctx context.Context, query string) (*pkg.Song, error) {
 	call := y.youtube.Search.List([]string{"id, snippet"}).
 		Q(query).
 		MaxResults(maxSearchResult)
 	call.Context(ctx)
+	y.quota.spend(searchListCost)
 	response, err := call.Do()
-	if err != nil || response.Items == nil {
+	if err != nil {
+		return nil, errors.Wrap(err, "youtube search list")
+	}
+	if response.Items == nil {
 		return nil, ErrSongNotFound
 	}
 
@@ -119,8 +175,20 @@ func (y *YouTube) findSong(ctx context.Context, query string) (*pkg.Song, error)
 	return nil, ErrSongNotFound
 }
 
+// LoadSongInfo fills song metadata like duration and the channel without resolving the stream.
+// The loaded video is reused by EnsureStreamInfo.
+func (y *YouTube) LoadSongInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	videoInfo, channelID, err := y.video(ctx, song.ID.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loag video metadata by url %s", song.URL)
+	}
+	song.MergeNoOverride(songFromInfo(videoInfo, channelID))
+	song.Live = isLive(videoInfo)
+	return song, nil
+}
+
 func (y *YouTube) EnsureStreamInfo(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
-	videoInfo, err := y.ytdl.GetVideo(song.URL)
+	videoInfo, channelID, err := y.video(ctx, song.ID.ID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "loag video metadata by url %s", song.URL)
 	}
@@ -150,14 +218,30 @@ func (y *YouTube) EnsureStreamInfo(ctx context.Context, song *pkg.Song) (*pkg.So
 		song.StreamURL = streamURL
 	}
 
-	additionalSongInfo := songFromInfo(videoInfo)
+	additionalSongInfo := songFromInfo(videoInfo, channelID)
 	song.MergeNoOverride(additionalSongInfo)
+	song.Live = isLive(videoInfo)
 	return song, nil
 }
 
-func songFromInfo(v *ytdl.Video) *pkg.Song {
-	art, thumb := getYTDLImages(v.Thumbnails)
+func isLive(v *ytdl.Video) bool {
+	return v.HLSManifestURL != "" && v.Duration == 0
+}
+
+func songFromID(id string) *pkg.Song {
 	return &pkg.Song{
+		URL:     videoPrefix + id,
+		Service: pkg.ServiceYouTube,
+		ID: pkg.SongID{
+			ID:      id,
+			Service: pkg.ServiceYouTube,
+		},
+	}
+}
+
+func songFromInfo(v *ytdl.Video, channelID string) *pkg.Song {
+	art, thumb := getYTDLImages(v.Thumbnails)
+	song := &pkg.Song{
 		Title:        v.Title,
 		URL:          videoPrefix + v.ID,
 		Service:      pkg.ServiceYouTube,
@@ -170,17 +254,38 @@ func songFromInfo(v *ytdl.Video) *pkg.Song {
 		},
 		Duration: v.Duration.Seconds(),
 	}
+	if channelID != "" {
+		song.ArtistURL = channelPrefix + channelID
+	}
+	return song
 }
 
+// FindSong searches the song and loads its metadata.
+// The stream must be resolved afterwards with EnsureStreamInfo.
 func (y *YouTube) FindSong(ctx context.Context, query string) (*pkg.Song, error) {
 	song, err := y.findSong(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	song, err = y.EnsureStreamInfo(ctx, song)
+	song, err = y.LoadSongInfo(ctx, song)
 	if err != nil {
-		return nil, errors.Wrap(err, "ensure stream info")
+		return nil, errors.Wrap(err, "load song info")
 	}
 	return song, nil
 }
+
+// QuotaStats returns YouTube Data API usage for the current day
+func (y *YouTube) QuotaStats() QuotaStats {
+	return y.quota.Stats()
+}
+
+// CacheStats returns statistics of the search query cache since the start
+func (y *YouTube) CacheStats() CacheStats {
+	return y.queries.Stats()
+}
+
+// PurgeQueries deletes the cached search query or all of them if query is empty
+func (y *YouTube) PurgeQueries(ctx context.Context, query string) (int, error) {
+	return y.queries.purge(ctx, query)
+}
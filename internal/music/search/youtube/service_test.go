@@ -0,0 +1,88 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ytdl "github.com/kkdai/youtube/v2"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	testVideoID   = "dQw4w9WgXcQ"
+	testChannelID = "UCuAXFkgsw1L7xaCfnd5JJOw"
+)
+
+// watchPage answers the watch page requests with the saved page of the test video
+type watchPage struct {
+	page     []byte
+	requests []string
+}
+
+func (p *watchPage) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.requests = append(p.requests, req.URL.String())
+	if !strings.HasPrefix(req.URL.String(), watchPageURL+testVideoID) {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(p.page)), Request: req}, nil
+}
+
+func TestFindSongLoadsVideoOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	page := &watchPage{page: readPage(t, "watch.html")}
+	queries := &fakeQueries{queries: map[string]*pkg.SearchQuery{
+		normalizeQuery("never gonna give you up"): {
+			Query:   normalizeQuery("never gonna give you up"),
+			ID:      pkg.SongID{ID: testVideoID, Service: pkg.ServiceYouTube},
+			Updated: time.Now(),
+		},
+	}}
+	loader, _ := newTestDownloader(ctx, t)
+	config := Config{Download: true, OutputDir: loader.OutputDir}
+	y := NewYouTubeClient(&ytdl.Client{HTTPClient: &http.Client{Transport: page}}, nil, loader, queries, config)
+
+	for _, query := range []string{"https://www.youtube.com/watch?v=" + testVideoID, "never gonna give you up"} {
+		t.Run(query, func(t *testing.T) {
+			song, err := y.FindSong(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if song.ID.ID != testVideoID || song.ArtistURL != channelPrefix+testChannelID || song.Duration != 212 {
+				t.Fatalf("got %+v, wanted the test video of the test channel", song)
+			}
+			song, err = y.EnsureStreamInfo(ctx, song)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(loader.OutputDir, testVideoID+videoFormat); song.StreamURL != want {
+				t.Fatalf("got stream %s, wanted %s", song.StreamURL, want)
+			}
+		})
+	}
+	if len(page.requests) != 1 {
+		t.Fatalf("got %v requests, wanted the video loaded once", page.requests)
+	}
+
+	// The stream is re-resolved from the reloaded video
+	y.videos.forget(testVideoID)
+	if _, err := y.EnsureStreamInfo(ctx, songFromID(testVideoID)); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.requests) != 2 {
+		t.Fatalf("got %v requests, wanted the forgotten video reloaded", page.requests)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,118 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const (
+	probeTimeout = 5 * time.Second
+	// The stream has to stay alive for the whole song
+	expirationMargin = 5 * time.Minute
+)
+
+var ErrStreamExpired = errors.New("stream url expired")
+
+// RefreshStream checks that the song stream is still playable and re-resolves it once if it is not.
+// Signed googlevideo links expire in a few hours and downloaded files might be already deleted.
+func (y *YouTube) RefreshStream(ctx context.Context, song *pkg.Song) (*pkg.Song, error) {
+	err := y.checkStream(ctx, song)
+	if err == nil {
+		return song, nil
+	}
+	contexts.GetLogger(ctx).Info("stream is not available, re-resolving",
+		zap.String("id", song.ID.String()),
+		zap.Error(err))
+
+	song.StreamURL = ""
+	y.videos.forget(song.ID.ID)
+	song, err = y.EnsureStreamInfo(ctx, song)
+	if err != nil {
+		return nil, errors.Wrap(err, "re-resolve stream")
+	}
+	if err := y.checkStream(ctx, song); err != nil {
+		return nil, errors.Wrap(err, "check re-resolved stream")
+	}
+	return song, nil
+}
+
+func (y *YouTube) checkStream(ctx context.Context, song *pkg.Song) error {
+	if song.StreamURL == "" {
+		return ErrStreamExpired
+	}
+	if !isRemoteStream(song.StreamURL) {
+		if y.loader != nil {
+			if err := y.loader.Wait(ctx, song.StreamURL); err != nil {
+				return errors.Wrap(err, "wait for download")
+			}
+		}
+		if _, err := os.Stat(song.StreamURL); err != nil {
+			return errors.Wrap(err, "stream file")
+		}
+		return nil
+	}
+
+	if expire, ok := streamExpiration(song.StreamURL); ok {
+		duration := time.Duration(song.Duration) * time.Second
+		if time.Now().Add(duration + expirationMargin).After(expire) {
+			return ErrStreamExpired
+		}
+	}
+	return y.probeStream(ctx, song.StreamURL)
+}
+
+// probeStream requests the first byte of the stream to catch 403 responses before ffmpeg does
+func (y *YouTube) probeStream(ctx context.Context, streamURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "create probe request")
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	client := y.ytdl.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "probe stream")
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return nil
+	case http.StatusForbidden, http.StatusGone, http.StatusNotFound:
+		return errors.Wrapf(ErrStreamExpired, "probe status %d", resp.StatusCode)
+	}
+	return errors.Errorf("probe status %d", resp.StatusCode)
+}
+
+func isRemoteStream(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
+
+// streamExpiration parses the "expire" unix time param of googlevideo links
+func streamExpiration(streamURL string) (time.Time, bool) {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expire, err := strconv.ParseInt(u.Query().Get("expire"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(expire, 0), true
+}
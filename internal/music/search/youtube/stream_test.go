@@ -0,0 +1,51 @@
+package youtube
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	ytdl "github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func TestCheckStream(t *testing.T) {
+	expire := func(d time.Duration) string {
+		return "https://rr1.googlevideo.com/videoplayback?expire=" + strconv.FormatInt(time.Now().Add(d).Unix(), 10)
+	}
+	testCases := []struct {
+		name     string
+		stream   string
+		status   int
+		err      error
+		requests int
+	}{
+		{name: "playable", stream: expire(time.Hour), status: http.StatusPartialContent, requests: 1},
+		{name: "forbidden", stream: expire(time.Hour), status: http.StatusForbidden, err: ErrStreamExpired, requests: 1},
+		{name: "expires during the song", stream: expire(time.Minute), status: http.StatusOK, err: ErrStreamExpired},
+		{name: "no stream", stream: "", err: ErrStreamExpired},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				return &http.Response{StatusCode: tc.status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+			})}
+			y := NewYouTubeClient(&ytdl.Client{HTTPClient: client}, nil, nil, nil, Config{})
+			err := y.checkStream(context.Background(), &pkg.Song{StreamURL: tc.stream, Duration: 200})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got %v, wanted %v", err, tc.err)
+			}
+			if requests != tc.requests {
+				t.Fatalf("got %d requests, wanted %d", requests, tc.requests)
+			}
+		})
+	}
+}
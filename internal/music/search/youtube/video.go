@@ -0,0 +1,144 @@
+package youtube
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	ytdl "github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+// videoTTL is how long the loaded video is reused, finding the song and resolving its stream take a few seconds
+const videoTTL = time.Minute
+
+var playerResponsePattern = regexp.MustCompile(`var ytInitialPlayerResponse\s*=\s*`)
+
+// playerResponse is the part of ytInitialPlayerResponse on the watch page needed to play the video
+type playerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"`
+	} `json:"playabilityStatus"`
+	StreamingData struct {
+		Formats         []ytdl.Format `json:"formats"`
+		AdaptiveFormats []ytdl.Format `json:"adaptiveFormats"`
+		DASHManifestURL string        `json:"dashManifestUrl"`
+		HLSManifestURL  string        `json:"hlsManifestUrl"`
+	} `json:"streamingData"`
+	VideoDetails struct {
+		VideoID       string `json:"videoId"`
+		Title         string `json:"title"`
+		LengthSeconds string `json:"lengthSeconds"`
+		ChannelID     string `json:"channelId"`
+		Author        string `json:"author"`
+		Thumbnail     struct {
+			Thumbnails ytdl.Thumbnails `json:"thumbnails"`
+		} `json:"thumbnail"`
+	} `json:"videoDetails"`
+}
+
+func (r *playerResponse) playable() bool {
+	return r.PlayabilityStatus.Status == "OK" && len(r.StreamingData.Formats)+len(r.StreamingData.AdaptiveFormats) > 0
+}
+
+func (r *playerResponse) video(id string) *ytdl.Video {
+	v := &ytdl.Video{
+		ID:              id,
+		Title:           r.VideoDetails.Title,
+		Author:          r.VideoDetails.Author,
+		Thumbnails:      r.VideoDetails.Thumbnail.Thumbnails,
+		Formats:         append(r.StreamingData.Formats, r.StreamingData.AdaptiveFormats...),
+		DASHManifestURL: r.StreamingData.DASHManifestURL,
+		HLSManifestURL:  r.StreamingData.HLSManifestURL,
+	}
+	if seconds, _ := strconv.Atoi(r.VideoDetails.LengthSeconds); seconds > 0 {
+		v.Duration = time.Duration(seconds) * time.Second
+	}
+	sort.SliceStable(v.Formats, v.SortBitrateDesc)
+	return v
+}
+
+type loadedVideo struct {
+	video     *ytdl.Video
+	channelID string
+	loaded    time.Time
+}
+
+// videoCache keeps recently loaded videos, so the metadata, the channel and the stream of the song are loaded once
+type videoCache struct {
+	mx     sync.Mutex
+	videos map[string]loadedVideo
+}
+
+func newVideoCache() *videoCache {
+	return &videoCache{videos: make(map[string]loadedVideo)}
+}
+
+func (c *videoCache) get(id string) (loadedVideo, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	v, ok := c.videos[id]
+	if !ok || time.Since(v.loaded) > videoTTL {
+		return loadedVideo{}, false
+	}
+	return v, true
+}
+
+func (c *videoCache) set(id string, v loadedVideo) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for k, old := range c.videos {
+		if time.Since(old.loaded) > videoTTL {
+			delete(c.videos, k)
+		}
+	}
+	c.videos[id] = v
+}
+
+func (c *videoCache) forget(id string) {
+	c.mx.Lock()
+	delete(c.videos, id)
+	c.mx.Unlock()
+}
+
+// video returns the recently loaded video with its channel ID or loads it
+func (y *YouTube) video(ctx context.Context, id string) (*ytdl.Video, string, error) {
+	if v, ok := y.videos.get(id); ok {
+		return v.video, v.channelID, nil
+	}
+	video, channelID, err := y.loadVideo(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	y.videos.set(id, loadedVideo{video: video, channelID: channelID, loaded: time.Now()})
+	return video, channelID, nil
+}
+
+// loadVideo loads the video from its watch page, unlike ytdl the page has the channel ID.
+// The video is loaded with ytdl if the page has no playable formats.
+func (y *YouTube) loadVideo(ctx context.Context, id string) (*ytdl.Video, string, error) {
+	var resp playerResponse
+	body, err := y.getPage(ctx, watchPageURL+url.QueryEscape(id))
+	if err == nil {
+		err = decodeAssigned(body, playerResponsePattern, &resp)
+	}
+	if err == nil && resp.playable() {
+		return resp.video(id), resp.VideoDetails.ChannelID, nil
+	}
+	contexts.GetLogger(ctx).Debug("watch page has no playable formats",
+		zap.String("id", id),
+		zap.String("status", resp.PlayabilityStatus.Status),
+		zap.Error(err))
+	video, err := y.ytdl.GetVideoContext(ctx, id)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "load video %s", id)
+	}
+	return video, resp.VideoDetails.ChannelID, nil
+}
@@ -0,0 +1,344 @@
+package stats
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type Window string
+
+const (
+	WindowDay   Window = "day"
+	WindowWeek  Window = "week"
+	WindowMonth Window = "month"
+	WindowAll   Window = "all"
+)
+
+const (
+	// cacheTTL is how long aggregates are served before they are computed again
+	cacheTTL = 10 * time.Minute
+	// allReload is how often the whole time aggregate, which is kept up to date by RecordEvent, is read again
+	allReload    = 24 * time.Hour
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+var ErrUnknownWindow = errors.New("unknown time window")
+
+var windows = map[Window]time.Duration{
+	WindowDay:   24 * time.Hour,
+	WindowWeek:  7 * 24 * time.Hour,
+	WindowMonth: 30 * 24 * time.Hour,
+	WindowAll:   0,
+}
+
+// ParseWindow returns the window by name, empty name is the whole time
+func ParseWindow(name string) (Window, error) {
+	if name == "" {
+		return WindowAll, nil
+	}
+	if _, ok := windows[Window(name)]; !ok {
+		return "", ErrUnknownWindow
+	}
+	return Window(name), nil
+}
+
+type statsStorage interface {
+	GetSong(ctx context.Context, id pkg.SongID) (*pkg.Song, error)
+	SongsIndex() []*pkg.Song
+	GetUsers(ctx context.Context) ([]string, error)
+	GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error)
+	GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error)
+}
+
+// Entry is a line of a leaderboard: a song, an artist or a user with the number of requests
+type Entry struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type UserStats struct {
+	UserID     string  `json:"user_id"`
+	Window     Window  `json:"window"`
+	Requests   int     `json:"requests"`
+	TopSongs   []Entry `json:"top_songs"`
+	TopArtists []Entry `json:"top_artists"`
+}
+
+// Service serves leaderboards of requested songs.
+// Only requests are counted, radio plays are not.
+// Aggregates of every window are cached, so storage is read at most once per cacheTTL.
+// The whole time aggregate is counted from play events after it is loaded and read again once per allReload.
+type Service struct {
+	storage statsStorage
+
+	mx     sync.Mutex
+	cache  map[Window]*aggregate
+	recaps map[string]*Recap // guild/from/to
+}
+
+func NewService(storage statsStorage) *Service {
+	return &Service{
+		storage: storage,
+		cache:   make(map[Window]*aggregate),
+		recaps:  make(map[string]*Recap),
+	}
+}
+
+func (s *Service) TopSongs(ctx context.Context, window Window, n int) ([]Entry, error) {
+	var top []Entry
+	if err := s.view(ctx, window, func(a *aggregate) { top = a.songs.top(n) }); err != nil {
+		return nil, err
+	}
+	s.resolveTitles(ctx, top)
+	return top, nil
+}
+
+func (s *Service) TopArtists(ctx context.Context, window Window, n int) ([]Entry, error) {
+	var top []Entry
+	if err := s.view(ctx, window, func(a *aggregate) { top = a.artists.top(n) }); err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+func (s *Service) TopRequesters(ctx context.Context, window Window, n int) ([]Entry, error) {
+	var top []Entry
+	if err := s.view(ctx, window, func(a *aggregate) { top = a.requesters.top(n) }); err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+// User returns requests of the user with the top songs and artists
+func (s *Service) User(ctx context.Context, userID string, window Window, n int) (*UserStats, error) {
+	res := &UserStats{UserID: userID, Window: window, TopSongs: []Entry{}, TopArtists: []Entry{}}
+	err := s.view(ctx, window, func(a *aggregate) {
+		if u, ok := a.users[userID]; ok {
+			res.Requests = u.requests
+			res.TopSongs = u.songs.top(n)
+			res.TopArtists = u.artists.top(n)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.resolveTitles(ctx, res.TopSongs)
+	return res, nil
+}
+
+// RecordEvent counts the requested song in the whole time aggregate, so it is not read from storage every cacheTTL
+func (s *Service) RecordEvent(e *pkg.PlayEvent) {
+	if e.Type != pkg.EventPlay || e.RequesterID == "" {
+		return
+	}
+	artist := ""
+	if song, err := s.storage.GetSong(context.Background(), e.SongID); err == nil {
+		artist = song.ArtistName
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if a, ok := s.cache[WindowAll]; ok {
+		a.add(e.RequesterID, Entry{ID: e.SongID.String(), Name: e.Title}, artist, 1)
+	}
+}
+
+// view calls f with the aggregate of the window under the lock.
+// Outdated aggregates are computed again without holding the lock.
+func (s *Service) view(ctx context.Context, window Window, f func(a *aggregate)) error {
+	period, ok := windows[window]
+	if !ok {
+		return ErrUnknownWindow
+	}
+	ttl := cacheTTL
+	if period == 0 {
+		ttl = allReload
+	}
+	s.mx.Lock()
+	a, ok := s.cache[window]
+	s.mx.Unlock()
+	if !ok || time.Since(a.computed) >= ttl {
+		var err error
+		if period == 0 {
+			a, err = s.aggregateAll(ctx)
+		} else {
+			a, err = s.aggregateEvents(ctx, time.Now().Add(-period))
+		}
+		if err != nil {
+			return err
+		}
+		s.mx.Lock()
+		s.cache[window] = a
+		s.mx.Unlock()
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	f(a)
+	return nil
+}
+
+// aggregateEvents counts play events since the time, only the events of the window are read
+func (s *Service) aggregateEvents(ctx context.Context, from time.Time) (*aggregate, error) {
+	events, err := s.storage.GetEvents(ctx, &pkg.EventFilter{From: from, Types: []pkg.EventType{pkg.EventPlay}})
+	if err != nil {
+		return nil, errors.Wrap(err, "get play events")
+	}
+	artists := s.artists()
+	a := newAggregate()
+	for _, e := range events {
+		if e.RequesterID == "" {
+			continue
+		}
+		a.add(e.RequesterID, Entry{ID: e.SongID.String(), Name: e.Title}, artists[e.SongID], 1)
+	}
+	return a, nil
+}
+
+// aggregateAll sums request counts of every user
+func (s *Service) aggregateAll(ctx context.Context) (*aggregate, error) {
+	users, err := s.storage.GetUsers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get users")
+	}
+	artists := s.artists()
+	a := newAggregate()
+	for _, user := range users {
+		songs, err := s.storage.GetUserSongs(ctx, user)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get songs of %s", user)
+		}
+		for _, song := range songs {
+			artist := song.ArtistName
+			if artist == "" {
+				artist = artists[song.ID]
+			}
+			a.add(user, Entry{ID: song.ID.String(), Name: song.Title}, artist, song.Playbacks)
+		}
+	}
+	return a, nil
+}
+
+// artists maps songs to their artists using the in-memory index
+func (s *Service) artists() map[pkg.SongID]string {
+	index := s.storage.SongsIndex()
+	res := make(map[pkg.SongID]string, len(index))
+	for _, song := range index {
+		res[song.ID] = song.ArtistName
+	}
+	return res
+}
+
+// resolveTitles fills missing song titles from the in-memory index.
+// Only songs which are not indexed yet are read from the storage.
+func (s *Service) resolveTitles(ctx context.Context, lists ...[]Entry) {
+	missing := make(map[string][]*Entry)
+	for _, entries := range lists {
+		for i := range entries {
+			if entries[i].Name == "" {
+				missing[entries[i].ID] = append(missing[entries[i].ID], &entries[i])
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	for _, song := range s.storage.SongsIndex() {
+		id := song.ID.String()
+		for _, e := range missing[id] {
+			e.Name = song.Title
+		}
+		delete(missing, id)
+	}
+	for id, entries := range missing {
+		song, err := s.storage.GetSong(ctx, pkg.ParseSongID(id))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			e.Name = song.Title
+		}
+	}
+}
+
+type counter map[string]*Entry
+
+func (c counter) add(id, name string, n int) {
+	e, ok := c[id]
+	if !ok {
+		e = &Entry{ID: id}
+		c[id] = e
+	}
+	if e.Name == "" {
+		e.Name = name
+	}
+	e.Count += n
+}
+
+// top returns up to n entries with the highest counts
+func (c counter) top(n int) []Entry {
+	res := make([]Entry, 0, len(c))
+	for _, e := range c {
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].ID < res[j].ID
+	})
+	if n > 0 && len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+type userAggregate struct {
+	requests int
+	songs    counter
+	artists  counter
+}
+
+type aggregate struct {
+	computed   time.Time
+	songs      counter
+	artists    counter
+	requesters counter
+	users      map[string]*userAggregate
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{
+		computed:   time.Now(),
+		songs:      make(counter),
+		artists:    make(counter),
+		requesters: make(counter),
+		users:      make(map[string]*userAggregate),
+	}
+}
+
+func (a *aggregate) add(user string, song Entry, artist string, n int) {
+	if n <= 0 {
+		return
+	}
+	u, ok := a.users[user]
+	if !ok {
+		u = &userAggregate{songs: make(counter), artists: make(counter)}
+		a.users[user] = u
+	}
+	u.requests += n
+	a.requesters.add(user, "", n)
+	a.songs.add(song.ID, song.Name, n)
+	u.songs.add(song.ID, song.Name, n)
+	if artist != "" {
+		a.artists.add(artist, artist, n)
+		u.artists.add(artist, artist, n)
+	}
+}
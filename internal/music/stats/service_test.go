@@ -0,0 +1,226 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+type fakeStorage struct {
+	index     []*pkg.Song
+	userSongs map[string][]*pkg.Song
+	events    []*pkg.PlayEvent
+	reads     int
+}
+
+func (f *fakeStorage) GetSong(ctx context.Context, id pkg.SongID) (*pkg.Song, error) {
+	return &pkg.Song{ID: id, Title: "title " + id.ID}, nil
+}
+
+func (f *fakeStorage) SongsIndex() []*pkg.Song {
+	return f.index
+}
+
+func (f *fakeStorage) GetUsers(ctx context.Context) ([]string, error) {
+	f.reads++
+	res := make([]string, 0, len(f.userSongs))
+	for user := range f.userSongs {
+		res = append(res, user)
+	}
+	return res, nil
+}
+
+func (f *fakeStorage) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	return f.userSongs[user], nil
+}
+
+func (f *fakeStorage) GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	f.reads++
+	res := make([]*pkg.PlayEvent, 0)
+	for _, e := range f.events {
+		if filter.Match(e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func songID(id string) pkg.SongID {
+	return pkg.SongID{ID: id, Service: pkg.ServiceYouTube}
+}
+
+func TestServiceWindows(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	play := func(id, user string, ago time.Duration) *pkg.PlayEvent {
+		return &pkg.PlayEvent{Type: pkg.EventPlay, SongID: songID(id), RequesterID: user, At: now.Add(-ago)}
+	}
+	storage := &fakeStorage{
+		index: []*pkg.Song{
+			{ID: songID("a"), Title: "title a", ArtistName: "Darude"},
+			{ID: songID("b"), Title: "title b", ArtistName: "a-ha"},
+		},
+		userSongs: map[string][]*pkg.Song{
+			"first":  {{ID: songID("a"), Playbacks: 5}, {ID: songID("b"), Playbacks: 1}},
+			"second": {{ID: songID("b"), Playbacks: 2}},
+		},
+		events: []*pkg.PlayEvent{
+			play("b", "second", time.Hour),
+			play("b", "first", 2*time.Hour),
+			play("a", "", 3*time.Hour), // radio
+			play("a", "first", 48*time.Hour),
+		},
+	}
+	s := NewService(storage)
+
+	songs, err := s.TopSongs(ctx, WindowDay, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(songs) != 1 || songs[0].ID != songID("b").String() || songs[0].Count != 2 || songs[0].Name != "title b" {
+		t.Fatalf("got %v, wanted only the requested song of the day", songs)
+	}
+	artists, err := s.TopArtists(ctx, WindowAll, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artists) != 2 || artists[0].Name != "Darude" || artists[0].Count != 5 || artists[1].Count != 3 {
+		t.Fatalf("got %v, wanted artists summed over users", artists)
+	}
+	requesters, err := s.TopRequesters(ctx, WindowWeek, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requesters) != 1 || requesters[0].ID != "first" || requesters[0].Count != 2 {
+		t.Fatalf("got %v, wanted the first user", requesters)
+	}
+	user, err := s.User(ctx, "second", WindowAll, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Requests != 2 || len(user.TopArtists) != 1 || user.TopArtists[0].Name != "a-ha" {
+		t.Fatalf("got %+v, wanted 2 requests of a-ha", user)
+	}
+
+	reads := storage.reads
+	if _, err := s.TopSongs(ctx, WindowDay, 10); err != nil {
+		t.Fatal(err)
+	}
+	if storage.reads != reads {
+		t.Fatalf("storage was read again, wanted the cached aggregate")
+	}
+	if _, err := s.TopSongs(ctx, "year", 10); err != ErrUnknownWindow {
+		t.Fatalf("got %v, wanted ErrUnknownWindow", err)
+	}
+}
+
+func TestServiceRecordEvent(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeStorage{
+		userSongs: map[string][]*pkg.Song{"first": {{ID: songID("a"), Title: "title a", Playbacks: 1}}},
+	}
+	s := NewService(storage)
+
+	// Nothing to keep up to date before the aggregate is loaded
+	s.RecordEvent(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: songID("a"), RequesterID: "first"})
+	if _, err := s.TopSongs(ctx, WindowAll, 10); err != nil {
+		t.Fatal(err)
+	}
+	reads := storage.reads
+
+	s.RecordEvent(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: songID("b"), Title: "title b", RequesterID: "second"})
+	s.RecordEvent(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: songID("a"), RequesterID: "first"})
+	s.RecordEvent(&pkg.PlayEvent{Type: pkg.EventPlay, SongID: songID("a")})                       // radio
+	s.RecordEvent(&pkg.PlayEvent{Type: pkg.EventSkip, SongID: songID("a"), RequesterID: "first"}) // not a request
+
+	songs, err := s.TopSongs(ctx, WindowAll, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(songs) != 2 || songs[0].ID != songID("a").String() || songs[0].Count != 2 || songs[1].Name != "title b" {
+		t.Fatalf("got %v, wanted the recorded requests", songs)
+	}
+	if storage.reads != reads {
+		t.Fatal("storage was read again, wanted the recorded aggregate")
+	}
+	user, err := s.User(ctx, "second", WindowAll, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Requests != 1 {
+		t.Fatalf("got %+v, wanted 1 recorded request", user)
+	}
+}
+
+func TestWrapped(t *testing.T) {
+	ctx := context.Background()
+	period := YearPeriod(2024, time.UTC)
+	at := func(month time.Month) time.Time {
+		return time.Date(2024, month, 1, 12, 0, 0, 0, time.UTC)
+	}
+	event := func(typ pkg.EventType, id, user, guild string, at time.Time, position float64) *pkg.PlayEvent {
+		return &pkg.PlayEvent{Type: typ, SongID: songID(id), Title: id, RequesterID: user, GuildID: guild, At: at, Position: position}
+	}
+	storage := &fakeStorage{
+		index: []*pkg.Song{{ID: songID("a"), ArtistName: "Darude"}},
+		events: []*pkg.PlayEvent{
+			event(pkg.EventPlay, "a", "first", "guild", at(1).AddDate(-1, 0, 0), 0),
+			event(pkg.EventPlay, "a", "second", "guild", at(2), 0),
+			event(pkg.EventComplete, "a", "second", "guild", at(2), 180),
+			event(pkg.EventPlay, "b", "first", "guild", at(3), 0),
+			event(pkg.EventSkip, "b", "first", "guild", at(3), 60),
+			event(pkg.EventPlay, "b", "first", "guild", at(4), 0),
+			event(pkg.EventPlay, "c", "", "guild", at(5), 0), // radio
+			event(pkg.EventComplete, "c", "", "guild", at(5), 120),
+			event(pkg.EventPlay, "d", "first", "other", at(6), 0),
+			event(pkg.EventPlay, "e", "first", "guild", at(1).AddDate(1, 0, 0), 0),
+		},
+	}
+	recap, err := NewService(storage).Wrapped(ctx, "guild", period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guild := recap.Guild
+	if guild.Requests != 3 || guild.Minutes != 6 || guild.NewSongs != 1 {
+		t.Fatalf("got guild %+v, wanted 3 requests, 6 minutes and the only new song b", guild)
+	}
+	if len(guild.TopSongs) != 2 || guild.TopSongs[0].ID != songID("b").String() || guild.TopSongs[0].Count != 2 {
+		t.Fatalf("got top songs %v", guild.TopSongs)
+	}
+	if len(guild.TopArtists) != 1 || guild.TopArtists[0].Name != "Darude" {
+		t.Fatalf("got top artists %v", guild.TopArtists)
+	}
+	second := recap.Users["second"]
+	if second == nil || second.NewSongs != 1 || second.Minutes != 3 {
+		t.Fatalf("got %+v, wanted the song a new for the second user", second)
+	}
+	if len(recap.Listeners) != 2 || recap.Listeners[0].ID != "second" {
+		t.Fatalf("got listeners %v", recap.Listeners)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		err  bool
+	}{
+		{name: "", from: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "2024-12", from: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "2030", err: true},
+		{name: "last", err: true},
+	}
+	for _, tt := range tests {
+		p, err := ParsePeriod(tt.name, now)
+		if (err != nil) != tt.err {
+			t.Fatalf("%q: got error %v", tt.name, err)
+		}
+		if !tt.err && (!p.From.Equal(tt.from) || !p.To.Equal(tt.to)) {
+			t.Fatalf("%q: got %v - %v", tt.name, p.From, p.To)
+		}
+	}
+}
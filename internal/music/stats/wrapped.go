@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const wrappedTop = 5
+
+var ErrUnknownPeriod = errors.New("unknown period, use year or year-month")
+
+// Period is a calendar year or month
+type Period struct {
+	Name string
+	From time.Time // inclusive
+	To   time.Time // exclusive
+}
+
+// ParsePeriod parses "2024" or "2024-06", empty name is the current year
+func ParsePeriod(name string, now time.Time) (Period, error) {
+	if name == "" {
+		name = strconv.Itoa(now.Year())
+	}
+	if t, err := time.ParseInLocation("2006-01", name, now.Location()); err == nil {
+		return MonthPeriod(t), nil
+	}
+	year, err := strconv.Atoi(name)
+	if err != nil || year < 2000 || year > now.Year() {
+		return Period{}, ErrUnknownPeriod
+	}
+	return YearPeriod(year, now.Location()), nil
+}
+
+func YearPeriod(year int, loc *time.Location) Period {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return Period{Name: strconv.Itoa(year), From: from, To: from.AddDate(1, 0, 0)}
+}
+
+func MonthPeriod(t time.Time) Period {
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return Period{Name: from.Format("January 2006"), From: from, To: from.AddDate(0, 1, 0)}
+}
+
+// Summary is the recap of a user or of the whole guild when UserID is empty
+type Summary struct {
+	UserID        string
+	Requests      int
+	Minutes       int     // played minutes of the requested songs, radio included for the guild
+	NewSongs      int     // songs requested for the first time ever
+	FirstRequests []Entry // earliest of the new songs
+	TopSongs      []Entry
+	TopArtists    []Entry
+}
+
+// Recap is the "Wrapped" of the guild for the period
+type Recap struct {
+	Period    Period
+	GuildID   string
+	Guild     *Summary
+	Listeners []Entry // users by minutes
+	Users     map[string]*Summary
+}
+
+// Wrapped builds the recap of the guild from the play history.
+// Events before the period are read to find first-ever requests.
+// Recaps of finished periods never change, so they are cached.
+func (s *Service) Wrapped(ctx context.Context, guildID string, period Period) (*Recap, error) {
+	key := guildID + "/" + period.From.String() + "/" + period.To.String()
+	s.mx.Lock()
+	recap, ok := s.recaps[key]
+	s.mx.Unlock()
+	if ok {
+		return recap, nil
+	}
+
+	events, err := s.storage.GetEvents(ctx, &pkg.EventFilter{To: period.To, GuildID: guildID})
+	if err != nil {
+		return nil, errors.Wrap(err, "get events")
+	}
+	recap = s.buildRecap(guildID, period, events)
+	s.resolveRecapTitles(ctx, recap)
+	if !period.To.After(time.Now()) {
+		s.mx.Lock()
+		s.recaps[key] = recap
+		s.mx.Unlock()
+	}
+	return recap, nil
+}
+
+type summaryBuilder struct {
+	summary *Summary
+	minutes float64
+	songs   counter
+	artists counter
+	first   []Entry
+}
+
+func newSummaryBuilder(userID string) *summaryBuilder {
+	return &summaryBuilder{
+		summary: &Summary{UserID: userID},
+		songs:   make(counter),
+		artists: make(counter),
+	}
+}
+
+func (b *summaryBuilder) build() *Summary {
+	b.summary.Minutes = int(b.minutes / 60)
+	b.summary.TopSongs = b.songs.top(wrappedTop)
+	b.summary.TopArtists = b.artists.top(wrappedTop)
+	b.summary.NewSongs = len(b.first)
+	if len(b.first) > wrappedTop {
+		b.first = b.first[:wrappedTop]
+	}
+	b.summary.FirstRequests = b.first
+	return b.summary
+}
+
+func (s *Service) buildRecap(guildID string, period Period, events []*pkg.PlayEvent) *Recap {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+	artists := s.artists()
+	guild := newSummaryBuilder("")
+	users := make(map[string]*summaryBuilder)
+	user := func(id string) *summaryBuilder {
+		b, ok := users[id]
+		if !ok {
+			b = newSummaryBuilder(id)
+			users[id] = b
+		}
+		return b
+	}
+	requested := make(map[string]struct{}) // songs requested before, by anyone and by the user
+	for _, e := range events {
+		inPeriod := !e.At.Before(period.From)
+		switch e.Type {
+		case pkg.EventPlay:
+			if e.RequesterID == "" {
+				continue
+			}
+			song := Entry{ID: e.SongID.String(), Name: e.Title}
+			firstInGuild := markRequested(requested, song.ID)
+			firstForUser := markRequested(requested, e.RequesterID+"/"+song.ID)
+			if !inPeriod {
+				continue
+			}
+			builders := []*summaryBuilder{guild, user(e.RequesterID)}
+			for _, b := range builders {
+				b.summary.Requests++
+				b.songs.add(song.ID, song.Name, 1)
+				if artist := artists[e.SongID]; artist != "" {
+					b.artists.add(artist, artist, 1)
+				}
+			}
+			if firstInGuild {
+				guild.first = append(guild.first, song)
+			}
+			if firstForUser {
+				user(e.RequesterID).first = append(user(e.RequesterID).first, song)
+			}
+		case pkg.EventSkip, pkg.EventComplete:
+			if !inPeriod {
+				continue
+			}
+			guild.minutes += e.Position
+			if e.RequesterID != "" {
+				user(e.RequesterID).minutes += e.Position
+			}
+		}
+	}
+
+	recap := &Recap{
+		Period:  period,
+		GuildID: guildID,
+		Guild:   guild.build(),
+		Users:   make(map[string]*Summary, len(users)),
+	}
+	listeners := make(counter)
+	for id, b := range users {
+		summary := b.build()
+		recap.Users[id] = summary
+		listeners.add(id, "", summary.Minutes)
+	}
+	recap.Listeners = listeners.top(0)
+	return recap
+}
+
+// markRequested reports whether the key is requested for the first time
+func markRequested(requested map[string]struct{}, key string) bool {
+	if _, ok := requested[key]; ok {
+		return false
+	}
+	requested[key] = struct{}{}
+	return true
+}
+
+func (s *Service) resolveRecapTitles(ctx context.Context, recap *Recap) {
+	lists := [][]Entry{recap.Guild.TopSongs, recap.Guild.FirstRequests}
+	for _, summary := range recap.Users {
+		lists = append(lists, summary.TopSongs, summary.FirstRequests)
+	}
+	s.resolveTitles(ctx, lists...)
+}
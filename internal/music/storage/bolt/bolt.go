@@ -0,0 +1,534 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+var (
+	songsBucket     = []byte("songs")
+	usersBucket     = []byte("users")
+	queriesBucket   = []byte("queries")
+	loginsBucket    = []byte("logins")
+	eventsBucket    = []byte("events")
+	playlistsBucket = []byte("playlists")
+	likesBucket     = []byte("likes")
+	bansBucket      = []byte("bans")
+	banAuditBucket  = []byte("ban_audit")
+)
+
+const openTimeout = 5 * time.Second
+
+// Client is the embedded storage backend in a single file.
+// Documents are stored as JSON under the same keys as in firestore.
+type Client struct {
+	db *bolt.DB
+}
+
+func NewBoltClient(path string) (*Client, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{songsBucket, usersBucket, queriesBucket, loginsBucket, eventsBucket, playlistsBucket, likesBucket, bansBucket, banAuditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return errors.Wrapf(err, "create bucket %s", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Client{db: db}, nil
+}
+
+func (c *Client) Close() error {
+	return c.db.Close()
+}
+
+func (c *Client) GetSongByID(ctx context.Context, id pkg.SongID) (*pkg.Song, error) {
+	var song pkg.Song
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return getSong(tx.Bucket(songsBucket), id.String(), &song)
+	})
+	if err != nil {
+		return nil, err
+	}
+	song.ID = id
+	return &song, nil
+}
+
+func (c *Client) SetSong(ctx context.Context, song *pkg.Song) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(songsBucket), song.ID.String(), storage.Versioned(song))
+	})
+}
+
+func (c *Client) DeleteSong(ctx context.Context, id pkg.SongID) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(songsBucket).Delete([]byte(id.String()))
+	})
+}
+
+func (c *Client) GetUserSong(ctx context.Context, id pkg.SongID, user string) (*pkg.Song, error) {
+	var song pkg.Song
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return getSong(tx.Bucket(usersBucket).Bucket([]byte(user)), id.String(), &song)
+	})
+	if err != nil {
+		return nil, err
+	}
+	song.ID = id
+	return &song, nil
+}
+
+func (c *Client) SetUserSong(ctx context.Context, song *pkg.Song, user string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(usersBucket).CreateBucketIfNotExists([]byte(user))
+		if err != nil {
+			return errors.Wrapf(err, "create user %s bucket", user)
+		}
+		return put(b, song.ID.String(), storage.Versioned(song))
+	})
+}
+
+func (c *Client) DeleteUserSong(ctx context.Context, id pkg.SongID, user string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id.String()))
+	})
+}
+
+func (c *Client) GetUsers(ctx context.Context) ([]string, error) {
+	var res []string
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, _ []byte) error {
+			res = append(res, string(k))
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (c *Client) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	var res []*pkg.Song
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			song, err := storage.UnmarshalSong(v)
+			if err != nil {
+				return errors.Wrapf(err, "unmarshal %s", k)
+			}
+			song.ID = pkg.ParseSongID(string(k))
+			res = append(res, &song)
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (c *Client) GetAllSongs(ctx context.Context) ([]*pkg.Song, error) {
+	var res []*pkg.Song
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(songsBucket)
+		res = make([]*pkg.Song, 0, b.Stats().KeyN)
+		return b.ForEach(func(k, v []byte) error {
+			song, err := storage.UnmarshalSong(v)
+			if err != nil {
+				return errors.Wrapf(err, "unmarshal %s", k)
+			}
+			song.ID = pkg.ParseSongID(string(k))
+			res = append(res, &song)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// MigrateSongs upgrades songs of every bucket to storage.SongSchemaVersion.
+// It is a single transaction, so an interrupted migration leaves nothing half done.
+func (c *Client) MigrateSongs(ctx context.Context, dryRun bool) (*storage.MigrationResult, error) {
+	res := &storage.MigrationResult{}
+	migrate := func(tx *bolt.Tx) error {
+		buckets := []*bolt.Bucket{tx.Bucket(songsBucket)}
+		err := tx.Bucket(usersBucket).ForEach(func(k, _ []byte) error {
+			if b := tx.Bucket(usersBucket).Bucket(k); b != nil {
+				buckets = append(buckets, b)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, b := range buckets {
+			if err := migrateBucket(ctx, b, dryRun, res); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if dryRun {
+		return res, c.db.View(migrate)
+	}
+	return res, c.db.Update(migrate)
+}
+
+func migrateBucket(ctx context.Context, b *bolt.Bucket, dryRun bool, res *storage.MigrationResult) error {
+	logger := contexts.GetLogger(ctx)
+	migrated := make(map[string][]byte)
+	err := b.ForEach(func(k, v []byte) error {
+		res.Checked++
+		var data map[string]interface{}
+		if err := json.Unmarshal(v, &data); err != nil {
+			logger.Error("skip broken song", zap.ByteString("key", k), zap.Error(err))
+			res.Skipped++
+			return nil
+		}
+		changed, err := storage.MigrateSong(data)
+		if err != nil {
+			logger.Error("skip broken song", zap.ByteString("key", k), zap.Error(err))
+			res.Skipped++
+			return nil
+		}
+		if !changed {
+			return nil
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return errors.Wrapf(err, "marshal %s", k)
+		}
+		migrated[string(k)] = raw
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	res.Migrated += len(migrated)
+	if dryRun {
+		return nil
+	}
+	// Keys are not put while ForEach iterates the bucket
+	for k, v := range migrated {
+		if err := b.Put([]byte(k), v); err != nil {
+			return errors.Wrapf(err, "put %s", k)
+		}
+	}
+	return nil
+}
+
+func (c *Client) GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error) {
+	var q pkg.SearchQuery
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(queriesBucket), query, &q)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (c *Client) SetQuery(ctx context.Context, query *pkg.SearchQuery) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(queriesBucket), query.Query, query)
+	})
+}
+
+// DeleteQueries deletes the cached query or all of them if query is empty.
+// Returns the number of deleted entries.
+func (c *Client) DeleteQueries(ctx context.Context, query string) (int, error) {
+	deleted := 0
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if query != "" {
+			b := tx.Bucket(queriesBucket)
+			if b.Get([]byte(query)) == nil {
+				return nil
+			}
+			deleted = 1
+			return b.Delete([]byte(query))
+		}
+		deleted = tx.Bucket(queriesBucket).Stats().KeyN
+		if err := tx.DeleteBucket(queriesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(queriesBucket)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "delete queries")
+	}
+	return deleted, nil
+}
+
+func (c *Client) GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error) {
+	var info pkg.AccountInfo
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(loginsBucket), login, &info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// SetAccount creates or replaces the web user, there is no other way to add accounts to the local database
+func (c *Client) SetAccount(ctx context.Context, login string, info *pkg.AccountInfo) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(loginsBucket), login, info)
+	})
+}
+
+func (c *Client) GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error) {
+	var p pkg.Playlist
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(playlistsBucket), pkg.PlaylistKey(ownerID, name), &p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *Client) SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(playlistsBucket), pkg.PlaylistKey(playlist.OwnerID, playlist.Name), playlist)
+	})
+}
+
+func (c *Client) DeletePlaylist(ctx context.Context, ownerID, name string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(playlistsBucket).Delete([]byte(pkg.PlaylistKey(ownerID, name)))
+	})
+}
+
+func (c *Client) GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error) {
+	res := make([]*pkg.Playlist, 0)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(playlistsBucket).ForEach(func(k, v []byte) error {
+			var p pkg.Playlist
+			if err := json.Unmarshal(v, &p); err != nil {
+				return errors.Wrapf(err, "unmarshal %s", k)
+			}
+			if filter.Match(&p) {
+				res = append(res, &p)
+			}
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (c *Client) SetLike(ctx context.Context, user string, like *pkg.Like) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(likesBucket).CreateBucketIfNotExists([]byte(user))
+		if err != nil {
+			return errors.Wrapf(err, "create user %s likes bucket", user)
+		}
+		return put(b, like.ID.String(), like)
+	})
+}
+
+func (c *Client) DeleteLike(ctx context.Context, user string, id pkg.SongID) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(likesBucket).Bucket([]byte(user))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id.String()))
+	})
+}
+
+func (c *Client) GetLikes(ctx context.Context, user string) ([]*pkg.Like, error) {
+	res := make([]*pkg.Like, 0)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(likesBucket).Bucket([]byte(user))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var like pkg.Like
+			if err := json.Unmarshal(v, &like); err != nil {
+				return errors.Wrapf(err, "unmarshal %s", k)
+			}
+			res = append(res, &like)
+			return nil
+		})
+	})
+	pkg.SortLikes(res)
+	return res, err
+}
+
+func (c *Client) SetBan(ctx context.Context, ban *pkg.Ban) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(bansBucket), pkg.BanKey(ban.Kind, ban.Value), ban)
+	})
+}
+
+func (c *Client) DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Delete([]byte(pkg.BanKey(kind, value)))
+	})
+}
+
+func (c *Client) GetBans(ctx context.Context) ([]*pkg.Ban, error) {
+	res := make([]*pkg.Ban, 0)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).ForEach(func(k, v []byte) error {
+			var ban pkg.Ban
+			if err := json.Unmarshal(v, &ban); err != nil {
+				return errors.Wrapf(err, "unmarshal %s", k)
+			}
+			res = append(res, &ban)
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (c *Client) AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(banAuditBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return errors.Wrap(err, "next audit sequence")
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "marshal audit entry")
+		}
+		return b.Put(eventKey(entry.At, seq), data)
+	})
+}
+
+func (c *Client) GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error) {
+	res := make([]*pkg.BanAudit, 0)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(banAuditBucket).Cursor()
+		for k, v := cur.Last(); k != nil && len(res) < limit; k, v = cur.Prev() {
+			var entry pkg.BanAudit
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return errors.Wrapf(err, "unmarshal audit entry %x", k)
+			}
+			res = append(res, &entry)
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (c *Client) AddEvents(ctx context.Context, events []*pkg.PlayEvent) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket)
+		for _, e := range events {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return errors.Wrap(err, "next event sequence")
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return errors.Wrap(err, "marshal event")
+			}
+			if err := b.Put(eventKey(e.At, seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetEvents walks the events backwards from filter.To, so only the requested range is read
+func (c *Client) GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	res := make([]*pkg.PlayEvent, 0)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(eventsBucket).Cursor()
+		var k, v []byte
+		if filter.To.IsZero() {
+			k, v = cur.Last()
+		} else if k, _ = cur.Seek(eventKey(filter.To, 0)); k == nil {
+			k, v = cur.Last()
+		} else {
+			k, v = cur.Prev()
+		}
+		from := eventKey(filter.From, 0)
+		for ; k != nil && !filter.Full(len(res)); k, v = cur.Prev() {
+			if !filter.From.IsZero() && bytes.Compare(k, from) < 0 {
+				break
+			}
+			var e pkg.PlayEvent
+			if err := json.Unmarshal(v, &e); err != nil {
+				return errors.Wrap(err, "unmarshal event")
+			}
+			if filter.Match(&e) {
+				res = append(res, &e)
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+// eventKey orders events by time, the sequence separates events of the same time
+func eventKey(at time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(at.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func get(b *bolt.Bucket, key string, v interface{}) error {
+	if b == nil {
+		return storage.ErrNotFound
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return storage.ErrNotFound
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "unmarshal %s", key)
+	}
+	return nil
+}
+
+// getSong is get of a song, songs of older schema versions are migrated
+func getSong(b *bolt.Bucket, key string, song *pkg.Song) error {
+	if b == nil {
+		return storage.ErrNotFound
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return storage.ErrNotFound
+	}
+	s, err := storage.UnmarshalSong(data)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshal %s", key)
+	}
+	*song = s
+	return nil
+}
+
+func put(b *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "marshal %s", key)
+	}
+	return b.Put([]byte(key), data)
+}
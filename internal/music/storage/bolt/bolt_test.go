@@ -0,0 +1,200 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := NewBoltClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClientSongs(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	id := pkg.SongID{ID: "dQw4w9WgXcQ", Service: pkg.ServiceYouTube}
+	if _, err := c.GetSongByID(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got %v for missing song, wanted ErrNotFound", err)
+	}
+
+	song := &pkg.Song{ID: id, Title: "title", Playbacks: 3}
+	if err := c.SetSong(ctx, song); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetSongByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != id || got.Title != song.Title || got.Playbacks != song.Playbacks {
+		t.Fatalf("got %+v, wanted %+v", got, song)
+	}
+
+	all, err := c.GetAllSongs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != id || all[0].Playbacks != song.Playbacks {
+		t.Fatalf("got songs %v, wanted [%v]", all, song)
+	}
+
+	if _, err := c.GetUserSong(ctx, id, "user"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got %v for missing user, wanted ErrNotFound", err)
+	}
+	if err := c.SetUserSong(ctx, song, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.GetUserSong(ctx, id, "user"); err != nil || got.Playbacks != song.Playbacks {
+		t.Fatalf("got %+v %v, wanted user song", got, err)
+	}
+	if users, err := c.GetUsers(ctx); err != nil || len(users) != 1 || users[0] != "user" {
+		t.Fatalf("got users %v %v, wanted [user]", users, err)
+	}
+	if songs, err := c.GetUserSongs(ctx, "user"); err != nil || len(songs) != 1 || songs[0].ID != id {
+		t.Fatalf("got user songs %v %v, wanted [%v]", songs, err, id)
+	}
+}
+
+func TestClientDeleteQueries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	for _, q := range []string{"first", "second", "third"} {
+		if err := c.SetQuery(ctx, &pkg.SearchQuery{Query: q}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := c.DeleteQueries(ctx, "first"); err != nil || n != 1 {
+		t.Fatalf("got %d %v, wanted 1 deleted query", n, err)
+	}
+	if n, err := c.DeleteQueries(ctx, "first"); err != nil || n != 0 {
+		t.Fatalf("got %d %v, wanted 0 deleted queries", n, err)
+	}
+	if n, err := c.DeleteQueries(ctx, ""); err != nil || n != 2 {
+		t.Fatalf("got %d %v, wanted 2 deleted queries", n, err)
+	}
+	if _, err := c.GetQuery(ctx, "second"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got %v for deleted query, wanted ErrNotFound", err)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	start := time.Date(2022, 8, 5, 20, 0, 0, 0, time.UTC)
+	events := make([]*pkg.PlayEvent, 0, 6)
+	for i := 0; i < 6; i++ {
+		e := &pkg.PlayEvent{Type: pkg.EventPlay, At: start.Add(time.Duration(i) * time.Hour)}
+		if i%2 == 1 {
+			e.Type = pkg.EventComplete
+		}
+		events = append(events, e)
+	}
+	if err := c.AddEvents(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetEvents(ctx, &pkg.EventFilter{
+		From:  start.Add(time.Hour),
+		To:    start.Add(5 * time.Hour),
+		Types: []pkg.EventType{pkg.EventPlay},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].At.Equal(events[4].At) || !got[1].At.Equal(events[2].At) {
+		t.Fatalf("got %v, wanted events 4 and 2", got)
+	}
+
+	got, err = c.GetEvents(ctx, &pkg.EventFilter{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || !got[0].At.Equal(events[5].At) {
+		t.Fatalf("got %v, wanted 3 newest events", got)
+	}
+}
+
+func TestClientLikes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	first := &pkg.Song{ID: pkg.SongID{ID: "dQw4w9WgXcQ", Service: pkg.ServiceYouTube}, Title: "first"}
+	second := &pkg.Song{ID: pkg.SongID{ID: "y6120QOlsfU", Service: pkg.ServiceYouTube}, Title: "second"}
+	now := time.Now()
+	if err := c.SetLike(ctx, "user", pkg.NewLike(first, now.Add(-time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetLike(ctx, "user", pkg.NewLike(second, now)); err != nil {
+		t.Fatal(err)
+	}
+	likes, err := c.GetLikes(ctx, "user")
+	if err != nil || len(likes) != 2 || likes[0].ID != second.ID || likes[1].ID != first.ID {
+		t.Fatalf("got %v %v, wanted the likes newest first", likes, err)
+	}
+	if err := c.DeleteLike(ctx, "user", second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteLike(ctx, "nobody", second.ID); err != nil {
+		t.Fatalf("got %v unliking for unknown user, wanted nil", err)
+	}
+	if likes, err := c.GetLikes(ctx, "user"); err != nil || len(likes) != 1 || likes[0].ID != first.ID {
+		t.Fatalf("got %v %v, wanted only the first like", likes, err)
+	}
+}
+
+func TestClientMigrateSongs(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	old := []byte(`{"title":"old","playbacks":2,"last_play":{"Time":"2022-05-01T10:00:00Z"}}`)
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(songsBucket).Put([]byte("youtube_old"), old); err != nil {
+			return err
+		}
+		b, err := tx.Bucket(usersBucket).CreateBucket([]byte("user"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("youtube_old"), old)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetSong(ctx, &pkg.Song{ID: pkg.SongID{ID: "new", Service: pkg.ServiceYouTube}, Title: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.MigrateSongs(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *res != (storage.MigrationResult{Checked: 3, Migrated: 2}) {
+		t.Fatalf("got %+v of dry run", res)
+	}
+	if res, err = c.MigrateSongs(ctx, false); err != nil || res.Migrated != 2 {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+	if res, err = c.MigrateSongs(ctx, false); err != nil || res.Migrated != 0 {
+		t.Fatalf("got %+v, %v of the second migration", res, err)
+	}
+
+	song, err := c.GetUserSong(ctx, pkg.SongID{ID: "old", Service: pkg.ServiceYouTube}, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlay := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	if song.SchemaVersion != storage.SongSchemaVersion || song.Playbacks != 2 || !song.LastPlay.Equal(wantPlay) {
+		t.Fatalf("got %+v", song)
+	}
+}
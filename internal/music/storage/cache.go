@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+// DefaultCacheSize is the number of cached songs if the config does not set it
+const DefaultCacheSize = 5000
+
+// SongsCache keeps up to size recently used songs, songs unused for the expiration time are dropped.
+// Downloaded files of the songs are not its business, see files.Manager.
+type SongsCache struct {
+	lru *LRU
+}
+
+func NewSongsCache(ctx context.Context, size int, expirationTime time.Duration) *SongsCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	c := &SongsCache{
+		lru: NewLRU(size, expirationTime),
+	}
+	c.expireProcess(ctx, expirationTime)
+	return c
+}
+
+func (c *SongsCache) Get(k string) (*pkg.Song, bool) {
+	v, ok := c.lru.Get(k)
+	if !ok {
+		return nil, false
+	}
+	song := v.(pkg.Song)
+	return &song, true
+}
+
+func (c *SongsCache) Set(k string, song *pkg.Song) {
+	if song == nil {
+		return
+	}
+	c.lru.Set(k, *song)
+}
+
+func (c *SongsCache) Delete(k string) {
+	c.lru.Delete(k)
+}
+
+func (c *SongsCache) KeyFromID(s pkg.SongID) string {
+	return s.String()
+}
+
+// Stats returns hit, miss and eviction counters since the start
+func (c *SongsCache) Stats() LRUStats {
+	return c.lru.Stats()
+}
+
+// Clear drops all cached songs
+func (c *SongsCache) Clear() {
+	c.lru.Clear()
+}
+
+func (c *SongsCache) expireProcess(ctx context.Context, expirationTime time.Duration) {
+	ticker := time.NewTicker(expirationTime)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed := c.lru.RemoveExpired()
+				stats := c.lru.Stats()
+				contexts.GetLogger(ctx).Info("songs cache",
+					zap.Int("expired", removed),
+					zap.Int("size", stats.Size),
+					zap.Int("hits", stats.Hits),
+					zap.Int("misses", stats.Misses),
+					zap.Int("evictions", stats.Evictions),
+					zap.Float64("hit_rate", stats.HitRate()))
+			}
+		}
+	}()
+}
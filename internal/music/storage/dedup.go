@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+const (
+	// DuplicateID is a song stored under different IDs of the same video
+	DuplicateID = "id"
+	// DuplicateTitle is a song uploaded as different videos with the same title and artist
+	DuplicateTitle = "title"
+)
+
+var (
+	titleBracketsRegexp = regexp.MustCompile(`[(\[][^)\]]*[)\]]`)
+	titleNoiseRegexp    = regexp.MustCompile(`\b(official|music|video|audio|lyrics?|hd|hq|4k|mv|clip)\b`)
+	titleSymbolsRegexp  = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// Duplicates is a group of stored songs which are the same song
+type Duplicates struct {
+	Target pkg.SongID
+	Reason string
+	Songs  []*pkg.Song // the song with the most playbacks goes first
+}
+
+// DedupResult is the outcome of Dedup, nothing is changed on dry run
+type DedupResult struct {
+	Groups    []*Duplicates
+	Removed   int // song entries merged into others
+	UserSongs int // user song entries merged into others
+}
+
+// FindDuplicates groups songs by the video ID parsed from the URL and, if titles is set,
+// by the normalized title and artist. Songs stored under a non-canonical ID form a group on their own.
+func FindDuplicates(songs []*pkg.Song, titles bool) []*Duplicates {
+	parent := make([]int, len(songs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	keys := make(map[string]int)
+	union := func(key string, i int) {
+		if j, ok := keys[key]; ok {
+			parent[find(i)] = find(j)
+			return
+		}
+		keys[key] = i
+	}
+	for i, song := range songs {
+		union("id:"+canonicalID(song).String(), i)
+		if titles {
+			if key := titleKey(song); key != "" {
+				union("title:"+key, i)
+			}
+		}
+	}
+
+	members := make(map[int][]*pkg.Song)
+	for i, song := range songs {
+		root := find(i)
+		members[root] = append(members[root], song)
+	}
+	res := make([]*Duplicates, 0)
+	for _, group := range members {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Playbacks > group[j].Playbacks
+		})
+		target := canonicalID(group[0])
+		if len(group) == 1 && group[0].ID == target {
+			continue
+		}
+		reason := DuplicateID
+		for _, song := range group {
+			if canonicalID(song) != target {
+				reason = DuplicateTitle
+			}
+		}
+		res = append(res, &Duplicates{Target: target, Reason: reason, Songs: group})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Target.String() < res[j].Target.String()
+	})
+	return res
+}
+
+// Merged returns the song which replaces the group: playbacks are summed and the latest LastPlay is kept
+func (d *Duplicates) Merged() *pkg.Song {
+	song := *d.Songs[0]
+	for _, dup := range d.Songs[1:] {
+		song.MergeDuplicate(dup)
+	}
+	song.ID = d.Target
+	return &song
+}
+
+// Dedup finds duplicate songs and merges them together with users' songs
+func Dedup(ctx context.Context, backend Backend, titles, dryRun bool) (*DedupResult, error) {
+	songs, err := backend.GetAllSongs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get all songs")
+	}
+	res := &DedupResult{Groups: FindDuplicates(uniqueSongs(ctx, songs), titles)}
+	if len(res.Groups) == 0 {
+		return res, nil
+	}
+	targets := make(map[pkg.SongID]pkg.SongID) // every song of the groups and the targets themselves
+	for _, group := range res.Groups {
+		targets[group.Target] = group.Target
+		for _, song := range group.Songs {
+			if song.ID != group.Target {
+				targets[song.ID] = group.Target
+				res.Removed++
+			}
+		}
+	}
+
+	logger := contexts.GetLogger(ctx)
+	for _, group := range res.Groups {
+		logger.Info("merge songs",
+			zap.String("id", group.Target.String()),
+			zap.String("reason", group.Reason),
+			zap.Int("songs", len(group.Songs)),
+			zap.Bool("dry_run", dryRun))
+		if dryRun {
+			continue
+		}
+		if err := backend.SetSong(ctx, group.Merged()); err != nil {
+			return res, errors.Wrapf(err, "set song %s", group.Target.String())
+		}
+	}
+	if !dryRun {
+		if err := deleteDuplicates(ctx, backend, res.Groups); err != nil {
+			return res, err
+		}
+	}
+
+	users, err := backend.GetUsers(ctx)
+	if err != nil {
+		return res, errors.Wrap(err, "get users")
+	}
+	for _, user := range users {
+		n, err := mergeUserSongs(ctx, backend, user, targets, dryRun)
+		res.UserSongs += n
+		if err != nil {
+			return res, errors.Wrapf(err, "merge songs of %s", user)
+		}
+	}
+	return res, nil
+}
+
+// mergeUserSongs moves the user's songs to their targets, the number of moved entries is returned
+func mergeUserSongs(ctx context.Context, backend Backend, user string, targets map[pkg.SongID]pkg.SongID, dryRun bool) (int, error) {
+	songs, err := backend.GetUserSongs(ctx, user)
+	if err != nil {
+		return 0, errors.Wrap(err, "get user songs")
+	}
+	groups := make(map[pkg.SongID]*Duplicates)
+	moved := 0
+	for _, song := range songs {
+		target, ok := targets[song.ID]
+		if !ok {
+			continue
+		}
+		if song.ID != target {
+			moved++
+		}
+		group, ok := groups[target]
+		if !ok {
+			group = &Duplicates{Target: target}
+			groups[target] = group
+		}
+		group.Songs = append(group.Songs, song)
+	}
+	if dryRun || moved == 0 {
+		return moved, nil
+	}
+	for _, group := range groups {
+		if err := backend.SetUserSong(ctx, group.Merged(), user); err != nil {
+			return 0, errors.Wrapf(err, "set user song %s", group.Target.String())
+		}
+	}
+	if err := flush(ctx, backend); err != nil {
+		return 0, errors.Wrap(err, "flush merged user songs")
+	}
+	for _, group := range groups {
+		for _, song := range group.Songs {
+			if song.ID == group.Target {
+				continue
+			}
+			if err := backend.DeleteUserSong(ctx, song.ID, user); err != nil {
+				return 0, errors.Wrapf(err, "delete user song %s", song.ID.String())
+			}
+		}
+	}
+	return moved, nil
+}
+
+// deleteDuplicates deletes merged songs except their targets.
+// The duplicates are deleted only when the merged playbacks are stored.
+func deleteDuplicates(ctx context.Context, backend Backend, groups []*Duplicates) error {
+	if err := flush(ctx, backend); err != nil {
+		return errors.Wrap(err, "flush merged songs")
+	}
+	for _, group := range groups {
+		for _, song := range group.Songs {
+			if song.ID == group.Target {
+				continue
+			}
+			if err := backend.DeleteSong(ctx, song.ID); err != nil {
+				return errors.Wrapf(err, "delete song %s", song.ID.String())
+			}
+		}
+	}
+	return nil
+}
+
+// flusher is the backend buffering writes, the firestore client
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// flush writes the buffered songs of the backend, so they are not lost after their duplicates are deleted
+func flush(ctx context.Context, backend Backend) error {
+	if f, ok := backend.(flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// uniqueSongs skips songs which can't be told apart by ID, they would be counted twice otherwise.
+// Legacy firestore documents are such songs, normalize-ids of dbtool merges them.
+func uniqueSongs(ctx context.Context, songs []*pkg.Song) []*pkg.Song {
+	seen := make(map[pkg.SongID]struct{}, len(songs))
+	res := make([]*pkg.Song, 0, len(songs))
+	for _, song := range songs {
+		if _, ok := seen[song.ID]; ok {
+			contexts.GetLogger(ctx).Warn("skip song with the same id", zap.String("id", song.ID.String()))
+			continue
+		}
+		seen[song.ID] = struct{}{}
+		res = append(res, song)
+	}
+	return res
+}
+
+func canonicalID(song *pkg.Song) pkg.SongID {
+	if id := pkg.GetIDFromURL(song.URL); id.ID != "" {
+		return id
+	}
+	return song.ID
+}
+
+// titleKey is the title without the artist name and noise like "(Official Video)", empty if the artist is unknown
+func titleKey(song *pkg.Song) string {
+	artist := normalizeTitle(song.ArtistName)
+	if artist == "" {
+		return ""
+	}
+	title := normalizeTitle(song.Title)
+	title = strings.TrimSpace(strings.TrimPrefix(title, artist))
+	if title == "" {
+		return ""
+	}
+	return artist + "|" + title
+}
+
+func normalizeTitle(s string) string {
+	s = strings.ToLower(s)
+	s = titleBracketsRegexp.ReplaceAllString(s, " ")
+	s = titleNoiseRegexp.ReplaceAllString(s, " ")
+	s = titleSymbolsRegexp.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,188 @@
+package files
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+// partialSuffix marks unfinished downloads, they are resumed by the downloader and are not cached files yet
+const partialSuffix = ".part"
+
+type entry struct {
+	path string
+	size int64
+	pins int
+}
+
+// Cache keeps downloaded files on disk within the byte budget.
+// Least recently used files are evicted first, pinned files are never evicted.
+// The file modification time is used as the last access time, so the order survives restarts.
+type Cache struct {
+	mx      sync.Mutex
+	ctx     context.Context
+	dir     string
+	budget  int64
+	size    int64
+	lru     *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+func NewCache(ctx context.Context, dir string, budget int64) (*Cache, error) {
+	c := &Cache{
+		ctx:     ctx,
+		dir:     dir,
+		budget:  budget,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "create cache dir")
+	}
+	if err := c.index(); err != nil {
+		return nil, errors.Wrap(err, "index cache dir")
+	}
+	c.mx.Lock()
+	c.evict()
+	c.mx.Unlock()
+	contexts.GetLogger(ctx).Info("files cache loaded",
+		zap.Int("files", c.lru.Len()),
+		zap.Int64("size", c.size),
+		zap.Int64("budget", budget))
+	return c, nil
+}
+
+// Add pins the file and marks it as recently used. The file might be not downloaded yet.
+func (c *Cache) Add(path string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	e := c.touch(path)
+	e.pins++
+}
+
+// Loaded accounts the size of the downloaded file and evicts others if it doesn't fit the budget
+func (c *Cache) Loaded(path string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.entries[path]
+	if !ok {
+		return
+	}
+	c.updateSize(el.Value.(*entry))
+	c.evict()
+}
+
+// Remove unpins the file. Unpinned files stay on disk until they are evicted.
+func (c *Cache) Remove(path string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.entries[path]
+	if !ok {
+		return
+	}
+	e := el.Value.(*entry)
+	if e.pins > 0 {
+		e.pins--
+	}
+	c.updateSize(e)
+	c.evict()
+}
+
+// Size returns the total size of the cached files in bytes
+func (c *Cache) Size() int64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.size
+}
+
+func (c *Cache) touch(path string) *entry {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	if el, ok := c.entries[path]; ok {
+		c.lru.MoveToFront(el)
+		e := el.Value.(*entry)
+		c.updateSize(e)
+		return e
+	}
+	e := &entry{path: path}
+	c.entries[path] = c.lru.PushFront(e)
+	c.updateSize(e)
+	return e
+}
+
+func (c *Cache) updateSize(e *entry) {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return
+	}
+	c.size += info.Size() - e.size
+	e.size = info.Size()
+}
+
+func (c *Cache) evict() {
+	logger := contexts.GetLogger(c.ctx)
+	for el := c.lru.Back(); el != nil && c.size > c.budget; {
+		prev := el.Prev()
+		e := el.Value.(*entry)
+		if e.pins == 0 {
+			c.updateSize(e)
+			if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				logger.Error("evict cached file", zap.String("path", e.path), zap.Error(err))
+			} else {
+				logger.Debug("cached file evicted", zap.String("path", e.path), zap.Int64("size", e.size))
+				c.size -= e.size
+				c.lru.Remove(el)
+				delete(c.entries, e.path)
+			}
+		}
+		el = prev
+	}
+}
+
+func (c *Cache) index() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]file, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		if de.IsDir() || strings.HasSuffix(de.Name(), partialSuffix) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{
+			path:    filepath.Join(c.dir, de.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for _, f := range files {
+		c.entries[f.path] = c.lru.PushFront(&entry{path: f.path, size: f.size})
+		c.size += f.size
+	}
+	return nil
+}
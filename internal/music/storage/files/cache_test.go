@@ -0,0 +1,117 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.m4a")
+	mid := filepath.Join(dir, "mid.m4a")
+	now := time.Now()
+	writeFile(t, old, 10, now.Add(-2*time.Hour))
+	writeFile(t, mid, 10, now.Add(-time.Hour))
+
+	c, err := NewCache(context.Background(), dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 20 {
+		t.Fatalf("got size %d after indexing, wanted 20", c.Size())
+	}
+
+	fresh := filepath.Join(dir, "fresh.m4a")
+	c.Add(fresh)
+	writeFile(t, fresh, 10, now)
+	c.Remove(fresh)
+
+	if exists(old) {
+		t.Errorf("least recently used file %s is not evicted", old)
+	}
+	if !exists(mid) || !exists(fresh) {
+		t.Errorf("recently used files are evicted")
+	}
+	if c.Size() != 20 {
+		t.Errorf("got size %d, wanted 20", c.Size())
+	}
+}
+
+func TestCacheKeepsPinnedFiles(t *testing.T) {
+	dir := t.TempDir()
+	pinned := filepath.Join(dir, "pinned.m4a")
+	writeFile(t, pinned, 10, time.Now().Add(-time.Hour))
+
+	c, err := NewCache(context.Background(), dir, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(pinned)
+	// Make the pinned file the least recently used one
+	other := filepath.Join(dir, "other.m4a")
+	c.Add(other)
+	writeFile(t, other, 10, time.Now())
+	c.Remove(other)
+
+	if !exists(pinned) {
+		t.Errorf("pinned file %s is evicted", pinned)
+	}
+	if exists(other) {
+		t.Errorf("unpinned file %s is not evicted", other)
+	}
+
+	c.Remove(pinned)
+	if !exists(pinned) {
+		t.Errorf("file %s within the budget is evicted", pinned)
+	}
+}
+
+func TestCacheEvictsOnLoaded(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.m4a")
+	writeFile(t, old, 10, time.Now().Add(-time.Hour))
+	partial := filepath.Join(dir, "partial.m4a"+partialSuffix)
+	writeFile(t, partial, 10, time.Now())
+
+	c, err := NewCache(context.Background(), dir, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 10 {
+		t.Fatalf("got size %d after indexing, wanted the partial download skipped", c.Size())
+	}
+
+	// The song is still queued, but the budget is exceeded as soon as it is downloaded
+	fresh := filepath.Join(dir, "fresh.m4a")
+	c.Add(fresh)
+	writeFile(t, fresh, 10, time.Now())
+	c.Loaded(fresh)
+
+	if exists(old) {
+		t.Errorf("file %s over the budget is not evicted", old)
+	}
+	if !exists(fresh) || !exists(partial) {
+		t.Errorf("downloaded or partial file is evicted")
+	}
+	if c.Size() != 10 {
+		t.Errorf("got size %d, wanted 10", c.Size())
+	}
+}
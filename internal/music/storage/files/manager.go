@@ -0,0 +1,184 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+var ErrNotPinned = errors.New("file is not pinned")
+
+// ManagerStats is a snapshot of the files tracked by Manager
+type ManagerStats struct {
+	Files    int
+	Pinned   int
+	Unpinned int
+	Bytes    int64
+}
+
+// Manager counts references to the downloaded files.
+// A file is pinned while it is queued or playing, unpinned files are deleted by Sweep.
+type Manager struct {
+	mx    sync.Mutex
+	ctx   context.Context
+	files map[string]int
+}
+
+// NewManager returns the manager which sweeps unpinned files every interval.
+// Files left in dir by the previous run are deleted, empty dir is not scanned.
+// Partial downloads are kept, so the downloader resumes them after the restart.
+// Non-positive interval disables periodic sweeping.
+func NewManager(ctx context.Context, dir string, interval time.Duration) (*Manager, error) {
+	m := &Manager{
+		ctx:   ctx,
+		files: make(map[string]int),
+	}
+	if dir != "" {
+		if err := m.index(dir); err != nil {
+			return nil, errors.Wrap(err, "index files dir")
+		}
+		deleted, err := m.Sweep()
+		if err != nil {
+			return nil, errors.Wrap(err, "sweep files left by the previous run")
+		}
+		contexts.GetLogger(ctx).Info("files manager started", zap.Int("deleted", deleted))
+	}
+	if interval > 0 {
+		go m.sweepEvery(interval)
+	}
+	return m, nil
+}
+
+// Pin increments the file references. The file might be not downloaded yet.
+func (m *Manager) Pin(path string) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.files[path]++
+}
+
+// Unpin decrements the file references. The file stays on disk until the next sweep.
+func (m *Manager) Unpin(path string) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	pins, ok := m.files[path]
+	if !ok || pins <= 0 {
+		return errors.Wrap(ErrNotPinned, path)
+	}
+	m.files[path] = pins - 1
+	return nil
+}
+
+// Add is Pin
+func (m *Manager) Add(path string) {
+	m.Pin(path)
+}
+
+// Remove is Unpin which logs unknown paths
+func (m *Manager) Remove(path string) {
+	if err := m.Unpin(path); err != nil {
+		contexts.GetLogger(m.ctx).Warn("unpin file", zap.Error(err))
+	}
+}
+
+// Stats returns the current state of the tracked files
+func (m *Manager) Stats() ManagerStats {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	stats := ManagerStats{Files: len(m.files)}
+	for path, pins := range m.files {
+		if pins > 0 {
+			stats.Pinned++
+		} else {
+			stats.Unpinned++
+		}
+		if info, err := os.Stat(path); err == nil {
+			stats.Bytes += info.Size()
+		}
+	}
+	return stats
+}
+
+// Sweep deletes unpinned files and returns the number of deleted ones
+func (m *Manager) Sweep() (int, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	var (
+		deleted int
+		errs    []error
+	)
+	for path, pins := range m.files {
+		if pins > 0 {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		delete(m.files, path)
+		deleted++
+	}
+	if len(errs) > 0 {
+		return deleted, errors.Wrapf(errs[0], "%d files were not deleted", len(errs))
+	}
+	return deleted, nil
+}
+
+// Shutdown deletes unpinned files and stops tracking the rest.
+// Pinned files are left on disk because they might be still in use.
+func (m *Manager) Shutdown() error {
+	deleted, err := m.Sweep()
+	m.mx.Lock()
+	left := len(m.files)
+	m.files = make(map[string]int)
+	m.mx.Unlock()
+	contexts.GetLogger(m.ctx).Info("files manager shutdown",
+		zap.Int("deleted", deleted),
+		zap.Int("pinned", left))
+	return err
+}
+
+// index tracks the files in dir as unpinned except partial downloads, the directory is created if it does not exist
+func (m *Manager) index(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	for _, de := range dirEntries {
+		if de.IsDir() || strings.HasSuffix(de.Name(), partialSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, de.Name())
+		if _, ok := m.files[path]; !ok {
+			m.files[path] = 0
+		}
+	}
+	return nil
+}
+
+func (m *Manager) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Sweep(); err != nil {
+				contexts.GetLogger(m.ctx).Error("sweep files", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m, err := NewManager(ctx, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, t.TempDir()
+}
+
+func TestManagerSweepKeepsPinned(t *testing.T) {
+	m, dir := newTestManager(t)
+	pinned := filepath.Join(dir, "pinned.m4a")
+	played := filepath.Join(dir, "played.m4a")
+	writeFile(t, pinned, 10, time.Now())
+	writeFile(t, played, 10, time.Now())
+
+	m.Pin(pinned)
+	m.Pin(played)
+	if err := m.Unpin(played); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := m.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("got %d deleted files, wanted 1", deleted)
+	}
+	if !exists(pinned) {
+		t.Fatal("pinned file was deleted")
+	}
+	if exists(played) {
+		t.Fatal("unpinned file was not deleted")
+	}
+}
+
+func TestManagerCountsReferences(t *testing.T) {
+	m, dir := newTestManager(t)
+	path := filepath.Join(dir, "song.m4a")
+	writeFile(t, path, 10, time.Now())
+
+	m.Pin(path)
+	m.Pin(path)
+	if err := m.Unpin(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(path) {
+		t.Fatal("file with a reference left was deleted")
+	}
+	if err := m.Unpin(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(path) {
+		t.Fatal("file without references was not deleted")
+	}
+}
+
+func TestManagerUnpinUnknown(t *testing.T) {
+	m, dir := newTestManager(t)
+	path := filepath.Join(dir, "song.m4a")
+	if err := m.Unpin(path); !errors.Is(err, ErrNotPinned) {
+		t.Fatalf("got %v for unknown path, wanted ErrNotPinned", err)
+	}
+	m.Pin(path)
+	if err := m.Unpin(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unpin(path); !errors.Is(err, ErrNotPinned) {
+		t.Fatalf("got %v for unpinned path, wanted ErrNotPinned", err)
+	}
+}
+
+func TestManagerStats(t *testing.T) {
+	m, dir := newTestManager(t)
+	pinned := filepath.Join(dir, "pinned.m4a")
+	played := filepath.Join(dir, "played.m4a")
+	loading := filepath.Join(dir, "loading.m4a")
+	writeFile(t, pinned, 10, time.Now())
+	writeFile(t, played, 5, time.Now())
+
+	m.Pin(pinned)
+	m.Pin(played)
+	m.Pin(loading)
+	if err := m.Unpin(played); err != nil {
+		t.Fatal(err)
+	}
+	want := ManagerStats{Files: 3, Pinned: 2, Unpinned: 1, Bytes: 15}
+	if got := m.Stats(); got != want {
+		t.Fatalf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestManagerSweepMissingFile(t *testing.T) {
+	m, dir := newTestManager(t)
+	path := filepath.Join(dir, "failed.m4a")
+	m.Pin(path)
+	if err := m.Unpin(path); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := m.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 || m.Stats().Files != 0 {
+		t.Fatal("missing file is still tracked")
+	}
+}
+
+func TestManagerShutdown(t *testing.T) {
+	m, dir := newTestManager(t)
+	pinned := filepath.Join(dir, "pinned.m4a")
+	played := filepath.Join(dir, "played.m4a")
+	writeFile(t, pinned, 10, time.Now())
+	writeFile(t, played, 10, time.Now())
+
+	m.Pin(pinned)
+	m.Pin(played)
+	m.Remove(played)
+	if err := m.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(pinned) {
+		t.Fatal("pinned file was deleted on shutdown")
+	}
+	if exists(played) {
+		t.Fatal("unpinned file was not deleted on shutdown")
+	}
+	if m.Stats().Files != 0 {
+		t.Fatal("files are still tracked after shutdown")
+	}
+}
+
+func TestManagerPeriodicSweep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, err := NewManager(ctx, "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "song.m4a")
+	writeFile(t, path, 10, time.Now())
+	m.Pin(path)
+	m.Remove(path)
+
+	deadline := time.Now().Add(time.Second)
+	for exists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("unpinned file was not swept")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerSweepsPreviousRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	left := filepath.Join(dir, "left.m4a")
+	writeFile(t, left, 10, time.Now())
+	partial := filepath.Join(dir, "partial.m4a"+partialSuffix)
+	writeFile(t, partial, 10, time.Now())
+
+	m, err := NewManager(ctx, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists(left) {
+		t.Fatal("file of the previous run was not deleted")
+	}
+	if m.Stats().Files != 0 {
+		t.Fatal("deleted file is still tracked")
+	}
+	if !exists(partial) {
+		t.Fatal("partial download was deleted, it can't be resumed")
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewManager(ctx, missing, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(missing) {
+		t.Fatal("files dir was not created")
+	}
+}
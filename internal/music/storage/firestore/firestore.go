@@ -2,6 +2,9 @@ package firestore
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,29 +13,44 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
 	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
 	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
-	"google.golang.org/grpc/status"
 )
 
 const (
-	songsCollection = "songs"
-	usersCollection = "users"
+	songsCollection      = "songs"
+	usersCollection      = "users"
+	queriesCollection    = "queries"
+	loginsCollection     = "logins"
+	eventsCollection     = "events"
+	playlistsCollection  = "playlists"
+	likesCollection      = "likes"
+	bansCollection       = "bans"
+	banAuditCollection   = "ban_audit"
+	migrationsCollection = "migrations"
 	// Maximum batch size by firestore docs
 	batchSize              = 500
 	approximateSongsNumber = 1000
+	writeAttempts          = 5
 )
 
+// retryBackoff is the delay before the second write attempt, it doubles with every next one
+var retryBackoff = 500 * time.Millisecond
+
 type Client struct {
 	*firestore.Client
 	updateMx  sync.Mutex
+	flushMx   sync.Mutex // only one flush of the buffers at a time
 	songs     map[string]*pkg.Song
 	userSongs map[string]map[string]*pkg.Song
+	events    []*pkg.PlayEvent
 	debug     bool
 }
 
-var ErrNotFound = errors.New("no docs found")
+var ErrNotFound = storage.ErrNotFound
 
 func NewFirestoreClient(ctx context.Context, client *firestore.Client, debug bool) (*Client, error) {
 	c := &Client{
@@ -76,16 +94,37 @@ func (c *Client) SetSongForced(ctx context.Context, song *pkg.Song) error {
 		return nil
 	}
 	contexts.GetLogger(ctx).Info("set song forced", zap.String("id", song.ID.String()))
-	_, err := c.Collection(songsCollection).Doc(song.ID.String()).Set(ctx, song)
+	_, err := c.Collection(songsCollection).Doc(song.ID.String()).Set(ctx, storage.Versioned(song))
 	if err != nil {
 		return errors.Wrapf(err, "failed to set %s from %s", song.ID.String(), songsCollection)
 	}
 	return nil
 }
 
+// DeleteSong drops the buffered write of the song and deletes its document right away
+func (c *Client) DeleteSong(ctx context.Context, id pkg.SongID) error {
+	if c.debug {
+		return nil
+	}
+	c.updateMx.Lock()
+	delete(c.songs, id.String())
+	c.updateMx.Unlock()
+	return retry(ctx, func() error {
+		_, err := c.Collection(songsCollection).Doc(id.String()).Delete(ctx)
+		return errors.Wrapf(err, "delete %s from %s", id.String(), songsCollection)
+	})
+}
+
 func (c *Client) GetUserSong(ctx context.Context, id pkg.SongID, user string) (*pkg.Song, error) {
 	contexts.GetLogger(ctx).Info("get user song", zap.String("id", id.String()), zap.String("user", user))
-	doc, err := c.Collection(usersCollection).Doc(user).Collection(songsCollection).Doc(id.String()).Get(ctx)
+	c.updateMx.Lock()
+	buffered, ok := c.userSongs[user][id.String()]
+	c.updateMx.Unlock()
+	if ok {
+		song := *buffered
+		return &song, nil
+	}
+	doc, err := c.userSongRef(user, id).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return nil, ErrNotFound
@@ -112,13 +151,79 @@ func (c *Client) SetUserSong(ctx context.Context, song *pkg.Song, user string) e
 	return nil
 }
 
-func (c *Client) GetAllSongsID(ctx context.Context) ([]pkg.SongID, error) {
+func (c *Client) DeleteUserSong(ctx context.Context, id pkg.SongID, user string) error {
+	if c.debug {
+		return nil
+	}
+	c.updateMx.Lock()
+	delete(c.userSongs[user], id.String())
+	c.updateMx.Unlock()
+	return retry(ctx, func() error {
+		_, err := c.userSongRef(user, id).Delete(ctx)
+		return errors.Wrapf(err, "delete %s of %s", id.String(), user)
+	})
+}
+
+func (c *Client) GetUsers(ctx context.Context) ([]string, error) {
+	if c.debug {
+		return nil, nil
+	}
+	// user documents may be missing, only their songs subcollections exist
+	refs, err := c.Collection(usersCollection).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "get user refs")
+	}
+	res := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		res = append(res, ref.ID)
+	}
+	return res, nil
+}
+
+// GetUserSongs returns stored user songs with the buffered changes applied
+func (c *Client) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	if c.debug {
+		return nil, nil
+	}
+	contexts.GetLogger(ctx).Debug("get user songs", zap.String("user", user))
+	iter := c.Collection(usersCollection).Doc(user).Collection(songsCollection).Documents(ctx)
+	songs := make(map[string]*pkg.Song)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "iterate songs of %s", user)
+		}
+		s, err := parseSongDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		s.ID = pkg.ParseSongID(doc.Ref.ID)
+		songs[doc.Ref.ID] = &s
+	}
+	c.updateMx.Lock()
+	for k, song := range c.userSongs[user] {
+		copied := *song
+		songs[k] = &copied
+	}
+	c.updateMx.Unlock()
+
+	res := make([]*pkg.Song, 0, len(songs))
+	for _, song := range songs {
+		res = append(res, song)
+	}
+	return res, nil
+}
+
+func (c *Client) GetAllSongs(ctx context.Context) ([]*pkg.Song, error) {
 	if c.debug {
 		return nil, nil
 	}
 	contexts.GetLogger(ctx).Info("get all songs")
 	iter := c.Collection(songsCollection).Documents(ctx)
-	res := make([]pkg.SongID, 0, approximateSongsNumber)
+	res := make([]*pkg.Song, 0, approximateSongsNumber)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -131,14 +236,322 @@ func (c *Client) GetAllSongsID(ctx context.Context) ([]pkg.SongID, error) {
 		if err != nil {
 			return nil, err
 		}
-		if s.ID.ID == "" {
-			s.ID = pkg.GetIDFromURL(s.URL)
+		// documents written by SetSong are keyed by the ID, legacy ones are identified by the URL
+		s.ID = pkg.GetIDFromURL(s.URL)
+		if strings.Contains(doc.Ref.ID, "_") {
+			s.ID = pkg.ParseSongID(doc.Ref.ID)
+		}
+		res = append(res, &s)
+	}
+
+	// buffered songs are newer than the stored ones
+	c.updateMx.Lock()
+	buffered := make(map[string]*pkg.Song, len(c.songs))
+	for k, v := range c.songs {
+		song := *v
+		buffered[k] = &song
+	}
+	c.updateMx.Unlock()
+	for i := range res {
+		if song, ok := buffered[res[i].ID.String()]; ok {
+			res[i] = song
+			delete(buffered, res[i].ID.String())
+		}
+	}
+	for _, song := range buffered {
+		res = append(res, song)
+	}
+	return res, nil
+}
+
+type queryDoc struct {
+	Query   string          `firestore:"query"`
+	ID      string          `firestore:"id"`
+	Service pkg.ServiceName `firestore:"service"`
+	Updated time.Time       `firestore:"updated"`
+}
+
+func (c *Client) GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error) {
+	doc, err := c.Collection(queriesCollection).Doc(queryDocID(query)).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get query %q from %s", query, queriesCollection)
+	}
+	var q queryDoc
+	if err := doc.DataTo(&q); err != nil {
+		return nil, errors.Wrap(err, "unable to marshal query data")
+	}
+	return &pkg.SearchQuery{
+		Query:   q.Query,
+		ID:      pkg.SongID{ID: q.ID, Service: q.Service},
+		Updated: q.Updated,
+	}, nil
+}
+
+func (c *Client) SetQuery(ctx context.Context, query *pkg.SearchQuery) error {
+	if c.debug {
+		return nil
+	}
+	_, err := c.Collection(queriesCollection).Doc(queryDocID(query.Query)).Set(ctx, &queryDoc{
+		Query:   query.Query,
+		ID:      query.ID.ID,
+		Service: query.ID.Service,
+		Updated: query.Updated,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set query %q to %s", query.Query, queriesCollection)
+	}
+	return nil
+}
+
+// DeleteQueries deletes the cached query or all of them if query is empty.
+// Returns the number of deleted entries.
+func (c *Client) DeleteQueries(ctx context.Context, query string) (int, error) {
+	if query != "" {
+		ref := c.Collection(queriesCollection).Doc(queryDocID(query))
+		if _, err := ref.Get(ctx); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return 0, nil
+			}
+			return 0, errors.Wrapf(err, "failed to get query %q from %s", query, queriesCollection)
+		}
+		if _, err := ref.Delete(ctx); err != nil {
+			return 0, errors.Wrapf(err, "failed to delete query %q", query)
+		}
+		return 1, nil
+	}
+
+	refs, err := c.Collection(queriesCollection).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list %s", queriesCollection)
+	}
+	for i := 0; i < len(refs); i += batchSize {
+		k := i + batchSize
+		if k > len(refs) {
+			k = len(refs)
+		}
+		batch := c.Batch()
+		for _, ref := range refs[i:k] {
+			batch.Delete(ref)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return i, errors.Wrapf(err, "faild to delete queries batch from %d to %d", i, k)
+		}
+	}
+	return len(refs), nil
+}
+
+func (c *Client) GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error) {
+	doc, err := c.Collection(loginsCollection).Doc(login).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get %s from %s", login, loginsCollection)
+	}
+	var info pkg.AccountInfo
+	if err := doc.DataTo(&info); err != nil {
+		return nil, errors.Wrap(err, "unable to marshal account data")
+	}
+	return &info, nil
+}
+
+func (c *Client) GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error) {
+	key := pkg.PlaylistKey(ownerID, name)
+	doc, err := c.Collection(playlistsCollection).Doc(key).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get %s from %s", key, playlistsCollection)
+	}
+	var p pkg.Playlist
+	if err := doc.DataTo(&p); err != nil {
+		return nil, errors.Wrap(err, "unable to marshal playlist data")
+	}
+	return &p, nil
+}
+
+func (c *Client) SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error {
+	if c.debug {
+		return nil
+	}
+	key := pkg.PlaylistKey(playlist.OwnerID, playlist.Name)
+	return retry(ctx, func() error {
+		_, err := c.Collection(playlistsCollection).Doc(key).Set(ctx, playlist)
+		return errors.Wrapf(err, "set playlist %s", key)
+	})
+}
+
+func (c *Client) DeletePlaylist(ctx context.Context, ownerID, name string) error {
+	if c.debug {
+		return nil
+	}
+	key := pkg.PlaylistKey(ownerID, name)
+	return retry(ctx, func() error {
+		_, err := c.Collection(playlistsCollection).Doc(key).Delete(ctx)
+		return errors.Wrapf(err, "delete playlist %s", key)
+	})
+}
+
+func (c *Client) GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error) {
+	query := c.Collection(playlistsCollection).Query
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id", "==", filter.OwnerID)
+	}
+	if filter.GuildID != "" {
+		query = query.Where("guild_id", "==", filter.GuildID)
+	}
+	if filter.Visibility != "" {
+		query = query.Where("visibility", "==", string(filter.Visibility))
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+	res := make([]*pkg.Playlist, 0)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "iterate playlists")
+		}
+		var p pkg.Playlist
+		if err := doc.DataTo(&p); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal playlist data")
+		}
+		res = append(res, &p)
+	}
+	return res, nil
+}
+
+func (c *Client) SetLike(ctx context.Context, user string, like *pkg.Like) error {
+	if c.debug {
+		return nil
+	}
+	return retry(ctx, func() error {
+		_, err := c.likeRef(user, like.ID).Set(ctx, like)
+		return errors.Wrapf(err, "set like %s of %s", like.ID.String(), user)
+	})
+}
+
+func (c *Client) DeleteLike(ctx context.Context, user string, id pkg.SongID) error {
+	if c.debug {
+		return nil
+	}
+	return retry(ctx, func() error {
+		_, err := c.likeRef(user, id).Delete(ctx)
+		return errors.Wrapf(err, "delete like %s of %s", id.String(), user)
+	})
+}
+
+func (c *Client) GetLikes(ctx context.Context, user string) ([]*pkg.Like, error) {
+	iter := c.Collection(usersCollection).Doc(user).Collection(likesCollection).OrderBy("at", firestore.Desc).Documents(ctx)
+	defer iter.Stop()
+	res := make([]*pkg.Like, 0)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "iterate likes of %s", user)
+		}
+		var like pkg.Like
+		if err := doc.DataTo(&like); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal like data")
+		}
+		res = append(res, &like)
+	}
+	return res, nil
+}
+
+func (c *Client) likeRef(user string, id pkg.SongID) *firestore.DocumentRef {
+	return c.Collection(usersCollection).Doc(user).Collection(likesCollection).Doc(id.String())
+}
+
+func (c *Client) SetBan(ctx context.Context, ban *pkg.Ban) error {
+	if c.debug {
+		return nil
+	}
+	key := pkg.BanKey(ban.Kind, ban.Value)
+	return retry(ctx, func() error {
+		_, err := c.Collection(bansCollection).Doc(queryDocID(key)).Set(ctx, ban)
+		return errors.Wrapf(err, "set ban %s", key)
+	})
+}
+
+func (c *Client) DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error {
+	if c.debug {
+		return nil
+	}
+	key := pkg.BanKey(kind, value)
+	return retry(ctx, func() error {
+		_, err := c.Collection(bansCollection).Doc(queryDocID(key)).Delete(ctx)
+		return errors.Wrapf(err, "delete ban %s", key)
+	})
+}
+
+func (c *Client) GetBans(ctx context.Context) ([]*pkg.Ban, error) {
+	iter := c.Collection(bansCollection).Documents(ctx)
+	defer iter.Stop()
+	res := make([]*pkg.Ban, 0)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "iterate bans")
+		}
+		var ban pkg.Ban
+		if err := doc.DataTo(&ban); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal ban data")
+		}
+		res = append(res, &ban)
+	}
+	return res, nil
+}
+
+func (c *Client) AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error {
+	if c.debug {
+		return nil
+	}
+	return retry(ctx, func() error {
+		_, err := c.Collection(banAuditCollection).NewDoc().Create(ctx, entry)
+		return errors.Wrap(err, "add ban audit entry")
+	})
+}
+
+func (c *Client) GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error) {
+	iter := c.Collection(banAuditCollection).OrderBy("at", firestore.Desc).Limit(limit).Documents(ctx)
+	defer iter.Stop()
+	res := make([]*pkg.BanAudit, 0)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "iterate ban audit")
+		}
+		var entry pkg.BanAudit
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal ban audit data")
 		}
-		res = append(res, s.ID)
+		res = append(res, &entry)
 	}
 	return res, nil
 }
 
+// queryDocID returns a document ID safe for any query text
+func queryDocID(query string) string {
+	sum := sha1.Sum([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
 // UpsertSongIncPlaybacks We don't use it because our cash of songs is always consistent
 // As we have only one writer to the song db - this bot
 func (c *Client) UpsertSongIncPlaybacks(ctx context.Context, new *pkg.Song) (int, error) {
@@ -150,14 +563,14 @@ func (c *Client) UpsertSongIncPlaybacks(ctx context.Context, new *pkg.Song) (int
 		if err != nil {
 			return err
 		}
-		var old pkg.Song
-		if err := doc.DataTo(&old); err != nil {
-			return errors.Wrap(err, "parsing data to Song failed")
+		old, err := parseSongDoc(doc)
+		if err != nil {
+			return err
 		}
 		playbacks = old.Playbacks + 1
 		new.MergeNoOverride(&old)
 		new.Playbacks = playbacks
-		return tx.Set(ref, new)
+		return tx.Set(ref, storage.Versioned(new))
 	})
 	if err != nil {
 		return 0, errors.Wrap(err, "transaction failed")
@@ -172,22 +585,12 @@ func (c *Client) updateSongs(ctx context.Context) {
 		for {
 			select {
 			case <-ticker.C:
-				c.updateMx.Lock()
-				if len(c.songs) == 0 {
-					c.updateMx.Unlock()
-					continue
-				}
-				toSend := make([]*pkg.Song, 0, len(c.songs))
-				for k, v := range c.songs {
-					toSend = append(toSend, v)
-					delete(c.songs, k)
-				}
-				c.updateMx.Unlock()
-				contexts.GetLogger(ctx).Info("updating songs", zap.Int("number", len(toSend)))
-				err := c.WriteBatch(ctx, toSend)
-				if err != nil {
+				if err := c.flushSongs(ctx); err != nil {
 					contexts.GetLogger(ctx).Error("unable to update songs", zap.Error(err))
 				}
+				if err := c.flushEvents(ctx); err != nil {
+					contexts.GetLogger(ctx).Error("unable to add events", zap.Error(err))
+				}
 			case <-ctx.Done():
 				return
 			}
@@ -202,33 +605,9 @@ func (c *Client) updateUserSongs(ctx context.Context) {
 		for {
 			select {
 			case <-ticker.C:
-				c.updateMx.Lock()
-				toSend := make(map[string][]*pkg.Song)
-				for user, songs := range c.userSongs {
-					toSend[user] = make([]*pkg.Song, 0, len(songs))
-					for k, v := range songs {
-						toSend[user] = append(toSend[user], v)
-						delete(c.songs, k)
-					}
-					delete(c.userSongs, user)
+				if err := c.flushUserSongs(ctx); err != nil {
+					contexts.GetLogger(ctx).Error("unable to update user songs", zap.Error(err))
 				}
-				c.updateMx.Unlock()
-				go func() {
-					for user, songs := range toSend {
-						contexts.GetLogger(ctx).Info("update user songs",
-							zap.String("user", user),
-							zap.Int("songs", len(songs)))
-						for i := range songs {
-							_, err := c.Collection(usersCollection).Doc(user).Collection(songsCollection).Doc(songs[i].ID.String()).Set(ctx, songs[i])
-							if err != nil {
-								contexts.GetLogger(ctx).Error("update user song",
-									zap.String("user", user),
-									zap.String("song", songs[i].ID.String()),
-									zap.Error(err))
-							}
-						}
-					}
-				}()
 			case <-ctx.Done():
 				return
 			}
@@ -236,6 +615,208 @@ func (c *Client) updateUserSongs(ctx context.Context) {
 	}()
 }
 
+// Flush writes all buffered songs and user songs. Writes are retried until ctx is done.
+func (c *Client) Flush(ctx context.Context) error {
+	songsErr := c.flushSongs(ctx)
+	usersErr := c.flushUserSongs(ctx)
+	eventsErr := c.flushEvents(ctx)
+	if songsErr != nil {
+		return songsErr
+	}
+	if usersErr != nil {
+		return usersErr
+	}
+	return eventsErr
+}
+
+// Close flushes the buffers within ctx deadline and closes the firestore client
+func (c *Client) Close(ctx context.Context) error {
+	err := c.Flush(ctx)
+	c.updateMx.Lock()
+	lost := len(c.songs) + len(c.events)
+	for _, songs := range c.userSongs {
+		lost += len(songs)
+	}
+	c.updateMx.Unlock()
+	if lost > 0 {
+		contexts.GetLogger(ctx).Error("songs are lost on close", zap.Int("number", lost), zap.Error(err))
+	}
+	if closeErr := c.Client.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *Client) flushSongs(ctx context.Context) error {
+	c.flushMx.Lock()
+	defer c.flushMx.Unlock()
+	c.updateMx.Lock()
+	if len(c.songs) == 0 {
+		c.updateMx.Unlock()
+		return nil
+	}
+	toSend := make([]*pkg.Song, 0, len(c.songs))
+	for k, v := range c.songs {
+		toSend = append(toSend, v)
+		delete(c.songs, k)
+	}
+	c.updateMx.Unlock()
+	contexts.GetLogger(ctx).Info("updating songs", zap.Int("number", len(toSend)))
+	if err := c.WriteBatch(ctx, toSend); err != nil {
+		c.requeueSongs(toSend)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) flushUserSongs(ctx context.Context) error {
+	c.flushMx.Lock()
+	defer c.flushMx.Unlock()
+	c.updateMx.Lock()
+	if len(c.userSongs) == 0 {
+		c.updateMx.Unlock()
+		return nil
+	}
+	toSend := make([]userSong, 0, len(c.userSongs))
+	for user, songs := range c.userSongs {
+		for _, v := range songs {
+			toSend = append(toSend, userSong{user: user, song: v})
+		}
+		delete(c.userSongs, user)
+	}
+	c.updateMx.Unlock()
+
+	contexts.GetLogger(ctx).Info("updating user songs", zap.Int("number", len(toSend)))
+	var lastErr error
+	for i := 0; i < len(toSend); i += batchSize {
+		k := i + batchSize
+		if k > len(toSend) {
+			k = len(toSend)
+		}
+		err := retry(ctx, func() error {
+			batch := c.Batch()
+			for _, us := range toSend[i:k] {
+				batch.Set(c.userSongRef(us.user, us.song.ID), storage.Versioned(us.song))
+			}
+			_, err := batch.Commit(ctx)
+			return err
+		})
+		if err != nil {
+			for _, us := range toSend[i:k] {
+				c.requeueUserSong(us.song, us.user)
+			}
+			lastErr = errors.Wrapf(err, "faild to send user songs batch from %d to %d", i, k)
+		}
+	}
+	return lastErr
+}
+
+type userSong struct {
+	user string
+	song *pkg.Song
+}
+
+func (c *Client) userSongRef(user string, id pkg.SongID) *firestore.DocumentRef {
+	return c.Collection(usersCollection).Doc(user).Collection(songsCollection).Doc(id.String())
+}
+
+func (c *Client) AddEvents(ctx context.Context, events []*pkg.PlayEvent) error {
+	if c.debug {
+		return nil
+	}
+	c.updateMx.Lock()
+	c.events = append(c.events, events...)
+	c.updateMx.Unlock()
+	return nil
+}
+
+// GetEvents flushes the buffered events and queries them by time, other filters are applied in memory
+func (c *Client) GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	if err := c.flushEvents(ctx); err != nil {
+		return nil, err
+	}
+	query := c.Collection(eventsCollection).OrderBy("at", firestore.Desc)
+	if !filter.From.IsZero() {
+		query = query.Where("at", ">=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("at", "<", filter.To)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+	res := make([]*pkg.PlayEvent, 0)
+	for !filter.Full(len(res)) {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "iteration failed")
+		}
+		var e pkg.PlayEvent
+		if err := doc.DataTo(&e); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal event data")
+		}
+		if filter.Match(&e) {
+			res = append(res, &e)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) flushEvents(ctx context.Context) error {
+	c.flushMx.Lock()
+	defer c.flushMx.Unlock()
+	c.updateMx.Lock()
+	toSend := c.events
+	c.events = nil
+	c.updateMx.Unlock()
+
+	for i := 0; i < len(toSend); i += batchSize {
+		k := i + batchSize
+		if k > len(toSend) {
+			k = len(toSend)
+		}
+		err := retry(ctx, func() error {
+			batch := c.Batch()
+			for _, e := range toSend[i:k] {
+				batch.Create(c.Collection(eventsCollection).NewDoc(), e)
+			}
+			_, err := batch.Commit(ctx)
+			return err
+		})
+		if err != nil {
+			c.updateMx.Lock()
+			c.events = append(toSend[i:], c.events...)
+			c.updateMx.Unlock()
+			return errors.Wrapf(err, "faild to send events batch from %d to %d", i, k)
+		}
+	}
+	return nil
+}
+
+// requeueSongs returns failed songs to the buffer unless they were updated in the meantime
+func (c *Client) requeueSongs(songs []*pkg.Song) {
+	c.updateMx.Lock()
+	defer c.updateMx.Unlock()
+	for _, s := range songs {
+		if _, ok := c.songs[s.ID.String()]; !ok {
+			c.songs[s.ID.String()] = s
+		}
+	}
+}
+
+func (c *Client) requeueUserSong(song *pkg.Song, user string) {
+	c.updateMx.Lock()
+	defer c.updateMx.Unlock()
+	if _, ok := c.userSongs[user]; !ok {
+		c.userSongs[user] = make(map[string]*pkg.Song)
+	}
+	if _, ok := c.userSongs[user][song.ID.String()]; !ok {
+		c.userSongs[user][song.ID.String()] = song
+	}
+}
+
 func (c *Client) WriteBatch(ctx context.Context, songs []*pkg.Song) error {
 	size := len(songs)
 	for i := 0; i < size; i += batchSize {
@@ -243,7 +824,9 @@ func (c *Client) WriteBatch(ctx context.Context, songs []*pkg.Song) error {
 		if k > size {
 			k = size
 		}
-		err := c.doBatch(ctx, songs[i:k])
+		err := retry(ctx, func() error {
+			return c.doBatch(ctx, songs[i:k])
+		})
 		if err != nil {
 			return errors.Wrapf(err, "faild to send songs batch from %d to %d", i, k)
 		}
@@ -251,10 +834,27 @@ func (c *Client) WriteBatch(ctx context.Context, songs []*pkg.Song) error {
 	return nil
 }
 
+// retry calls f until it succeeds, doubling the pause between attempts
+func retry(ctx context.Context, f func() error) error {
+	backoff := retryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil || attempt == writeAttempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return errors.Wrap(err, ctx.Err().Error())
+		}
+	}
+}
+
 func (c *Client) doBatch(ctx context.Context, songs []*pkg.Song) error {
 	batch := c.Batch()
 	for s := range songs {
-		batch.Set(c.Collection(songsCollection).Doc(songs[s].ID.String()), songs[s])
+		batch.Set(c.Collection(songsCollection).Doc(songs[s].ID.String()), storage.Versioned(songs[s]))
 	}
 	_, err := batch.Commit(ctx)
 	return err
@@ -0,0 +1,291 @@
+package firestore
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+// fakeFirestore is the firestore server which only commits writes
+type fakeFirestore struct {
+	pb.UnimplementedFirestoreServer
+
+	mx       sync.Mutex
+	failures int            // commits failing before the next one succeeds
+	onFail   func()         // called on every failed commit
+	commits  []int          // number of writes of every successful commit
+	docs     map[string]int // number of writes of every document by its path
+}
+
+func (f *fakeFirestore) Commit(ctx context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		if f.onFail != nil {
+			f.onFail()
+		}
+		return nil, status.Error(codes.Internal, "commit failed")
+	}
+	res := &pb.CommitResponse{CommitTime: timestamppb.Now()}
+	for _, w := range req.Writes {
+		name := w.GetUpdate().GetName()
+		f.docs[name[strings.Index(name, "/documents/")+len("/documents/"):]]++
+		res.WriteResults = append(res.WriteResults, &pb.WriteResult{UpdateTime: timestamppb.Now()})
+	}
+	f.commits = append(f.commits, len(req.Writes))
+	return res, nil
+}
+
+func (f *fakeFirestore) fail(n int) {
+	f.mx.Lock()
+	f.failures = n
+	f.mx.Unlock()
+}
+
+func (f *fakeFirestore) written() ([]int, map[string]int) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	docs := make(map[string]int, len(f.docs))
+	for k, v := range f.docs {
+		docs[k] = v
+	}
+	return append([]int(nil), f.commits...), docs
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeFirestore) {
+	t.Helper()
+	fake := &fakeFirestore{docs: make(map[string]int)}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterFirestoreServer(server, fake)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	backoff := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = backoff })
+	t.Setenv("FIRESTORE_EMULATOR_HOST", lis.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	fs, err := firestore.NewClient(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = fs.Close() })
+	c, err := NewFirestoreClient(ctx, fs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, fake
+}
+
+func testSong(i int) *pkg.Song {
+	return &pkg.Song{ID: pkg.SongID{ID: "song" + strconv.Itoa(i), Service: pkg.ServiceYouTube}, Title: "song"}
+}
+
+func TestFlushUserSongsBatches(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newTestClient(t)
+	users := []string{"first", "second", "third"}
+	for i := 0; i < batchSize; i++ {
+		for _, user := range users {
+			if err := c.SetUserSong(ctx, testSong(i), user); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := c.flushUserSongs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	commits, docs := fake.written()
+	if len(commits) != len(users) {
+		t.Fatalf("got commits %v, wanted %d batches of %d writes", commits, len(users), batchSize)
+	}
+	for _, n := range commits {
+		if n != batchSize {
+			t.Fatalf("got commits %v, wanted %d batches of %d writes", commits, len(users), batchSize)
+		}
+	}
+	if len(docs) != len(users)*batchSize || docs["users/second/songs/"+testSong(7).ID.String()] != 1 {
+		t.Fatalf("got %d documents, wanted every user song written once", len(docs))
+	}
+}
+
+func TestFlushUserSongsRequeue(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newTestClient(t)
+	updated, failed := testSong(1), testSong(2)
+	for _, song := range []*pkg.Song{updated, failed} {
+		if err := c.SetUserSong(ctx, song, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The song updated during the flush is newer than the requeued one
+	newer := testSong(1)
+	newer.Playbacks = 2
+	fake.fail(writeAttempts)
+	fake.onFail = func() {
+		if err := c.SetUserSong(ctx, newer, "user"); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := c.flushUserSongs(ctx); err == nil {
+		t.Fatal("got no error, wanted the failed batch")
+	}
+	if commits, _ := fake.written(); len(commits) != 0 {
+		t.Fatalf("got commits %v, wanted none", commits)
+	}
+	c.updateMx.Lock()
+	buffered := c.userSongs["user"]
+	c.updateMx.Unlock()
+	if len(buffered) != 2 || buffered[updated.ID.String()] != newer || buffered[failed.ID.String()] != failed {
+		t.Fatalf("got buffered %v, wanted the failed song requeued and the newer one kept", buffered)
+	}
+
+	if err := c.flushUserSongs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, docs := fake.written(); docs["users/user/songs/"+updated.ID.String()] != 1 || docs["users/user/songs/"+failed.ID.String()] != 1 {
+		t.Fatalf("got documents %v, wanted the requeued songs written", docs)
+	}
+	c.updateMx.Lock()
+	left := len(c.userSongs)
+	c.updateMx.Unlock()
+	if left != 0 {
+		t.Fatalf("got %d users buffered after the flush, wanted none", left)
+	}
+}
+
+func TestFlushUserSongsKeepsSongs(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newTestClient(t)
+	song := testSong(1)
+	if err := c.SetSong(ctx, song); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetUserSong(ctx, song, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	// User songs are flushed on their own timer, the buffered song is not theirs
+	if err := c.flushUserSongs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.flushSongs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, docs := fake.written(); docs["songs/"+song.ID.String()] != 1 || docs["users/user/songs/"+song.ID.String()] != 1 {
+		t.Fatalf("got documents %v, wanted the song and the user song written", docs)
+	}
+}
+
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newTestClient(t)
+	if err := c.SetSong(ctx, testSong(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetUserSong(ctx, testSong(1), "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddEvents(ctx, []*pkg.PlayEvent{{Type: pkg.EventPlay, SongID: testSong(1).ID}}); err != nil {
+		t.Fatal(err)
+	}
+	// Every write succeeds on the second attempt
+	fake.fail(1)
+
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	commits, docs := fake.written()
+	events := 0
+	for doc := range docs {
+		if strings.HasPrefix(doc, eventsCollection+"/") {
+			events++
+		}
+	}
+	if len(commits) != 3 || docs["songs/"+testSong(1).ID.String()] != 1 || docs["users/user/songs/"+testSong(1).ID.String()] != 1 || events != 1 {
+		t.Fatalf("got commits %v and documents %v, wanted every buffer flushed", commits, docs)
+	}
+}
+
+func TestCloseKeepsFailed(t *testing.T) {
+	c, fake := newTestClient(t)
+	if err := c.SetSong(context.Background(), testSong(1)); err != nil {
+		t.Fatal(err)
+	}
+	fake.fail(1000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Close(ctx); err == nil {
+		t.Fatal("got no error, wanted the failed flush")
+	}
+	c.updateMx.Lock()
+	left := len(c.songs)
+	c.updateMx.Unlock()
+	if left != 1 {
+		t.Fatalf("got %d songs buffered, wanted the failed song kept", left)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = backoff }()
+	errCommit := status.Error(codes.Internal, "commit failed")
+
+	attempts := 0
+	err := retry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errCommit
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("got %v after %d attempts, wanted success on the third one", err, attempts)
+	}
+
+	attempts = 0
+	err = retry(context.Background(), func() error {
+		attempts++
+		return errCommit
+	})
+	if err == nil || attempts != writeAttempts {
+		t.Fatalf("got %v after %d attempts, wanted the error after %d", err, attempts, writeAttempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = retry(ctx, func() error {
+		attempts++
+		return errCommit
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("got %v after %d attempts, wanted to give up on the cancelled context", err, attempts)
+	}
+}
@@ -0,0 +1,258 @@
+package firestore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+// MergeDuplicateSongs moves every song document to the canonical ID parsed from its URL.
+// Documents that end up with the same ID are merged into one: playbacks are summed
+// and the latest LastPlay is kept. The same is done for every users/{id}/songs collection.
+// Returns the number of removed duplicate documents.
+func (c *Client) MergeDuplicateSongs(ctx context.Context, dryRun bool) (int, error) {
+	merged, err := c.mergeDuplicates(ctx, c.Collection(songsCollection), dryRun)
+	if err != nil {
+		return 0, errors.Wrap(err, "merge songs")
+	}
+
+	users, err := c.Collection(usersCollection).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return merged, errors.Wrap(err, "get users")
+	}
+	for _, user := range users {
+		n, err := c.mergeDuplicates(ctx, user.Collection(songsCollection), dryRun)
+		if err != nil {
+			return merged, errors.Wrapf(err, "merge songs of user %s", user.ID)
+		}
+		merged += n
+	}
+	return merged, nil
+}
+
+type songDoc struct {
+	ref  *firestore.DocumentRef
+	song pkg.Song
+}
+
+func (c *Client) mergeDuplicates(ctx context.Context, collection *firestore.CollectionRef, dryRun bool) (int, error) {
+	logger := contexts.GetLogger(ctx)
+	groups := make(map[string][]songDoc)
+	iter := collection.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "iteration failed")
+		}
+		s, err := parseSongDoc(doc)
+		if err != nil {
+			logger.Error("skip broken song doc", zap.String("path", doc.Ref.Path), zap.Error(err))
+			continue
+		}
+		key := doc.Ref.ID
+		if id := pkg.GetIDFromURL(s.URL); id.ID != "" {
+			key = id.String()
+		}
+		groups[key] = append(groups[key], songDoc{ref: doc.Ref, song: s})
+	}
+
+	batch := c.Batch()
+	operations, removed := 0, 0
+	for key, docs := range groups {
+		if len(docs) == 1 && docs[0].ref.ID == key {
+			continue
+		}
+		// The document which already has the canonical ID is the base of the merge
+		for i := range docs {
+			if docs[i].ref.ID == key {
+				docs[0], docs[i] = docs[i], docs[0]
+				break
+			}
+		}
+		song := docs[0].song
+		for i := 1; i < len(docs); i++ {
+			song.MergeDuplicate(&docs[i].song)
+		}
+		logger.Info("merge song docs",
+			zap.String("collection", collection.Path),
+			zap.String("id", key),
+			zap.Int("docs", len(docs)),
+			zap.Int("playbacks", song.Playbacks))
+		if dryRun {
+			removed += len(docs) - 1
+			if docs[0].ref.ID != key {
+				removed++
+			}
+			continue
+		}
+
+		if operations+len(docs)+1 > batchSize {
+			if _, err := batch.Commit(ctx); err != nil {
+				return removed, errors.Wrap(err, "commit merge batch")
+			}
+			batch = c.Batch()
+			operations = 0
+		}
+		batch.Set(collection.Doc(key), storage.Versioned(&song))
+		operations++
+		for i := range docs {
+			if docs[i].ref.ID != key {
+				batch.Delete(docs[i].ref)
+				operations++
+				removed++
+			}
+		}
+	}
+	if operations > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return removed, errors.Wrap(err, "commit merge batch")
+		}
+	}
+	return removed, nil
+}
+
+// migrationCheckpoint is the last migrated document, a stopped migration continues after it
+type migrationCheckpoint struct {
+	Version    int       `firestore:"version"`
+	Collection string    `firestore:"collection"`
+	LastID     string    `firestore:"last_id"`
+	Updated    time.Time `firestore:"updated"`
+}
+
+// MigrateSongs upgrades song documents of songs and every users/{id}/songs collection
+// to storage.SongSchemaVersion in batches. The checkpoint is committed with every batch,
+// so an interrupted migration continues from the last migrated document.
+func (c *Client) MigrateSongs(ctx context.Context, dryRun bool) (*storage.MigrationResult, error) {
+	logger := contexts.GetLogger(ctx)
+	users, err := c.Collection(usersCollection).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "get users")
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	collections := []*firestore.CollectionRef{c.Collection(songsCollection)}
+	for _, user := range users {
+		collections = append(collections, user.Collection(songsCollection))
+	}
+
+	checkpointRef := c.Collection(migrationsCollection).Doc(songsCollection)
+	var checkpoint migrationCheckpoint
+	doc, err := checkpointRef.Get(ctx)
+	switch {
+	case err == nil:
+		if err := doc.DataTo(&checkpoint); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal checkpoint data")
+		}
+	case status.Code(err) != codes.NotFound:
+		return nil, errors.Wrap(err, "get checkpoint")
+	}
+	start := 0
+	if checkpoint.Version == storage.SongSchemaVersion {
+		for i := range collections {
+			if collections[i].Path == checkpoint.Collection {
+				start = i
+				logger.Info("resume migration", zap.String("collection", checkpoint.Collection), zap.String("after", checkpoint.LastID))
+				break
+			}
+		}
+	}
+
+	res := &storage.MigrationResult{}
+	for i := start; i < len(collections); i++ {
+		after := ""
+		if i == start && collections[i].Path == checkpoint.Collection {
+			after = checkpoint.LastID
+		}
+		if err := c.migrateCollection(ctx, collections[i], checkpointRef, after, dryRun, res); err != nil {
+			return res, errors.Wrapf(err, "migrate %s", collections[i].Path)
+		}
+	}
+	if !dryRun {
+		if _, err := checkpointRef.Delete(ctx); err != nil {
+			return res, errors.Wrap(err, "delete checkpoint")
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) migrateCollection(
+	ctx context.Context,
+	collection *firestore.CollectionRef,
+	checkpointRef *firestore.DocumentRef,
+	after string,
+	dryRun bool,
+	res *storage.MigrationResult,
+) error {
+	logger := contexts.GetLogger(ctx)
+	for {
+		// One write of the batch is left for the checkpoint
+		query := collection.OrderBy(firestore.DocumentID, firestore.Asc).Limit(batchSize - 1)
+		if after != "" {
+			query = query.StartAfter(after)
+		}
+		docs, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			return errors.Wrap(err, "get documents")
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		batch := c.Batch()
+		migrated := 0
+		for _, doc := range docs {
+			res.Checked++
+			data := doc.Data()
+			changed, err := storage.MigrateSong(data)
+			if err != nil {
+				logger.Error("skip broken song doc", zap.String("path", doc.Ref.Path), zap.Error(err))
+				res.Skipped++
+				continue
+			}
+			if changed {
+				batch.Set(doc.Ref, data)
+				migrated++
+			}
+		}
+		after = docs[len(docs)-1].Ref.ID
+		if !dryRun {
+			batch.Set(checkpointRef, &migrationCheckpoint{
+				Version:    storage.SongSchemaVersion,
+				Collection: collection.Path,
+				LastID:     after,
+				Updated:    time.Now(),
+			})
+			if err := retry(ctx, func() error {
+				_, err := batch.Commit(ctx)
+				return err
+			}); err != nil {
+				return errors.Wrap(err, "commit migration batch")
+			}
+		}
+		res.Migrated += migrated
+		logger.Info("songs migrated",
+			zap.String("collection", collection.Path),
+			zap.String("last_id", after),
+			zap.Int("migrated", migrated),
+			zap.Bool("dry_run", dryRun))
+		if len(docs) < batchSize-1 {
+			return nil
+		}
+	}
+}
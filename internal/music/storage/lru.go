@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRUStats are counters of the cache since the start
+type LRUStats struct {
+	Hits      int `json:"hits"`
+	Misses    int `json:"misses"`
+	Evictions int `json:"evictions"`
+	Expired   int `json:"expired"`
+	Size      int `json:"size"`
+	Capacity  int `json:"capacity"`
+}
+
+func (s LRUStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+	used  time.Time
+}
+
+// LRU is a cache evicting the least recently used entry when it is full.
+// Zero capacity is unbounded, zero ttl keeps entries until they are evicted.
+type LRU struct {
+	mx       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // front is the most recently used
+	entries  map[string]*list.Element
+	stats    LRUStats
+}
+
+func NewLRU(capacity int, ttl time.Duration) *LRU {
+	return &LRU{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value and marks it as used, entries idle longer than ttl are missed
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	e := elem.Value.(*lruEntry)
+	now := time.Now()
+	if c.expired(e, now) {
+		c.remove(elem)
+		c.stats.Expired++
+		c.stats.Misses++
+		return nil, false
+	}
+	e.used = now
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return e.value, true
+}
+
+// Set adds or replaces the value, the least recently used entry is evicted if the cache is full
+func (c *LRU) Set(key string, value interface{}) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*lruEntry)
+		e.value = value
+		e.used = time.Now()
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, used: time.Now()})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Values returns all values, the most recently used first
+func (c *LRU) Values() []interface{} {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	res := make([]interface{}, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		res = append(res, elem.Value.(*lruEntry).value)
+	}
+	return res
+}
+
+func (c *LRU) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.order.Len()
+}
+
+// RemoveExpired drops entries idle longer than ttl and returns their number
+func (c *LRU) RemoveExpired() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	now := time.Now()
+	removed := 0
+	// The back is the least recently used, so the walk stops at the first fresh entry
+	for elem := c.order.Back(); elem != nil && c.expired(elem.Value.(*lruEntry), now); elem = c.order.Back() {
+		c.remove(elem)
+		removed++
+	}
+	c.stats.Expired += removed
+	return removed
+}
+
+// Clear drops all entries, the counters are kept
+func (c *LRU) Clear() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+func (c *LRU) Stats() LRUStats {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
+
+func (c *LRU) expired(e *lruEntry, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(e.used) > c.ttl
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
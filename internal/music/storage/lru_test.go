@@ -0,0 +1,49 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := storage.NewLRU(2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a is missed")
+	}
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b is not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v.(int) != 1 {
+		t.Fatalf("got %v, %v for a", v, ok)
+	}
+	want := storage.LRUStats{Hits: 2, Misses: 1, Evictions: 1, Size: 2, Capacity: 2}
+	if got := c.Stats(); got != want {
+		t.Fatalf("got %+v, wanted %+v", got, want)
+	}
+	values := c.Values()
+	if len(values) != 2 || values[0].(int) != 1 || values[1].(int) != 3 {
+		t.Fatalf("got values %v", values)
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	c := storage.NewLRU(0, 50*time.Millisecond)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	time.Sleep(100 * time.Millisecond)
+	c.Set("c", 3)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a is not expired")
+	}
+	if n := c.RemoveExpired(); n != 1 || c.Len() != 1 {
+		t.Fatalf("removed %d, left %d", n, c.Len())
+	}
+	if got := c.Stats(); got.Expired != 2 || got.Misses != 1 {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -0,0 +1,352 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+// Client is the storage backend which keeps everything in memory.
+// It is used in tests and by botmock.
+type Client struct {
+	mx        sync.RWMutex
+	songs     map[string]pkg.Song
+	userSongs map[string]map[string]pkg.Song
+	queries   map[string]pkg.SearchQuery
+	accounts  map[string]pkg.AccountInfo
+	events    []pkg.PlayEvent // ordered by time
+	playlists map[string]pkg.Playlist
+	likes     map[string]map[pkg.SongID]pkg.Like
+	bans      map[string]pkg.Ban
+	banAudit  []pkg.BanAudit // ordered by time
+}
+
+func NewMemoryClient() *Client {
+	return &Client{
+		songs:     make(map[string]pkg.Song),
+		userSongs: make(map[string]map[string]pkg.Song),
+		queries:   make(map[string]pkg.SearchQuery),
+		accounts:  make(map[string]pkg.AccountInfo),
+		playlists: make(map[string]pkg.Playlist),
+		likes:     make(map[string]map[pkg.SongID]pkg.Like),
+		bans:      make(map[string]pkg.Ban),
+	}
+}
+
+// Fixtures is the initial content of the storage.
+// Song IDs are parsed from their URLs.
+type Fixtures struct {
+	Songs    []pkg.Song                 `json:"songs"`
+	Users    map[string][]pkg.Song      `json:"users"`
+	Accounts map[string]pkg.AccountInfo `json:"accounts"`
+}
+
+// LoadFixtures reads JSON encoded Fixtures and adds them to the storage
+func (c *Client) LoadFixtures(r io.Reader) error {
+	var f Fixtures
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return errors.Wrap(err, "decode fixtures")
+	}
+	ctx := context.Background()
+	for i := range f.Songs {
+		song := f.Songs[i]
+		song.ID = pkg.GetIDFromURL(song.URL)
+		if err := c.SetSong(ctx, &song); err != nil {
+			return err
+		}
+	}
+	for user, songs := range f.Users {
+		for i := range songs {
+			song := songs[i]
+			song.ID = pkg.GetIDFromURL(song.URL)
+			if err := c.SetUserSong(ctx, &song, user); err != nil {
+				return err
+			}
+		}
+	}
+	for login, info := range f.Accounts {
+		if err := c.SetAccount(ctx, login, &info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) GetSongByID(ctx context.Context, id pkg.SongID) (*pkg.Song, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	song, ok := c.songs[id.String()]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &song, nil
+}
+
+func (c *Client) SetSong(ctx context.Context, song *pkg.Song) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.songs[song.ID.String()] = *song
+	return nil
+}
+
+func (c *Client) DeleteSong(ctx context.Context, id pkg.SongID) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.songs, id.String())
+	return nil
+}
+
+func (c *Client) GetUserSong(ctx context.Context, id pkg.SongID, user string) (*pkg.Song, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	song, ok := c.userSongs[user][id.String()]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &song, nil
+}
+
+func (c *Client) SetUserSong(ctx context.Context, song *pkg.Song, user string) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if _, ok := c.userSongs[user]; !ok {
+		c.userSongs[user] = make(map[string]pkg.Song)
+	}
+	c.userSongs[user][song.ID.String()] = *song
+	return nil
+}
+
+func (c *Client) DeleteUserSong(ctx context.Context, id pkg.SongID, user string) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.userSongs[user], id.String())
+	return nil
+}
+
+func (c *Client) GetUsers(ctx context.Context) ([]string, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]string, 0, len(c.userSongs))
+	for user := range c.userSongs {
+		res = append(res, user)
+	}
+	return res, nil
+}
+
+func (c *Client) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.Song, 0, len(c.userSongs[user]))
+	for k := range c.userSongs[user] {
+		song := c.userSongs[user][k]
+		res = append(res, &song)
+	}
+	return res, nil
+}
+
+func (c *Client) GetAllSongs(ctx context.Context) ([]*pkg.Song, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.Song, 0, len(c.songs))
+	for k := range c.songs {
+		song := c.songs[k]
+		res = append(res, &song)
+	}
+	return res, nil
+}
+
+func (c *Client) GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	q, ok := c.queries[query]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &q, nil
+}
+
+func (c *Client) SetQuery(ctx context.Context, query *pkg.SearchQuery) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.queries[query.Query] = *query
+	return nil
+}
+
+// DeleteQueries deletes the cached query or all of them if query is empty.
+// Returns the number of deleted entries.
+func (c *Client) DeleteQueries(ctx context.Context, query string) (int, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if query != "" {
+		if _, ok := c.queries[query]; !ok {
+			return 0, nil
+		}
+		delete(c.queries, query)
+		return 1, nil
+	}
+	n := len(c.queries)
+	c.queries = make(map[string]pkg.SearchQuery)
+	return n, nil
+}
+
+func (c *Client) GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	info, ok := c.accounts[login]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &info, nil
+}
+
+func (c *Client) SetAccount(ctx context.Context, login string, info *pkg.AccountInfo) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.accounts[login] = *info
+	return nil
+}
+
+func (c *Client) GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	p, ok := c.playlists[pkg.PlaylistKey(ownerID, name)]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	p.Songs = append([]pkg.PlaylistSong(nil), p.Songs...)
+	return &p, nil
+}
+
+func (c *Client) SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	p := *playlist
+	p.Songs = append([]pkg.PlaylistSong(nil), p.Songs...)
+	c.playlists[pkg.PlaylistKey(p.OwnerID, p.Name)] = p
+	return nil
+}
+
+func (c *Client) DeletePlaylist(ctx context.Context, ownerID, name string) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.playlists, pkg.PlaylistKey(ownerID, name))
+	return nil
+}
+
+func (c *Client) GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.Playlist, 0)
+	for k := range c.playlists {
+		p := c.playlists[k]
+		if filter.Match(&p) {
+			p.Songs = append([]pkg.PlaylistSong(nil), p.Songs...)
+			res = append(res, &p)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) SetLike(ctx context.Context, user string, like *pkg.Like) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.likes[user] == nil {
+		c.likes[user] = make(map[pkg.SongID]pkg.Like)
+	}
+	c.likes[user][like.ID] = *like
+	return nil
+}
+
+func (c *Client) DeleteLike(ctx context.Context, user string, id pkg.SongID) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.likes[user], id)
+	return nil
+}
+
+func (c *Client) GetLikes(ctx context.Context, user string) ([]*pkg.Like, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.Like, 0, len(c.likes[user]))
+	for id := range c.likes[user] {
+		like := c.likes[user][id]
+		res = append(res, &like)
+	}
+	pkg.SortLikes(res)
+	return res, nil
+}
+
+func (c *Client) SetBan(ctx context.Context, ban *pkg.Ban) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.bans[pkg.BanKey(ban.Kind, ban.Value)] = *ban
+	return nil
+}
+
+func (c *Client) DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.bans, pkg.BanKey(kind, value))
+	return nil
+}
+
+func (c *Client) GetBans(ctx context.Context) ([]*pkg.Ban, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.Ban, 0, len(c.bans))
+	for k := range c.bans {
+		ban := c.bans[k]
+		res = append(res, &ban)
+	}
+	return res, nil
+}
+
+func (c *Client) AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.banAudit = append(c.banAudit, *entry)
+	return nil
+}
+
+func (c *Client) GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.BanAudit, 0)
+	for i := len(c.banAudit) - 1; i >= 0 && len(res) < limit; i-- {
+		entry := c.banAudit[i]
+		res = append(res, &entry)
+	}
+	return res, nil
+}
+
+func (c *Client) AddEvents(ctx context.Context, events []*pkg.PlayEvent) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for _, e := range events {
+		c.events = append(c.events, *e)
+	}
+	sort.SliceStable(c.events, func(i, j int) bool {
+		return c.events[i].At.Before(c.events[j].At)
+	})
+	return nil
+}
+
+func (c *Client) GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	res := make([]*pkg.PlayEvent, 0)
+	for i := len(c.events) - 1; i >= 0 && !filter.Full(len(res)); i-- {
+		if filter.Match(&c.events[i]) {
+			e := c.events[i]
+			res = append(res, &e)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+// SongSchemaVersion is the version of song documents written by the backends.
+// A change of the stored fields of pkg.Song bumps it and appends a migration to songMigrations.
+const SongSchemaVersion = 1
+
+const schemaVersionField = "schema_version"
+
+// ErrNewerSchema is returned for documents written by a newer version of the bot
+var ErrNewerSchema = errors.New("song schema is newer than supported")
+
+// SongMigration upgrades the raw stored song by one schema version in place
+type SongMigration func(data map[string]interface{}) error
+
+// songMigrations[i] upgrades the song of version i to i+1
+var songMigrations = []SongMigration{
+	migrateLastPlay,
+}
+
+// MigrationResult is the number of song documents checked and upgraded by the migration
+type MigrationResult struct {
+	Checked  int
+	Migrated int
+	Skipped  int
+}
+
+// SongVersion returns the schema version of the raw stored song, songs without it are of version 0
+func SongVersion(data map[string]interface{}) int {
+	switch v := data[schemaVersionField].(type) {
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// MigrateSong upgrades the raw stored song to SongSchemaVersion, returns whether it has been changed
+func MigrateSong(data map[string]interface{}) (bool, error) {
+	version := SongVersion(data)
+	if version > SongSchemaVersion {
+		return false, errors.Wrapf(ErrNewerSchema, "version %d", version)
+	}
+	if version == SongSchemaVersion {
+		return false, nil
+	}
+	for ; version < SongSchemaVersion; version++ {
+		if err := songMigrations[version](data); err != nil {
+			return false, errors.Wrapf(err, "migrate from version %d", version)
+		}
+	}
+	data[schemaVersionField] = int64(SongSchemaVersion)
+	return true, nil
+}
+
+// DecodeSong migrates the raw stored song and decodes it.
+// Field names of the documents are the same in every backend, so it goes through JSON.
+func DecodeSong(data map[string]interface{}) (pkg.Song, error) {
+	if _, err := MigrateSong(data); err != nil {
+		return pkg.Song{}, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return pkg.Song{}, errors.Wrap(err, "marshal song data")
+	}
+	var song pkg.Song
+	if err := json.Unmarshal(raw, &song); err != nil {
+		return pkg.Song{}, errors.Wrap(err, "unmarshal song data")
+	}
+	return song, nil
+}
+
+// UnmarshalSong decodes the song stored as JSON, songs of older versions are migrated first
+func UnmarshalSong(raw []byte) (pkg.Song, error) {
+	var song pkg.Song
+	if err := json.Unmarshal(raw, &song); err == nil && song.SchemaVersion >= SongSchemaVersion {
+		return song, nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return pkg.Song{}, errors.Wrap(err, "unmarshal song data")
+	}
+	return DecodeSong(data)
+}
+
+// Versioned returns a copy of the song stamped with SongSchemaVersion, the backends write it
+func Versioned(song *pkg.Song) *pkg.Song {
+	res := *song
+	res.SchemaVersion = SongSchemaVersion
+	return &res
+}
+
+// migrateLastPlay replaces last_play stored as a map by the time in it, the first songs were written so
+func migrateLastPlay(data map[string]interface{}) error {
+	lastPlay, ok := data["last_play"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if t, ok := lastPlay["Time"]; ok {
+		data["last_play"] = t
+	} else {
+		delete(data, "last_play")
+	}
+	return nil
+}
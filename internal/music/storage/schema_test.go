@@ -0,0 +1,47 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+)
+
+func TestUnmarshalSong(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want time.Time
+	}{
+		{"version 0 with map", `{"title":"t","last_play":{"Time":"2022-05-01T10:00:00Z"}}`, time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"version 0 with empty map", `{"title":"t","last_play":{}}`, time.Time{}},
+		{"version 0", `{"title":"t","last_play":"2022-05-01T10:00:00Z"}`, time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"current", `{"title":"t","last_play":"2022-05-01T10:00:00Z","schema_version":1}`, time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song, err := storage.UnmarshalSong([]byte(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if song.Title != "t" || !song.LastPlay.Equal(tt.want) || song.SchemaVersion != storage.SongSchemaVersion {
+				t.Fatalf("got %+v", song)
+			}
+		})
+	}
+}
+
+func TestMigrateSong(t *testing.T) {
+	data := map[string]interface{}{"title": "t", "schema_version": int64(storage.SongSchemaVersion)}
+	if changed, err := storage.MigrateSong(data); changed || err != nil {
+		t.Fatalf("got %v, %v for the current version", changed, err)
+	}
+	data["schema_version"] = int64(storage.SongSchemaVersion + 1)
+	if _, err := storage.MigrateSong(data); err == nil {
+		t.Fatal("newer version is migrated")
+	}
+	data = map[string]interface{}{"title": "t"}
+	if changed, err := storage.MigrateSong(data); !changed || err != nil || storage.SongVersion(data) != storage.SongSchemaVersion {
+		t.Fatalf("got %v, %v, %v", changed, err, data)
+	}
+}
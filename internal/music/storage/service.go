@@ -0,0 +1,397 @@
+package storage
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+)
+
+// reconcileInterval is how often the songs index is compared with the full scan of the backend
+const reconcileInterval = 24 * time.Hour
+
+// songsIndex keeps all songs without their heavy fields, banned ones too.
+// Writes of the service update it right away, the reconciliation scan catches what was written elsewhere.
+type songsIndex struct {
+	sync.Mutex
+	songs map[string]*pkg.Song
+	// pending are songs written during the reconciliation scan, nil is a deleted song
+	pending map[string]*pkg.Song
+	// loaded is set by the first successful scan, the index is incomplete before it
+	loaded bool
+}
+
+// Service caches songs and samples random ones on top of any Backend
+type Service struct {
+	cache  *SongsCache
+	client Backend
+	users  *userPlays
+
+	index       songsIndex
+	reconcileMx sync.Mutex // only one reconciliation scan at a time
+
+	rngMx sync.Mutex
+	rng   *rand.Rand
+}
+
+func NewService(ctx context.Context, client Backend, songs *SongsCache) (*Service, error) {
+	f := Service{
+		cache:  songs,
+		client: client,
+		users:  newUserPlays(),
+		index:  songsIndex{songs: make(map[string]*pkg.Song)},
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	go f.reconcileIndex(ctx)
+	f.reconcileIndexProcess(ctx)
+	return &f, nil
+}
+
+func (s *Service) GetSong(ctx context.Context, id pkg.SongID) (*pkg.Song, error) {
+	key := s.cache.KeyFromID(id)
+	logger := contexts.GetLogger(ctx)
+	logger.Debug("get song from cache", zap.String("id", id.String()))
+	if s, ok := s.cache.Get(key); ok {
+		return s, nil
+	}
+
+	logger.Debug("get song from db", zap.String("id", id.String()))
+
+	song, err := s.client.GetSongByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get song by id %s", id)
+	}
+	logger.Debug("set song to cache", zap.String("id", id.String()))
+	s.cache.Set(key, song)
+	return song, nil
+}
+
+func (s *Service) SetSong(ctx context.Context, song *pkg.Song) error {
+	if err := s.client.SetSong(ctx, song); err != nil {
+		return errors.Wrap(err, "backend set song")
+	}
+	s.cache.Set(s.cache.KeyFromID(song.ID), song)
+	s.index.set(song.ID.String(), indexSong(song))
+	return nil
+}
+
+func (s *Service) UpsertSongIncPlaybacks(ctx context.Context, new *pkg.Song) (int, error) {
+	old, err := s.GetSong(ctx, new.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, errors.Wrap(err, "failed to get song from db")
+	}
+	playbacks := 0
+	new.MergeNoOverride(old)
+	new.Playbacks++
+	playbacks = new.Playbacks
+	if err = s.SetSong(ctx, new); err != nil {
+		return 0, errors.Wrap(err, "failed to set song into db")
+	}
+	return playbacks, nil
+}
+
+func (s *Service) GetUsers(ctx context.Context) ([]string, error) {
+	return s.client.GetUsers(ctx)
+}
+
+func (s *Service) GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error) {
+	return s.client.GetUserSongs(ctx, user)
+}
+
+// GetAllSongs reads every stored song, SongsIndex is enough for sampling
+func (s *Service) GetAllSongs(ctx context.Context) ([]*pkg.Song, error) {
+	return s.client.GetAllSongs(ctx)
+}
+
+func (s *Service) GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error) {
+	return s.client.GetQuery(ctx, query)
+}
+
+func (s *Service) SetQuery(ctx context.Context, query *pkg.SearchQuery) error {
+	return s.client.SetQuery(ctx, query)
+}
+
+func (s *Service) DeleteQueries(ctx context.Context, query string) (int, error) {
+	return s.client.DeleteQueries(ctx, query)
+}
+
+func (s *Service) GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error) {
+	return s.client.GetAccount(ctx, login)
+}
+
+func (s *Service) GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error) {
+	return s.client.GetPlaylist(ctx, ownerID, name)
+}
+
+func (s *Service) SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error {
+	return s.client.SetPlaylist(ctx, playlist)
+}
+
+func (s *Service) DeletePlaylist(ctx context.Context, ownerID, name string) error {
+	return s.client.DeletePlaylist(ctx, ownerID, name)
+}
+
+func (s *Service) GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error) {
+	return s.client.GetPlaylists(ctx, filter)
+}
+
+func (s *Service) SetLike(ctx context.Context, user string, like *pkg.Like) error {
+	return s.client.SetLike(ctx, user, like)
+}
+
+func (s *Service) DeleteLike(ctx context.Context, user string, id pkg.SongID) error {
+	return s.client.DeleteLike(ctx, user, id)
+}
+
+func (s *Service) GetLikes(ctx context.Context, user string) ([]*pkg.Like, error) {
+	return s.client.GetLikes(ctx, user)
+}
+
+func (s *Service) SetBan(ctx context.Context, ban *pkg.Ban) error {
+	return s.client.SetBan(ctx, ban)
+}
+
+func (s *Service) DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error {
+	return s.client.DeleteBan(ctx, kind, value)
+}
+
+func (s *Service) GetBans(ctx context.Context) ([]*pkg.Ban, error) {
+	return s.client.GetBans(ctx)
+}
+
+func (s *Service) AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error {
+	return s.client.AddBanAudit(ctx, entry)
+}
+
+func (s *Service) GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error) {
+	return s.client.GetBanAudit(ctx, limit)
+}
+
+// FlagSongs sets the banned flag of every stored song matching the ban, songs matching any of kept bans stay flagged.
+// The matching songs are found in the index, the backend is scanned only until the index is loaded.
+// The index is updated right away, so banned songs are never picked at random.
+func (s *Service) FlagSongs(ctx context.Context, ban *pkg.Ban, banned bool, kept ...*pkg.Ban) (int, error) {
+	songs, scanned, err := s.songsToFlag(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, song := range songs {
+		if song.Banned == banned || !ban.Match(song) || matchAny(kept, song) {
+			continue
+		}
+		if !scanned {
+			if song, err = s.GetSong(ctx, song.ID); err != nil {
+				return n, errors.Wrap(err, "get song to flag")
+			}
+		}
+		song.Banned = banned
+		if err := s.SetSong(ctx, song); err != nil {
+			return n, errors.Wrapf(err, "flag song %s", song.ID.String())
+		}
+		n++
+	}
+	return n, nil
+}
+
+// songsToFlag returns the indexed songs with the banned ones, or all stored songs if the index is not loaded yet
+func (s *Service) songsToFlag(ctx context.Context) ([]*pkg.Song, bool, error) {
+	s.index.Lock()
+	loaded := s.index.loaded
+	s.index.Unlock()
+	if loaded {
+		return s.FilterSongsIndex(&pkg.SongFilter{Banned: true}), false, nil
+	}
+	songs, err := s.client.GetAllSongs(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "get all songs")
+	}
+	return songs, true, nil
+}
+
+func matchAny(bans []*pkg.Ban, song *pkg.Song) bool {
+	for _, ban := range bans {
+		if ban.Match(song) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dedup merges duplicate songs, see Dedup. Cached songs and user counts of the merged songs are dropped,
+// the merged songs are replaced by their targets in the index.
+func (s *Service) Dedup(ctx context.Context, titles, dryRun bool) (*DedupResult, error) {
+	res, err := Dedup(ctx, s.client, titles, dryRun)
+	if dryRun || res == nil || len(res.Groups) == 0 {
+		return res, err
+	}
+	for _, group := range res.Groups {
+		s.cache.Delete(s.cache.KeyFromID(group.Target))
+		for _, song := range group.Songs {
+			s.cache.Delete(s.cache.KeyFromID(song.ID))
+			s.index.set(song.ID.String(), nil)
+		}
+		target, getErr := s.client.GetSongByID(ctx, group.Target)
+		if getErr != nil {
+			contexts.GetLogger(ctx).Error("get merged song", zap.String("id", group.Target.String()), zap.Error(getErr))
+			continue
+		}
+		target.ID = group.Target
+		s.index.set(group.Target.String(), indexSong(target))
+	}
+	s.users.reset()
+	return res, err
+}
+
+func (s *Service) AddEvent(ctx context.Context, event *pkg.PlayEvent) error {
+	return s.client.AddEvents(ctx, []*pkg.PlayEvent{event})
+}
+
+func (s *Service) GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error) {
+	return s.client.GetEvents(ctx, filter)
+}
+
+// GetRandomSongs returns up to n distinct songs matching the filter picked uniformly
+func (s *Service) GetRandomSongs(ctx context.Context, n int, filter *pkg.SongFilter) ([]*pkg.Song, error) {
+	index := s.FilterSongsIndex(filter)
+	if len(index) == 0 {
+		return nil, errors.New("no preloaded songs")
+	}
+	if n > len(index) {
+		n = len(index)
+	}
+	s.rngMx.Lock()
+	perm := s.rng.Perm(len(index))[:n]
+	s.rngMx.Unlock()
+
+	result := make([]*pkg.Song, 0, n)
+	for _, i := range perm {
+		song, err := s.GetSong(ctx, index[i].ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "get song failed")
+		}
+		result = append(result, song)
+	}
+	return result, nil
+}
+
+// SongsIndex returns all known songs except banned ones with only ID, artist, playbacks and last play filled.
+// The songs must not be modified.
+func (s *Service) SongsIndex() []*pkg.Song {
+	return s.FilterSongsIndex(nil)
+}
+
+// FilterSongsIndex is SongsIndex of the songs matching the filter, nil filter skips banned songs
+func (s *Service) FilterSongsIndex(filter *pkg.SongFilter) []*pkg.Song {
+	s.index.Lock()
+	defer s.index.Unlock()
+	res := make([]*pkg.Song, 0, len(s.index.songs))
+	for _, song := range s.index.songs {
+		if filter.Match(song) {
+			res = append(res, song)
+		}
+	}
+	return res
+}
+
+// set updates the indexed song, nil song is removed
+func (i *songsIndex) set(key string, song *pkg.Song) {
+	i.Lock()
+	defer i.Unlock()
+	if i.pending != nil {
+		i.pending[key] = song
+	}
+	if song == nil {
+		delete(i.songs, key)
+		return
+	}
+	i.songs[key] = song
+}
+
+func (s *Service) reconcileIndexProcess(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.reconcileIndex(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reconcileIndex rebuilds the index from the full scan of the backend.
+// Songs written during the scan are newer than the scanned ones, so they are applied on top.
+func (s *Service) reconcileIndex(ctx context.Context) {
+	s.reconcileMx.Lock()
+	defer s.reconcileMx.Unlock()
+	logger := contexts.GetLogger(ctx)
+	s.index.Lock()
+	s.index.pending = make(map[string]*pkg.Song)
+	s.index.Unlock()
+
+	songs, err := s.client.GetAllSongs(ctx)
+
+	s.index.Lock()
+	defer s.index.Unlock()
+	pending := s.index.pending
+	s.index.pending = nil
+	if err != nil {
+		logger.Error("getting all songs", zap.Error(err))
+		return
+	}
+	fresh := make(map[string]*pkg.Song, len(songs))
+	for _, song := range songs {
+		fresh[song.ID.String()] = indexSong(song)
+	}
+	for key, song := range pending {
+		if song == nil {
+			delete(fresh, key)
+		} else {
+			fresh[key] = song
+		}
+	}
+	drift := indexDrift(s.index.songs, fresh)
+	s.index.songs = fresh
+	s.index.loaded = true
+	logger.Info("songs index reconciled", zap.Int("songs", len(fresh)), zap.Int("drift", drift))
+}
+
+// indexDrift is the number of songs added, removed or changed by the reconciliation
+func indexDrift(old, fresh map[string]*pkg.Song) int {
+	drift := 0
+	for key, song := range fresh {
+		prev, ok := old[key]
+		if !ok || prev.Playbacks != song.Playbacks || prev.Banned != song.Banned ||
+			prev.ArtistName != song.ArtistName || !prev.LastPlay.Equal(song.LastPlay) {
+			drift++
+		}
+	}
+	for key := range old {
+		if _, ok := fresh[key]; !ok {
+			drift++
+		}
+	}
+	return drift
+}
+
+// indexSong is the song without its heavy fields
+func indexSong(song *pkg.Song) *pkg.Song {
+	return &pkg.Song{
+		ID:         song.ID,
+		ArtistName: song.ArtistName,
+		ArtistURL:  song.ArtistURL,
+		Playbacks:  song.Playbacks,
+		LastPlay:   song.LastPlay,
+		Banned:     song.Banned,
+	}
+}
@@ -0,0 +1,440 @@
+package storage_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage"
+	"github.com/HalvaPovidlo/halvabot-go/internal/music/storage/memory"
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+func newTestService(t *testing.T) (*storage.Service, *memory.Client) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	backend := memory.NewMemoryClient()
+	s, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, backend
+}
+
+func testSong(id string) *pkg.Song {
+	return &pkg.Song{
+		ID:      pkg.SongID{ID: id, Service: pkg.ServiceYouTube},
+		Title:   "song " + id,
+		URL:     "https://www.youtube.com/watch?v=" + id,
+		Service: pkg.ServiceYouTube,
+	}
+}
+
+func TestServiceUpsertSongIncPlaybacks(t *testing.T) {
+	ctx := context.Background()
+	s, backend := newTestService(t)
+	stored := testSong("dQw4w9WgXcQ")
+	stored.Playbacks = 3
+	if err := backend.SetSong(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	for want := 4; want <= 5; want++ {
+		got, err := s.UpsertSongIncPlaybacks(ctx, testSong("dQw4w9WgXcQ"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("got %d playbacks, wanted %d", got, want)
+		}
+	}
+	if got, err := s.UpsertSongIncPlaybacks(ctx, testSong("y6120QOlsfU")); err != nil || got != 1 {
+		t.Fatalf("got %d playbacks %v for a new song, wanted 1", got, err)
+	}
+}
+
+// staleBackend is a write-behind backend which never returns what was written
+type staleBackend struct {
+	*memory.Client
+	mx    sync.Mutex
+	reads int
+}
+
+func (b *staleBackend) GetUserSong(ctx context.Context, id pkg.SongID, user string) (*pkg.Song, error) {
+	b.mx.Lock()
+	b.reads++
+	b.mx.Unlock()
+	return &pkg.Song{ID: id, Playbacks: 5}, nil
+}
+
+func TestServiceIncrementUserRequests(t *testing.T) {
+	ctx := context.Background()
+	s, backend := newTestService(t)
+	song := testSong("dQw4w9WgXcQ")
+	song.Playbacks = 10
+	for i := 0; i < 2; i++ {
+		if err := s.IncrementUserRequests(ctx, song, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if song.Playbacks != 10 {
+		t.Fatalf("got %d song playbacks, user requests must not change them", song.Playbacks)
+	}
+	userSong, err := backend.GetUserSong(ctx, song.ID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userSong.Playbacks != 2 {
+		t.Fatalf("got %d user playbacks, wanted 2", userSong.Playbacks)
+	}
+	if _, err := backend.GetUserSong(ctx, song.ID, "other"); err == nil {
+		t.Fatal("requests of another user are changed")
+	}
+}
+
+func TestServiceIncrementUserRequestsLoadsOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := &staleBackend{Client: memory.NewMemoryClient()}
+	s, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 50
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.IncrementUserRequests(ctx, testSong("dQw4w9WgXcQ"), "user"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	song, err := backend.Client.GetUserSong(ctx, testSong("dQw4w9WgXcQ").ID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if song.Playbacks != 5+requests {
+		t.Fatalf("got %d user playbacks, wanted %d", song.Playbacks, 5+requests)
+	}
+	if err := s.IncrementUserRequests(ctx, testSong("dQw4w9WgXcQ"), "user"); err != nil {
+		t.Fatal(err)
+	}
+	if backend.reads > requests {
+		t.Fatalf("user song was read %d times after it was loaded", backend.reads)
+	}
+}
+
+// slowBackend holds user song writes of the slow user until the gate is closed
+type slowBackend struct {
+	*memory.Client
+	slow string
+	gate chan struct{}
+}
+
+func (b *slowBackend) SetUserSong(ctx context.Context, song *pkg.Song, user string) error {
+	if user == b.slow {
+		<-b.gate
+	}
+	return b.Client.SetUserSong(ctx, song, user)
+}
+
+func TestServiceIncrementUserRequestsInParallel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := &slowBackend{Client: memory.NewMemoryClient(), slow: "slow", gate: make(chan struct{})}
+	s, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slowDone := make(chan error, 1)
+	go func() {
+		slowDone <- s.IncrementUserRequests(ctx, testSong("dQw4w9WgXcQ"), "slow")
+	}()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.IncrementUserRequests(ctx, testSong("y6120QOlsfU"), "user")
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request of the user waits for the write of another one")
+	}
+	close(backend.gate)
+	if err := <-slowDone; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceGetRandomSongs(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewMemoryClient()
+	ids := []string{"dQw4w9WgXcQ", "y6120QOlsfU", "djV11Xbc914"}
+	for _, id := range ids {
+		if err := backend.SetSong(ctx, testSong(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		songs, err := s.GetRandomSongs(ctx, 2, nil)
+		if err == nil {
+			if len(songs) != 2 || songs[0].ID == songs[1].ID {
+				t.Fatalf("got %v, wanted 2 different songs", songs)
+			}
+			return
+		}
+		// the index of songs is loaded in background
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServiceIndexFollowsWrites(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+	fine, banned, other := testSong("dQw4w9WgXcQ"), testSong("y6120QOlsfU"), testSong("djV11Xbc914")
+	banned.Banned = true
+	other.ID.Service = "soundcloud"
+	for _, song := range []*pkg.Song{fine, banned, other} {
+		if err := s.SetSong(ctx, song); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter *pkg.SongFilter
+		want   int
+	}{
+		{"not banned", nil, 2},
+		{"service", &pkg.SongFilter{Service: pkg.ServiceYouTube}, 1},
+		{"banned too", &pkg.SongFilter{Banned: true}, 3},
+		{"banned of service", &pkg.SongFilter{Service: pkg.ServiceYouTube, Banned: true}, 2},
+	}
+	for _, tt := range tests {
+		if got := s.FilterSongsIndex(tt.filter); len(got) != tt.want {
+			t.Errorf("%s: got %d songs, wanted %d", tt.name, len(got), tt.want)
+		}
+	}
+
+	fine.Banned = true
+	if err := s.SetSong(ctx, fine); err != nil {
+		t.Fatal(err)
+	}
+	if index := s.SongsIndex(); len(index) != 1 || index[0].ID != other.ID {
+		t.Fatalf("got %v after the ban", index)
+	}
+	songs, err := s.GetRandomSongs(ctx, 5, &pkg.SongFilter{Service: "soundcloud"})
+	if err != nil || len(songs) != 1 || songs[0].Title != other.Title {
+		t.Fatalf("got %v, %v", songs, err)
+	}
+}
+
+// scanCounter counts full scans of the songs
+type scanCounter struct {
+	*memory.Client
+	mx    sync.Mutex
+	scans int
+}
+
+func (c *scanCounter) GetAllSongs(ctx context.Context) ([]*pkg.Song, error) {
+	c.mx.Lock()
+	c.scans++
+	c.mx.Unlock()
+	return c.Client.GetAllSongs(ctx)
+}
+
+func (c *scanCounter) count() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.scans
+}
+
+func TestServiceFlagSongs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := &scanCounter{Client: memory.NewMemoryClient()}
+	first, second, other := testSong("dQw4w9WgXcQ"), testSong("y6120QOlsfU"), testSong("djV11Xbc914")
+	first.ArtistName, second.ArtistName, other.ArtistName = "Earrape", "Earrape", "Darude"
+	for _, song := range []*pkg.Song{first, second, other} {
+		if err := backend.SetSong(ctx, song); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := storage.NewService(ctx, backend, storage.NewSongsCache(ctx, 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); backend.count() == 0 || len(s.SongsIndex()) != 3; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("songs index is not loaded")
+		}
+	}
+
+	artist := &pkg.Ban{Kind: pkg.BanArtist, Value: "earrape"}
+	song := &pkg.Ban{Kind: pkg.BanSong, Value: first.ID.String()}
+	if n, err := s.FlagSongs(ctx, artist, true); err != nil || n != 2 {
+		t.Fatalf("got %d flagged %v, wanted 2 songs of the artist", n, err)
+	}
+	if n, err := s.FlagSongs(ctx, artist, false, song); err != nil || n != 1 {
+		t.Fatalf("got %d unflagged %v, wanted the song not kept by the other ban", n, err)
+	}
+	stored, err := backend.GetSongByID(ctx, first.ID)
+	if err != nil || !stored.Banned || stored.Title != first.Title {
+		t.Fatalf("got %+v %v, wanted the whole song still flagged", stored, err)
+	}
+	if index := s.SongsIndex(); len(index) != 2 {
+		t.Fatalf("got index %v, wanted the kept song excluded", index)
+	}
+	if scans := backend.count(); scans != 1 {
+		t.Fatalf("got %d scans, wanted only the one loading the index", scans)
+	}
+}
+
+func TestServiceDedup(t *testing.T) {
+	ctx := context.Background()
+	s, backend := newTestService(t)
+	now := time.Now().Round(time.Second)
+	canonical := testSong("dQw4w9WgXcQ")
+	canonical.Playbacks, canonical.LastPlay = 3, now.Add(-time.Hour)
+	shared := testSong("dQw4w9WgXcQ")
+	shared.ID.ID = "dQw4w9WgXcQ&t=10"
+	shared.URL = "https://youtu.be/dQw4w9WgXcQ?t=10"
+	shared.Playbacks, shared.LastPlay = 2, now
+	original := testSong("y6120QOlsfU")
+	original.Title, original.ArtistName, original.Playbacks = "Darude - Sandstorm (Official Video)", "Darude", 5
+	reupload := testSong("aaaaaaaaaaa")
+	reupload.Title, reupload.ArtistName, reupload.Playbacks = "Sandstorm [HD]", "darude", 1
+	for _, song := range []*pkg.Song{canonical, shared, original, reupload} {
+		if err := backend.SetSong(ctx, song); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, song := range []*pkg.Song{canonical, shared} {
+		userSong := *song
+		userSong.Playbacks = 1
+		if err := backend.SetUserSong(ctx, &userSong, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := s.Dedup(ctx, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Groups) != 1 || res.Removed != 1 || res.UserSongs != 1 {
+		t.Fatalf("got %d groups, %d removed, %d user songs on dry run, wanted the shared link only", len(res.Groups), res.Removed, res.UserSongs)
+	}
+	if songs, _ := backend.GetAllSongs(ctx); len(songs) != 4 {
+		t.Fatalf("got %d songs after dry run, wanted 4", len(songs))
+	}
+
+	res, err = s.Dedup(ctx, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Groups) != 2 || res.Removed != 2 {
+		t.Fatalf("got %d groups and %d removed, wanted 2 and 2", len(res.Groups), res.Removed)
+	}
+	songs, _ := backend.GetAllSongs(ctx)
+	if len(songs) != 2 {
+		t.Fatalf("got %d songs, wanted 2", len(songs))
+	}
+	merged, err := backend.GetSongByID(ctx, canonical.ID)
+	if err != nil || merged.Playbacks != 5 || !merged.LastPlay.Equal(now) {
+		t.Fatalf("got %+v %v, wanted summed playbacks and the latest play", merged, err)
+	}
+	if song, err := backend.GetSongByID(ctx, original.ID); err != nil || song.Playbacks != 6 {
+		t.Fatalf("got %+v %v, wanted the reupload merged into the original", song, err)
+	}
+	userSongs, _ := backend.GetUserSongs(ctx, "user")
+	if len(userSongs) != 1 || userSongs[0].ID != canonical.ID || userSongs[0].Playbacks != 2 {
+		t.Fatalf("got user songs %+v, wanted one merged song", userSongs)
+	}
+}
+
+// bufferedBackend keeps written songs until Flush like the firestore client does
+type bufferedBackend struct {
+	*memory.Client
+	songs     []*pkg.Song
+	userSongs map[string][]*pkg.Song
+}
+
+func (b *bufferedBackend) SetSong(ctx context.Context, song *pkg.Song) error {
+	b.songs = append(b.songs, song)
+	return nil
+}
+
+func (b *bufferedBackend) SetUserSong(ctx context.Context, song *pkg.Song, user string) error {
+	b.userSongs[user] = append(b.userSongs[user], song)
+	return nil
+}
+
+func (b *bufferedBackend) Flush(ctx context.Context) error {
+	for _, song := range b.songs {
+		if err := b.Client.SetSong(ctx, song); err != nil {
+			return err
+		}
+	}
+	for user, songs := range b.userSongs {
+		for _, song := range songs {
+			if err := b.Client.SetUserSong(ctx, song, user); err != nil {
+				return err
+			}
+		}
+	}
+	b.songs, b.userSongs = nil, make(map[string][]*pkg.Song)
+	return nil
+}
+
+func TestDedupFlushesBeforeDelete(t *testing.T) {
+	ctx := context.Background()
+	backend := &bufferedBackend{Client: memory.NewMemoryClient(), userSongs: make(map[string][]*pkg.Song)}
+	canonical := testSong("dQw4w9WgXcQ")
+	canonical.Playbacks = 3
+	shared := testSong("dQw4w9WgXcQ")
+	shared.ID.ID = "dQw4w9WgXcQ&t=10"
+	shared.Playbacks = 2
+	for _, song := range []*pkg.Song{canonical, shared} {
+		if err := backend.Client.SetSong(ctx, song); err != nil {
+			t.Fatal(err)
+		}
+		userSong := *song
+		userSong.Playbacks = 1
+		if err := backend.Client.SetUserSong(ctx, &userSong, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := storage.Dedup(ctx, backend, false, false); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := backend.GetSongByID(ctx, canonical.ID)
+	if err != nil || merged.Playbacks != 5 {
+		t.Fatalf("got %v %v, wanted the merged song stored before the duplicate is deleted", merged, err)
+	}
+	userSong, err := backend.GetUserSong(ctx, canonical.ID, "user")
+	if err != nil || userSong.Playbacks != 2 {
+		t.Fatalf("got %v %v, wanted the merged user song stored before the duplicate is deleted", userSong, err)
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+const (
+	BackendFirestore = "firestore"
+	BackendBolt      = "bolt"
+)
+
+// ErrNotFound is returned by every backend when the requested entry does not exist
+var ErrNotFound = errors.New("no docs found")
+
+// Backend is the persistent storage of songs, user songs, search queries and accounts
+type Backend interface {
+	GetSongByID(ctx context.Context, id pkg.SongID) (*pkg.Song, error)
+	SetSong(ctx context.Context, song *pkg.Song) error
+	// DeleteSong does nothing if the song does not exist
+	DeleteSong(ctx context.Context, id pkg.SongID) error
+	GetUserSong(ctx context.Context, id pkg.SongID, user string) (*pkg.Song, error)
+	SetUserSong(ctx context.Context, song *pkg.Song, user string) error
+	// DeleteUserSong does nothing if the user has not requested the song
+	DeleteUserSong(ctx context.Context, id pkg.SongID, user string) error
+	// GetUsers returns IDs of all users who requested songs
+	GetUsers(ctx context.Context) ([]string, error)
+	GetUserSongs(ctx context.Context, user string) ([]*pkg.Song, error)
+	// GetAllSongs is used to build the index of songs for the radio
+	GetAllSongs(ctx context.Context) ([]*pkg.Song, error)
+
+	GetQuery(ctx context.Context, query string) (*pkg.SearchQuery, error)
+	SetQuery(ctx context.Context, query *pkg.SearchQuery) error
+	DeleteQueries(ctx context.Context, query string) (int, error)
+
+	GetAccount(ctx context.Context, login string) (*pkg.AccountInfo, error)
+
+	GetPlaylist(ctx context.Context, ownerID, name string) (*pkg.Playlist, error)
+	SetPlaylist(ctx context.Context, playlist *pkg.Playlist) error
+	// DeletePlaylist does nothing if the playlist does not exist
+	DeletePlaylist(ctx context.Context, ownerID, name string) error
+	GetPlaylists(ctx context.Context, filter *pkg.PlaylistFilter) ([]*pkg.Playlist, error)
+
+	SetLike(ctx context.Context, user string, like *pkg.Like) error
+	// DeleteLike does nothing if the song is not liked
+	DeleteLike(ctx context.Context, user string, id pkg.SongID) error
+	// GetLikes returns the user's likes, newest first
+	GetLikes(ctx context.Context, user string) ([]*pkg.Like, error)
+
+	SetBan(ctx context.Context, ban *pkg.Ban) error
+	// DeleteBan does nothing if there is no such ban
+	DeleteBan(ctx context.Context, kind pkg.BanKind, value string) error
+	GetBans(ctx context.Context) ([]*pkg.Ban, error)
+	AddBanAudit(ctx context.Context, entry *pkg.BanAudit) error
+	// GetBanAudit returns up to limit last entries, newest first
+	GetBanAudit(ctx context.Context, limit int) ([]*pkg.BanAudit, error)
+
+	AddEvents(ctx context.Context, events []*pkg.PlayEvent) error
+	// GetEvents returns events matching the filter, newest first
+	GetEvents(ctx context.Context, filter *pkg.EventFilter) ([]*pkg.PlayEvent, error)
+}
+
+type Config struct {
+	// Backend is firestore (default) or bolt
+	Backend string `json:"backend"`
+	// Path to the bolt database file
+	Path string `json:"path"`
+	// Credentials is the firebase credentials file
+	Credentials string `json:"credentials"`
+	// CacheSize is the number of cached songs, DefaultCacheSize if not set
+	CacheSize int `json:"cache_size"`
+}
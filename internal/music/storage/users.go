@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/HalvaPovidlo/halvabot-go/internal/pkg"
+)
+
+// userPlaysLocks is the number of locks ordering the writes of the counts
+const userPlaysLocks = 64
+
+// userPlays is the authoritative count of songs requested by each user.
+// Counts are loaded from the backend once and only written afterwards,
+// so buffered writes of the backend never make them go back.
+// Writes of a count are ordered by one of the striped locks, so the backend is not called under the global lock.
+type userPlays struct {
+	sync.Mutex
+	counts map[string]map[string]int // user -> song -> playbacks
+	writes [userPlaysLocks]sync.Mutex
+}
+
+func newUserPlays() *userPlays {
+	return &userPlays{counts: make(map[string]map[string]int)}
+}
+
+func (u *userPlays) get(user string, id pkg.SongID) (int, bool) {
+	u.Lock()
+	defer u.Unlock()
+	n, ok := u.counts[user][id.String()]
+	return n, ok
+}
+
+// reset forgets all counts, they are loaded from the backend again.
+// It waits for the writes in progress, so their counts are forgotten too.
+func (u *userPlays) reset() {
+	for i := range u.writes {
+		u.writes[i].Lock()
+	}
+	u.Lock()
+	u.counts = make(map[string]map[string]int)
+	u.Unlock()
+	for i := range u.writes {
+		u.writes[i].Unlock()
+	}
+}
+
+// set replaces the count, it is written under the lock of the count like the increments
+func (u *userPlays) set(user string, id pkg.SongID, playbacks int, write func() error) error {
+	l := u.lock(user, id)
+	l.Lock()
+	defer l.Unlock()
+	if err := write(); err != nil {
+		return err
+	}
+	u.store(user, id, playbacks)
+	return nil
+}
+
+// increment adds one play to the count or to the loaded one if the count is unknown yet.
+// The new count is written under the lock of the count, so the writes are never reordered.
+func (u *userPlays) increment(user string, id pkg.SongID, loaded int, write func(playbacks int) error) error {
+	l := u.lock(user, id)
+	l.Lock()
+	defer l.Unlock()
+	n, ok := u.get(user, id)
+	if !ok {
+		n = loaded
+	}
+	if err := write(n + 1); err != nil {
+		return err
+	}
+	u.store(user, id, n+1)
+	return nil
+}
+
+func (u *userPlays) store(user string, id pkg.SongID, playbacks int) {
+	u.Lock()
+	defer u.Unlock()
+	if _, ok := u.counts[user]; !ok {
+		u.counts[user] = make(map[string]int)
+	}
+	u.counts[user][id.String()] = playbacks
+}
+
+// lock returns the lock ordering the writes of the user's song count
+func (u *userPlays) lock(user string, id pkg.SongID) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(user + "/" + id.String()))
+	return &u.writes[h.Sum32()%userPlaysLocks]
+}
+
+func (s *Service) IncrementUserRequests(ctx context.Context, song *pkg.Song, userID string) error {
+	loaded := 0
+	if _, ok := s.users.get(userID, song.ID); !ok {
+		userSong, err := s.client.GetUserSong(ctx, song.ID, userID)
+		switch {
+		case err == nil:
+			loaded = userSong.Playbacks
+		case !errors.Is(err, ErrNotFound):
+			return errors.Wrap(err, "get user song")
+		}
+	}
+
+	return s.users.increment(userID, song.ID, loaded, func(playbacks int) error {
+		userSong := *song
+		userSong.Playbacks = playbacks
+		return errors.Wrap(s.client.SetUserSong(ctx, &userSong, userID), "set user song")
+	})
+}
+
+// SetUserSong replaces the user's song together with the number of requests
+func (s *Service) SetUserSong(ctx context.Context, song *pkg.Song, userID string) error {
+	return s.users.set(userID, song.ID, song.Playbacks, func() error {
+		return errors.Wrap(s.client.SetUserSong(ctx, song, userID), "set user song")
+	})
+}
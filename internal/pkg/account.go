@@ -0,0 +1,7 @@
+package pkg
+
+// AccountInfo is the login credentials of the web user
+type AccountInfo struct {
+	Password string `firestore:"password" json:"password"`
+	UserID   string `firestore:"user_id" json:"user_id"`
+}
@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+type BanKind string
+
+const (
+	BanSong    BanKind = "song"    // Value is the song ID
+	BanArtist  BanKind = "artist"  // Value is the case-insensitive artist name
+	BanChannel BanKind = "channel" // Value is the channel ID, the last part of the artist URL
+)
+
+type BanAction string
+
+const (
+	BanAdded   BanAction = "ban"
+	BanRemoved BanAction = "unban"
+)
+
+// Ban excludes matching songs from the radio and refuses to play them
+type Ban struct {
+	Kind   BanKind   `firestore:"kind" json:"kind"`
+	Value  string    `firestore:"value" json:"value"`
+	Reason string    `firestore:"reason,omitempty" json:"reason,omitempty"`
+	By     string    `firestore:"by" json:"by"`
+	At     time.Time `firestore:"at" json:"at"`
+}
+
+// BanAudit is the record of who banned or unbanned what
+type BanAudit struct {
+	Action BanAction `firestore:"action" json:"action"`
+	Kind   BanKind   `firestore:"kind" json:"kind"`
+	Value  string    `firestore:"value" json:"value"`
+	Reason string    `firestore:"reason,omitempty" json:"reason,omitempty"`
+	By     string    `firestore:"by" json:"by"`
+	At     time.Time `firestore:"at" json:"at"`
+	Songs  int       `firestore:"songs" json:"songs"` // number of flagged or unflagged songs
+}
+
+// BanKey is the storage key of the ban
+func BanKey(kind BanKind, value string) string {
+	return string(kind) + ":" + value
+}
+
+// NormalizeBanValue returns the value the ban is stored with or empty string if it is not valid.
+// Song URLs are converted to IDs, channel URLs to channel IDs and artist names are lowercased.
+func NormalizeBanValue(kind BanKind, value string) string {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case BanSong:
+		if id := GetIDFromURL(value); id.ID != "" {
+			return id.String()
+		}
+		if id := ParseSongID(value); id.Service != "" && id.ID != "" {
+			return id.String()
+		}
+	case BanArtist:
+		return strings.ToLower(value)
+	case BanChannel:
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			value = strings.Trim(u.Path, "/")
+			value = value[strings.LastIndex(value, "/")+1:]
+		}
+		if value != "" && !strings.ContainsAny(value, "/ ") {
+			return value
+		}
+	}
+	return ""
+}
+
+// Match reports whether the song is banned, the ban value must be normalized
+func (b *Ban) Match(song *Song) bool {
+	switch b.Kind {
+	case BanSong:
+		return song.ID.String() == b.Value
+	case BanArtist:
+		return song.ArtistName != "" && strings.ToLower(song.ArtistName) == b.Value
+	case BanChannel:
+		return song.ArtistURL != "" && strings.HasSuffix(strings.TrimRight(song.ArtistURL, "/"), "/"+b.Value)
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package pkg
+
+import "testing"
+
+func TestBanMatch(t *testing.T) {
+	song := &Song{
+		ID:         SongID{ID: "hDfFXWinkAk", Service: ServiceYouTube},
+		ArtistName: "Loud Memes",
+		ArtistURL:  "https://www.youtube.com/channel/UC123",
+	}
+	testCases := []struct {
+		kind  BanKind
+		value string
+		match bool
+	}{
+		{BanSong, "https://youtu.be/hDfFXWinkAk", true},
+		{BanSong, "youtube_hDfFXWinkAk", true},
+		{BanSong, "youtube_other", false},
+		{BanArtist, "loud memes", true},
+		{BanArtist, "loud", false},
+		{BanChannel, "https://www.youtube.com/channel/UC123/", true},
+		{BanChannel, "UC123", true},
+		{BanChannel, "UC12", false},
+	}
+	for _, tc := range testCases {
+		ban := Ban{Kind: tc.kind, Value: NormalizeBanValue(tc.kind, tc.value)}
+		if ban.Match(song) != tc.match {
+			t.Errorf("ban %s %q: got %v, wanted %v", tc.kind, tc.value, !tc.match, tc.match)
+		}
+	}
+	if v := NormalizeBanValue(BanSong, "not a song"); v != "" {
+		t.Errorf("got %q for invalid song, wanted empty", v)
+	}
+}
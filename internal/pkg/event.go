@@ -0,0 +1,68 @@
+package pkg
+
+import "time"
+
+type EventType string
+
+const (
+	EventPlay     EventType = "play"
+	EventSkip     EventType = "skip"
+	EventComplete EventType = "complete"
+)
+
+// PlayEvent is a record of the append-only playback log.
+// Every song produces the play event when it starts and the skip or complete event when it ends.
+type PlayEvent struct {
+	Type        EventType `firestore:"type" json:"type"`
+	SongID      SongID    `firestore:"song_id" json:"song_id"`
+	Title       string    `firestore:"title,omitempty" json:"title,omitempty"`
+	RequesterID string    `firestore:"requester_id,omitempty" json:"requester_id,omitempty"` // empty for radio
+	GuildID     string    `firestore:"guild_id,omitempty" json:"guild_id,omitempty"`
+	StartedAt   time.Time `firestore:"started_at" json:"started_at"`
+	At          time.Time `firestore:"at" json:"at"`
+	Position    float64   `firestore:"position" json:"position"` // seconds played
+	Duration    float64   `firestore:"duration" json:"duration"` // seconds
+}
+
+// EventFilter selects events, zero fields match everything
+type EventFilter struct {
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+	GuildID     string
+	RequesterID string
+	SongID      SongID
+	Types       []EventType
+	Limit       int
+}
+
+func (f *EventFilter) Match(e *PlayEvent) bool {
+	if !f.From.IsZero() && e.At.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.At.Before(f.To) {
+		return false
+	}
+	if f.GuildID != "" && e.GuildID != f.GuildID {
+		return false
+	}
+	if f.RequesterID != "" && e.RequesterID != f.RequesterID {
+		return false
+	}
+	if f.SongID != (SongID{}) && e.SongID != f.SongID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Full reports that no more events are needed
+func (f *EventFilter) Full(n int) bool {
+	return f.Limit > 0 && n >= f.Limit
+}
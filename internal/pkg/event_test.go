@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	now := time.Now()
+	event := &PlayEvent{
+		Type:        EventSkip,
+		SongID:      SongID{ID: "dQw4w9WgXcQ", Service: ServiceYouTube},
+		RequesterID: "user",
+		GuildID:     "guild",
+		At:          now,
+	}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"empty", EventFilter{}, true},
+		{"in range", EventFilter{From: now, To: now.Add(time.Second)}, true},
+		{"range end is exclusive", EventFilter{To: now}, false},
+		{"before range", EventFilter{From: now.Add(time.Second)}, false},
+		{"guild", EventFilter{GuildID: "guild"}, true},
+		{"other guild", EventFilter{GuildID: "other"}, false},
+		{"other requester", EventFilter{RequesterID: "other"}, false},
+		{"song", EventFilter{SongID: event.SongID}, true},
+		{"other song", EventFilter{SongID: SongID{ID: "y6120QOlsfU", Service: ServiceYouTube}}, false},
+		{"types", EventFilter{Types: []EventType{EventPlay, EventSkip}}, true},
+		{"other type", EventFilter{Types: []EventType{EventComplete}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(event); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package pkg
+
+import (
+	"sort"
+	"time"
+)
+
+// Like is a song explicitly marked as favorite by a user
+type Like struct {
+	ID    SongID    `firestore:"id" json:"id"`
+	Title string    `firestore:"title" json:"title"`
+	URL   string    `firestore:"url" json:"url"`
+	At    time.Time `firestore:"at" json:"at"`
+}
+
+func NewLike(song *Song, at time.Time) *Like {
+	return &Like{ID: song.ID, Title: song.Title, URL: song.URL, At: at}
+}
+
+// SortLikes orders likes from the newest one
+func SortLikes(likes []*Like) {
+	sort.SliceStable(likes, func(i, j int) bool {
+		return likes[i].At.After(likes[j].At)
+	})
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type PlaylistVisibility string
+
+const (
+	PlaylistPrivate PlaylistVisibility = "private" // only the owner can see and load
+	PlaylistGuild   PlaylistVisibility = "guild"   // everyone in the guild can see and load
+)
+
+var playlistNameRegexp = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+type PlaylistSong struct {
+	ID    SongID `firestore:"id" json:"id"`
+	Title string `firestore:"title" json:"title"`
+	URL   string `firestore:"url" json:"url"`
+}
+
+// Playlist is a named list of songs owned by a user.
+// Names are unique per owner, songs are kept in playing order.
+type Playlist struct {
+	Name       string             `firestore:"name" json:"name"`
+	OwnerID    string             `firestore:"owner_id" json:"owner_id"`
+	GuildID    string             `firestore:"guild_id,omitempty" json:"guild_id,omitempty"`
+	Visibility PlaylistVisibility `firestore:"visibility" json:"visibility"`
+	Songs      []PlaylistSong     `firestore:"songs" json:"songs"`
+	Created    time.Time          `firestore:"created" json:"created"`
+	Updated    time.Time          `firestore:"updated" json:"updated"`
+}
+
+// PlaylistFilter selects playlists, zero fields match everything
+type PlaylistFilter struct {
+	OwnerID    string
+	GuildID    string
+	Visibility PlaylistVisibility
+}
+
+// PlaylistKey is the storage key of the owner's playlist
+func PlaylistKey(ownerID, name string) string {
+	return ownerID + "_" + name
+}
+
+// NormalizePlaylistName returns the lowercase name or empty string if the name is not allowed
+func NormalizePlaylistName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !playlistNameRegexp.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+func (f *PlaylistFilter) Match(p *Playlist) bool {
+	if f.OwnerID != "" && p.OwnerID != f.OwnerID {
+		return false
+	}
+	if f.GuildID != "" && p.GuildID != f.GuildID {
+		return false
+	}
+	return f.Visibility == "" || p.Visibility == f.Visibility
+}
+
+// NewPlaylistSong keeps only what is needed to enqueue the song again
+func NewPlaylistSong(song *Song) PlaylistSong {
+	return PlaylistSong{ID: song.ID, Title: song.Title, URL: song.URL}
+}
@@ -0,0 +1,10 @@
+package pkg
+
+import "time"
+
+// SearchQuery is a cached result of searching a song by text query
+type SearchQuery struct {
+	Query   string    `json:"query"`
+	ID      SongID    `json:"id"`
+	Updated time.Time `json:"updated"`
+}
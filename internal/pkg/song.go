@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -14,26 +15,45 @@ const (
 	ServiceYouTube ServiceName = "youtube"
 )
 
+var youtubeIDRegexp = regexp.MustCompile(`^[\w-]{11}$`)
+
 type SongID struct {
-	ID      string
-	Service ServiceName
+	ID      string      `firestore:"id" json:"id"`
+	Service ServiceName `firestore:"service" json:"service"`
+}
+
+// SongFilter selects songs of the index, the zero filter selects songs which are not banned
+type SongFilter struct {
+	Service ServiceName // any service if empty
+	Banned  bool        // banned songs are selected too
+}
+
+func (f *SongFilter) Match(s *Song) bool {
+	if f == nil {
+		return !s.Banned
+	}
+	return (f.Banned || !s.Banned) && (f.Service == "" || s.ID.Service == f.Service)
 }
 
 type Song struct {
-	Title        string      `firestore:"title,omitempty" csv:"title" json:"title,omitempty"`
-	URL          string      `firestore:"url,omitempty" csv:"url,omitempty" json:"url,omitempty"`
-	Service      ServiceName `firestore:"service,omitempty" csv:"service,omitempty" json:"service,omitempty"`
-	ArtistName   string      `firestore:"artist_name,omitempty" csv:"artist_name,omitempty" json:"artist_name,omitempty"`
-	ArtistURL    string      `firestore:"artist_url,omitempty" csv:"artist_url,omitempty" json:"artist_url,omitempty"`
-	ArtworkURL   string      `firestore:"artwork_url,omitempty" csv:"artwork_url,omitempty" json:"artwork_url,omitempty"`
-	ThumbnailURL string      `firestore:"thumbnail_url,omitempty" csv:"thumbnail_url,omitempty" json:"thumbnail_url,omitempty"`
-	Playbacks    int         `firestore:"playbacks,omitempty" csv:"playbacks" json:"playbacks,omitempty"`
-	LastPlay     time.Time   `firestore:"last_play,omitempty" csv:"last_play,omitempty" json:"last_play,omitempty"`
-
-	ID        SongID          `firestore:"-" csv:"-" json:"-"`
-	Requester *discordgo.User `firestore:"-" csv:"-" json:"-"`
-	StreamURL string          `firestore:"-" csv:"-" json:"-"`
-	Duration  float64         `firestore:"-" csv:"-" json:"-"`
+	Title         string      `firestore:"title,omitempty" csv:"title" json:"title,omitempty"`
+	URL           string      `firestore:"url,omitempty" csv:"url,omitempty" json:"url,omitempty"`
+	Service       ServiceName `firestore:"service,omitempty" csv:"service,omitempty" json:"service,omitempty"`
+	ArtistName    string      `firestore:"artist_name,omitempty" csv:"artist_name,omitempty" json:"artist_name,omitempty"`
+	ArtistURL     string      `firestore:"artist_url,omitempty" csv:"artist_url,omitempty" json:"artist_url,omitempty"`
+	ArtworkURL    string      `firestore:"artwork_url,omitempty" csv:"artwork_url,omitempty" json:"artwork_url,omitempty"`
+	ThumbnailURL  string      `firestore:"thumbnail_url,omitempty" csv:"thumbnail_url,omitempty" json:"thumbnail_url,omitempty"`
+	Playbacks     int         `firestore:"playbacks,omitempty" csv:"playbacks" json:"playbacks,omitempty"`
+	LastPlay      time.Time   `firestore:"last_play,omitempty" csv:"last_play,omitempty" json:"last_play,omitempty"`
+	Banned        bool        `firestore:"banned,omitempty" csv:"banned,omitempty" json:"banned,omitempty"`
+	SchemaVersion int         `firestore:"schema_version,omitempty" csv:"-" json:"schema_version,omitempty"`
+
+	ID          SongID          `firestore:"-" csv:"-" json:"-"`
+	Requester   *discordgo.User `firestore:"-" csv:"-" json:"-"`
+	RequesterID string          `firestore:"-" csv:"-" json:"-"`
+	StreamURL   string          `firestore:"-" csv:"-" json:"-"`
+	Duration    float64         `firestore:"-" csv:"-" json:"-"`
+	Live        bool            `firestore:"-" csv:"-" json:"-"`
 }
 
 type User struct {
@@ -57,6 +77,15 @@ func (id SongID) String() string {
 	return string(id.Service) + "_" + id.ID
 }
 
+// ParseSongID is the reverse of SongID String
+func ParseSongID(s string) SongID {
+	service, id, ok := strings.Cut(s, "_")
+	if !ok {
+		return SongID{ID: s}
+	}
+	return SongID{ID: id, Service: ServiceName(service)}
+}
+
 func (s *Song) MergeNoOverride(new *Song) {
 	if new == nil {
 		return
@@ -94,22 +123,73 @@ func (s *Song) MergeNoOverride(new *Song) {
 	if s.StreamURL == "" {
 		s.StreamURL = new.StreamURL
 	}
+	if !s.Banned {
+		s.Banned = new.Banned
+	}
+}
+
+// MergeDuplicate merges another stored copy of the same song into s.
+// Playbacks are summed, the latest LastPlay is kept and empty fields are filled.
+func (s *Song) MergeDuplicate(dup *Song) {
+	if dup == nil {
+		return
+	}
+	playbacks := s.Playbacks + dup.Playbacks
+	lastPlay := s.LastPlay
+	if dup.LastPlay.After(lastPlay) {
+		lastPlay = dup.LastPlay
+	}
+	s.MergeNoOverride(dup)
+	s.Playbacks = playbacks
+	s.LastPlay = lastPlay
 }
 
-func GetIDFromURL(url string) SongID {
+// GetIDFromURL returns canonical SongID for every known form of YouTube URL:
+// watch, youtu.be, shorts, embed, live, music.youtube.com and others.
+// Extra params like &t= or &list= are ignored. Empty SongID is returned for unknown URLs.
+func GetIDFromURL(rawURL string) SongID {
 	var id SongID
-	if TestYoutubeURL(url) {
+	if videoID := youtubeVideoID(rawURL); videoID != "" {
 		id.Service = ServiceYouTube
-		// TODO: trim all urls https://stackoverflow.com/questions/19377262/regex-for-youtube-url
-		url = strings.TrimPrefix(url, `https://www.youtube.com/watch?v=`)
-		url = strings.TrimPrefix(url, `https://youtube.com/watch?v=`)
-		id.ID = url
-		return id
+		id.ID = videoID
 	}
 	return id
 }
 
 func TestYoutubeURL(url string) bool {
-	test, _ := regexp.MatchString("^((?:https?:)?\\/\\/)?((?:www|m)\\.)?((?:youtube(-nocookie)?\\.com|youtu.be))(\\/(?:[\\w\\-]+\\?v=|embed\\/|v\\/)?)([\\w\\-]+)(\\S+)?$", url)
-	return test
+	return youtubeVideoID(url) != ""
+}
+
+func youtubeVideoID(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	} else if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = path[0]
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		switch path[0] {
+		case "watch":
+			id = u.Query().Get("v")
+		case "shorts", "embed", "v", "e", "live":
+			if len(path) > 1 {
+				id = path[1]
+			}
+		}
+	}
+	if !youtubeIDRegexp.MatchString(id) {
+		return ""
+	}
+	return id
 }
@@ -17,6 +17,46 @@ func TestGetIDFromURL(t *testing.T) {
 			in:  "https://youtube.com/watch?v=hDfFXWinkAk",
 			out: "youtube_hDfFXWinkAk",
 		},
+		{
+			in:  "https://www.youtube.com/watch?v=hDfFXWinkAk&t=42s&list=PL123",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://www.youtube.com/watch?feature=share&v=hDfFXWinkAk",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://youtu.be/hDfFXWinkAk?t=10",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://m.youtube.com/watch?v=hDfFXWinkAk",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://music.youtube.com/watch?v=hDfFXWinkAk&feature=share",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://youtube.com/shorts/hDfFXWinkAk?feature=share",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://www.youtube-nocookie.com/embed/hDfFXWinkAk",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "youtube.com/watch?v=hDfFXWinkAk",
+			out: "youtube_hDfFXWinkAk",
+		},
+		{
+			in:  "https://vk.com/watch?v=hDfFXWinkAk",
+			out: "_",
+		},
+		{
+			in:  "https://www.youtube.com/watch?v=short",
+			out: "_",
+		},
 	}
 
 	for i := range testCases {
@@ -51,6 +91,14 @@ func TestTestYoutubeURL(t *testing.T) {
 			in:  "https://vk.com/watch?v=hDfFXWinkAk",
 			out: false,
 		},
+		{
+			in:  "https://youtu.be/hDfFXWinkAk",
+			out: true,
+		},
+		{
+			in:  "https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw",
+			out: false,
+		},
 	}
 
 	for i := range testCases {
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+
+	"github.com/HalvaPovidlo/halvabot-go/pkg/contexts"
+	"github.com/HalvaPovidlo/halvabot-go/pkg/discord"
+)
+
+// ReactionHandler is called when the reaction is added or removed
+type ReactionHandler func(ctx context.Context, s *discordgo.Session, r *discordgo.MessageReaction, added bool)
+
+type Reaction struct {
+	handler ReactionHandler
+	Emoji   string
+	debug   bool
+}
+
+func NewReactionCommand(emoji string, handler ReactionHandler, debug bool) *Reaction {
+	return &Reaction{
+		handler: handler,
+		Emoji:   emoji,
+		debug:   debug,
+	}
+}
+
+// RegisterCommand runs Reaction.handler for every added or removed Reaction.Emoji except the bot's own ones
+func (r *Reaction) RegisterCommand(s *discordgo.Session, logger *zap.Logger) {
+	s.AddHandler(func(s *discordgo.Session, i *discordgo.MessageReactionAdd) {
+		r.handle(s, i.MessageReaction, true, logger)
+	})
+	s.AddHandler(func(s *discordgo.Session, i *discordgo.MessageReactionRemove) {
+		r.handle(s, i.MessageReaction, false, logger)
+	})
+}
+
+func (r *Reaction) handle(s *discordgo.Session, reaction *discordgo.MessageReaction, added bool, logger *zap.Logger) {
+	if reaction.UserID == s.State.User.ID || reaction.Emoji.Name != r.Emoji {
+		return
+	}
+	if (reaction.ChannelID == discord.ChannelDebugID) != r.debug {
+		return
+	}
+	ctx := contexts.WithValues(context.Background(), logger, "")
+	contexts.GetLogger(ctx).Info("reaction handled",
+		zap.String("emoji", r.Emoji),
+		zap.String("message", reaction.MessageID),
+		zap.Bool("added", added))
+	r.handler(ctx, s, reaction, added)
+}